@@ -2,16 +2,25 @@ package main
 
 import (
 	"context"
+	"crypto/subtle"
 	"encoding/json"
+	"flag"
 	"fmt"
 	tgbotapi "github.com/go-telegram-bot-api/telegram-bot-api/v5"
 	"io"
 	"log"
 	"net/http"
 	"os"
+	"os/signal"
 	"qweasley/internal/database"
 	"qweasley/internal/handlers"
+	"qweasley/internal/polling"
+	"qweasley/internal/repository"
+	"strconv"
 	"strings"
+	"sync"
+	"syscall"
+	"time"
 )
 
 type Response struct {
@@ -25,11 +34,34 @@ type YandexCloudRequest struct {
 	Body       string            `json:"body"`
 }
 
+// TimerTriggerRequest вызов функции таймером Yandex Cloud
+type TimerTriggerRequest struct {
+	Messages []struct {
+		EventMetadata struct {
+			EventType string `json:"event_type"`
+		} `json:"event_metadata"`
+	} `json:"messages"`
+}
+
+// timerEventType тип события, с которым функцию вызывает таймер
+const timerEventType = "yandex.cloud.events.serverless.triggers.TimerMessage"
+
 var (
 	botInstance *tgbotapi.BotAPI
 	registry    *handlers.Registry
+	updateStore repository.UpdateStore
 )
 
+// secretTokenHeader заголовок, в котором Telegram передает секрет, заданный при setWebhook
+const secretTokenHeader = "X-Telegram-Bot-Api-Secret-Token"
+
+// updateLease на сколько обновление занимается обработкой: если вызов прервали, повтор от Telegram
+// после этого срока обработается заново
+const updateLease = time.Minute
+
+// processedUpdatesCleanupEvery как часто (по update_id) удалять старые отметки обработанных обновлений
+const processedUpdatesCleanupEvery = 100
+
 func cloudLog(message []byte, caption string) {
 	parsed := make(map[string]interface{})
 	if err := json.Unmarshal(message, &parsed); err != nil {
@@ -51,13 +83,24 @@ func cloudLog(message []byte, caption string) {
 	fmt.Println(string(jsonData))
 }
 
-func init() {
-	loadEnvFile()
+// setupOnce защищает подключение из окружения при первом вызове Handler
+var setupOnce sync.Once
 
-	// Инициализируем базу данных
-	if err := database.InitDatabase(); err != nil {
-		log.Fatal("Failed to initialize database:", err)
-	}
+// ensureSetup подключает бота и хранилища из окружения, если их еще не подключили через configure
+func ensureSetup() {
+	setupOnce.Do(func() {
+		// Тест подключает поддельный Bot API и хранилища в памяти сам
+		if registry != nil {
+			return
+		}
+		setup()
+	})
+}
+
+// setup подключает базу данных, бота из TELEGRAM_TOKEN и хранилища в Postgres
+func setup() {
+	loadEnvFile()
+	connectDatabase(true)
 
 	// Инициализируем бота
 	token := os.Getenv("TELEGRAM_TOKEN")
@@ -65,14 +108,40 @@ func init() {
 		panic("TELEGRAM_TOKEN environment variable is not set")
 	}
 
-	var err error
-	botInstance, err = tgbotapi.NewBotAPI(token)
+	// Адрес Bot API можно переопределить, например, для локального тестового сервера
+	apiEndpoint := os.Getenv("TELEGRAM_API_ENDPOINT")
+	if apiEndpoint == "" {
+		apiEndpoint = tgbotapi.APIEndpoint
+	}
+
+	bot, err := tgbotapi.NewBotAPIWithAPIEndpoint(token, apiEndpoint)
 	if err != nil {
 		panic(err)
 	}
 
+	configure(bot, repository.NewPostgresStores())
+}
+
+// connectDatabase подключает базу данных и, если migrate и не задано DB_AUTO_MIGRATE=false, приводит схему к актуальной версии
+func connectDatabase(migrate bool) {
+	if err := database.InitDatabase(); err != nil {
+		log.Fatal("Failed to initialize database:", err)
+	}
+
+	if migrate && os.Getenv("DB_AUTO_MIGRATE") != "false" {
+		if err := database.Migrate(context.Background()); err != nil {
+			log.Fatal("Failed to migrate database:", err)
+		}
+	}
+}
+
+// configure подключает бота и хранилища, с которыми Handler обрабатывает обновления
+func configure(bot *tgbotapi.BotAPI, stores *repository.Stores) {
+	botInstance = bot
+	updateStore = stores.Updates
+
 	// Создаем реестр обработчиков (автоматически регистрирует все обработчики)
-	registry = handlers.NewRegistry(botInstance)
+	registry = handlers.NewRegistry(bot, stores)
 }
 
 func loadEnvFile() {
@@ -104,17 +173,28 @@ func loadEnvFile() {
 }
 
 func Handler(ctx context.Context, request json.RawMessage) (*Response, error) {
-	var bodyData []byte
+	ensureSetup()
 
 	// Пробуем распарсить как обертку Yandex Cloud
+	// Обновление без обертки разбирается как обертка без httpMethod и без заголовков
 	var cloudRequest YandexCloudRequest
-	if err := json.Unmarshal(request, &cloudRequest); err != nil {
-		bodyData = []byte(request)
-	} else {
-		// Извлекаем тело из обертки Yandex Cloud
-		bodyData = []byte(cloudRequest.Body)
+	if err := json.Unmarshal(request, &cloudRequest); err != nil || cloudRequest.HTTPMethod == "" {
+		// Вызов таймером: выполняем наступившие отложенные задачи
+		if isTimerTrigger(request) {
+			ran := registry.RunDueJobs(ctx)
+			return &Response{StatusCode: 200, Body: fmt.Sprintf("Ran %d jobs", ran)}, nil
+		}
+		cloudRequest = YandexCloudRequest{Body: string(request)}
 	}
 
+	// Проверяем, что запрос пришел от Telegram; обновление без обертки не несет секрета и при настроенном секрете отклоняется
+	if !hasValidSecretToken(cloudRequest.Headers) {
+		return &Response{StatusCode: 401, Body: "Unauthorized"}, nil
+	}
+
+	// Извлекаем тело из обертки Yandex Cloud
+	bodyData := []byte(cloudRequest.Body)
+
 	if len(bodyData) == 0 {
 		return &Response{StatusCode: 400, Body: "Empty body"}, nil
 	}
@@ -124,43 +204,236 @@ func Handler(ctx context.Context, request json.RawMessage) (*Response, error) {
 		return &Response{StatusCode: 400, Body: "Bad request"}, nil
 	}
 
-	if update.Message != nil {
-		handleMessage(update.Message)
-	} else if update.CallbackQuery != nil {
+	// Telegram повторяет доставку, если функция не ответила вовремя: повтор обработанного обновления подтверждаем,
+	// а повтор обновления, которое еще обрабатывается, просим прислать позже
+	switch claimUpdate(update.UpdateID) {
+	case repository.UpdateProcessed:
+		return &Response{StatusCode: 200, Body: "OK"}, nil
+	case repository.UpdateBusy:
+		return &Response{StatusCode: 503, Body: "Update is being processed"}, nil
+	}
+
+	if update.CallbackQuery != nil {
 		cloudLog(bodyData, update.CallbackQuery.Data)
-		handleCallbackQuery(update.CallbackQuery)
+	} else if update.PreCheckoutQuery != nil {
+		cloudLog(bodyData, update.PreCheckoutQuery.InvoicePayload)
+	}
+
+	registry.HandleUpdate(&update)
+
+	// Отмечаем обновление только после обработки, чтобы повтор прерванного вызова не потерялся
+	markProcessed(update.UpdateID)
+
+	// Таймер вызывает функцию раз в минуту, а отсчет в блице короче: заодно выполняем наступившие задачи этого чата.
+	// Чужие задачи остаются таймеру, чтобы ответ Telegram не ждал всю очередь
+	if chat := update.FromChat(); chat != nil {
+		registry.RunDueChatJobs(ctx, chat.ID)
 	}
 
 	return &Response{StatusCode: 200, Body: "OK"}, nil
 }
 
-func handleMessage(message *tgbotapi.Message) {
-	if message.IsCommand() {
-		// Обрабатываем команду
-		if err := registry.HandleCommand(message.Command(), message); err != nil {
-			fmt.Printf("Failed to handle command %s: %v\n", message.Command(), err)
+// isTimerTrigger проверяет, что функцию вызвал таймер, а не Telegram
+func isTimerTrigger(request json.RawMessage) bool {
+	var trigger TimerTriggerRequest
+	if err := json.Unmarshal(request, &trigger); err != nil || len(trigger.Messages) == 0 {
+		return false
+	}
+	return trigger.Messages[0].EventMetadata.EventType == timerEventType
+}
+
+// runScheduler выполняет отложенные задачи с интервалом SCHEDULER_INTERVAL (по умолчанию 1 секунда) до завершения ctx.
+// Нужен там, где нет таймера Yandex Cloud: в режиме long polling и на локальном сервере
+func runScheduler(ctx context.Context) {
+	interval, err := time.ParseDuration(os.Getenv("SCHEDULER_INTERVAL"))
+	if err != nil || interval <= 0 {
+		interval = time.Second
+	}
+
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+			registry.RunDueJobs(ctx)
 		}
-	} else {
-		// Обрабатываем текстовое сообщение
-		if err := registry.HandleTextMessage(message); err != nil {
-			fmt.Printf("Failed to handle text message: %v\n", err)
+	}
+}
+
+// hasValidSecretToken сверяет секрет вебхука с TELEGRAM_SECRET_TOKEN; без настроенного секрета проверка отключена
+func hasValidSecretToken(headers map[string]string) bool {
+	secret := os.Getenv("TELEGRAM_SECRET_TOKEN")
+	if secret == "" {
+		return true
+	}
+
+	for name, value := range headers {
+		if strings.EqualFold(name, secretTokenHeader) {
+			return subtle.ConstantTimeCompare([]byte(value), []byte(secret)) == 1
 		}
 	}
+	return false
 }
 
-func handleCallbackQuery(callback *tgbotapi.CallbackQuery) {
-	// Обрабатываем callback
-	if err := registry.HandleCallback(callback.Data, callback); err != nil {
-		fmt.Printf("Failed to handle callback %s: %v\n", callback.Data, err)
+// claimUpdate занимает обновление для обработки на updateLease.
+// Если состояние обновления прочитать не удалось, обновление все равно обрабатывается
+func claimUpdate(updateID int) repository.UpdateClaim {
+	claim, err := updateStore.Claim(updateID, updateLease)
+	if err != nil {
+		fmt.Printf("Failed to claim update: %v (update_id: %d)\n", err, updateID)
+		return repository.UpdateClaimed
+	}
+	switch claim {
+	case repository.UpdateProcessed:
+		fmt.Printf("Skipping duplicate update (update_id: %d)\n", updateID)
+	case repository.UpdateBusy:
+		fmt.Printf("Update is still being processed (update_id: %d)\n", updateID)
+	}
+
+	if claim == repository.UpdateClaimed && updateID%processedUpdatesCleanupEvery == 0 {
+		if _, err := updateStore.DeleteOlderThan(processedUpdatesTTL()); err != nil {
+			fmt.Printf("Failed to delete old processed updates: %v\n", err)
+		}
+	}
+
+	return claim
+}
+
+// markProcessed отмечает обработанное обновление, чтобы его повторная доставка подтверждалась без обработки
+func markProcessed(updateID int) {
+	if err := updateStore.MarkProcessed(updateID); err != nil {
+		fmt.Printf("Failed to mark update as processed: %v (update_id: %d)\n", err, updateID)
 	}
 }
 
+// processedUpdatesTTL сколько хранить отметки обработанных обновлений (PROCESSED_UPDATES_TTL, по умолчанию 24 часа)
+func processedUpdatesTTL() time.Duration {
+	ttl, err := time.ParseDuration(os.Getenv("PROCESSED_UPDATES_TTL"))
+	if err != nil || ttl <= 0 {
+		return 24 * time.Hour
+	}
+	return ttl
+}
+
 func main() {
-	if os.Getenv("LOCAL_TEST") == "true" {
-		startLocalServer()
+	// Для подкоманд бот не нужен; migrate сам управляет схемой
+	if isMigrateCommand() {
+		loadEnvFile()
+		connectDatabase(false)
+		runMigrate(os.Args[2:])
+		return
+	}
+	if isRecomputeRatingsCommand() {
+		loadEnvFile()
+		connectDatabase(true)
+		runRecomputeRatings()
+		return
+	}
+
+	ensureSetup()
+
+	mode := flag.String("mode", "webhook", "режим запуска: webhook (локальный сервер при LOCAL_TEST=true) или poll")
+	flag.Parse()
+
+	switch *mode {
+	case "poll":
+		runPolling()
+	case "webhook":
+		if os.Getenv("LOCAL_TEST") == "true" {
+			startLocalServer()
+		}
+	default:
+		log.Fatalf("Unknown mode: %s (expected webhook or poll)", *mode)
+	}
+}
+
+// runPolling получает обновления через getUpdates до SIGINT или SIGTERM
+func runPolling() {
+	ctx, stop := signal.NotifyContext(context.Background(), syscall.SIGINT, syscall.SIGTERM)
+	defer stop()
+
+	workers, err := strconv.Atoi(os.Getenv("POLL_WORKERS"))
+	if err != nil || workers <= 0 {
+		workers = 4
+	}
+
+	poller := polling.NewPoller(botInstance, registry.HandleUpdate, workers)
+	go runScheduler(ctx)
+
+	fmt.Printf("Polling updates as @%s with %d workers\n", botInstance.Self.UserName, workers)
+	if err := poller.Run(ctx); err != nil {
+		log.Fatal("Polling stopped:", err)
+	}
+	fmt.Println("Polling stopped")
+}
+
+// isMigrateCommand проверяет, запущен ли бинарник с подкомандой migrate
+func isMigrateCommand() bool {
+	return len(os.Args) > 1 && os.Args[1] == "migrate"
+}
+
+// runMigrate выполняет подкоманду migrate [up|down [N]|status]
+func runMigrate(args []string) {
+	ctx := context.Background()
+
+	action := "up"
+	if len(args) > 0 {
+		action = args[0]
+	}
+
+	switch action {
+	case "up":
+		if err := database.Migrate(ctx); err != nil {
+			log.Fatal("Failed to migrate database:", err)
+		}
+		fmt.Println("Database is up to date")
+	case "down":
+		steps := 1
+		if len(args) > 1 {
+			n, err := strconv.Atoi(args[1])
+			if err != nil || n <= 0 {
+				log.Fatalf("Invalid number of steps: %s", args[1])
+			}
+			steps = n
+		}
+		if err := database.MigrateDown(ctx, steps); err != nil {
+			log.Fatal("Failed to roll back database:", err)
+		}
+	case "status":
+		states, err := database.MigrationStatus(ctx)
+		if err != nil {
+			log.Fatal("Failed to get migration status:", err)
+		}
+		for _, state := range states {
+			status := "pending"
+			if state.AppliedAt != nil {
+				status = "applied " + state.AppliedAt.Format("2006-01-02 15:04:05")
+			}
+			fmt.Printf("%04d_%-30s %s\n", state.Version, state.Name, status)
+		}
+	default:
+		log.Fatalf("Unknown migrate action: %s (expected up, down or status)", action)
 	}
 }
 
+// isRecomputeRatingsCommand проверяет, запущен ли бинарник с подкомандой recompute-ratings
+func isRecomputeRatingsCommand() bool {
+	return len(os.Args) > 1 && os.Args[1] == "recompute-ratings"
+}
+
+// runRecomputeRatings пересчитывает счетчики и рейтинг всех опубликованных вопросов,
+// например после изменения формулы рейтинга
+func runRecomputeRatings() {
+	updated, err := repository.NewQuestionRepository().RecomputeRatings()
+	if err != nil {
+		log.Fatal("Failed to recompute question ratings:", err)
+	}
+	fmt.Printf("Recomputed ratings of %d questions\n", updated)
+}
+
 func startLocalServer() {
 	port := os.Getenv("PORT")
 	if port == "" {
@@ -179,7 +452,18 @@ func startLocalServer() {
 			return
 		}
 
-		response, err := Handler(r.Context(), json.RawMessage(body))
+		// Передаем запрос в той же обертке, что и Yandex Cloud, чтобы проверялся секрет вебхука
+		headers := make(map[string]string, len(r.Header))
+		for name := range r.Header {
+			headers[name] = r.Header.Get(name)
+		}
+		request, err := json.Marshal(YandexCloudRequest{HTTPMethod: r.Method, Headers: headers, Body: string(body)})
+		if err != nil {
+			http.Error(w, err.Error(), http.StatusInternalServerError)
+			return
+		}
+
+		response, err := Handler(r.Context(), request)
 		if err != nil {
 			http.Error(w, err.Error(), http.StatusInternalServerError)
 			return
@@ -193,6 +477,8 @@ func startLocalServer() {
 		}
 	})
 
+	go runScheduler(context.Background())
+
 	fmt.Printf("Local server starting on port %s\n", port)
 	log.Fatal(http.ListenAndServe(":"+port, nil))
 }
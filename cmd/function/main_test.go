@@ -0,0 +1,228 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"qweasley/internal/models"
+	"qweasley/internal/repository"
+	"qweasley/internal/repository/memory"
+	"qweasley/internal/telegramtest"
+	"strings"
+	"testing"
+)
+
+// newTestScenario подключает Handler к поддельному Bot API и хранилищам в памяти с опубликованными вопросами
+func newTestScenario(t *testing.T, questions ...*models.Question) (*telegramtest.Scenario, *telegramtest.Server, *repository.Stores) {
+	t.Helper()
+
+	server := telegramtest.NewServer()
+	t.Cleanup(server.Close)
+
+	bot, err := server.NewBot()
+	if err != nil {
+		t.Fatalf("failed to create bot: %v", err)
+	}
+
+	stores := memory.NewStores()
+	for _, question := range questions {
+		question.IsPublished = true
+		if err := stores.Questions.Create(question); err != nil {
+			t.Fatalf("failed to create question: %v", err)
+		}
+	}
+	configure(bot, stores)
+
+	scenario := &telegramtest.Scenario{
+		Server: server,
+		Dispatch: func(update json.RawMessage) error {
+			response, err := Handler(context.Background(), update)
+			if err != nil {
+				return err
+			}
+			if response.StatusCode != 200 {
+				return fmt.Errorf("unexpected status %d: %v", response.StatusCode, response.Body)
+			}
+			return nil
+		},
+	}
+	return scenario, server, stores
+}
+
+func TestHandlerQuestionFlow(t *testing.T) {
+	const chatID = 1001
+
+	scenario, server, _ := newTestScenario(t,
+		&models.Question{Text: "Столица Франции?", Answer: "Париж"},
+		&models.Question{Text: "Столица Италии?", Answer: "Рим"},
+	)
+
+	err := scenario.Run(
+		telegramtest.Step{
+			Name:   "start",
+			Update: telegramtest.CommandUpdate(chatID, "/start"),
+			Expect: telegramtest.ExpectAll(
+				telegramtest.ExpectText(chatID, "Столица"),
+				telegramtest.ExpectButton(chatID, "skip"),
+			),
+		},
+		telegramtest.Step{
+			Name:   "wrong answer",
+			Update: telegramtest.TextUpdate(chatID, "Лондон"),
+			Expect: telegramtest.ExpectText(chatID, "Ответ неверный"),
+		},
+	)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// Вопрос выбирается случайно: правильный ответ берем по тексту последнего заданного вопроса
+	answer := ""
+	for _, call := range server.Sent(chatID) {
+		if _, ok := call.Button("skip"); !ok {
+			continue
+		}
+		answer = "Париж"
+		if strings.Contains(call.Text(), "Италии") {
+			answer = "Рим"
+		}
+	}
+	if answer == "" {
+		t.Fatal("no question sent")
+	}
+
+	err = scenario.Run(telegramtest.Step{
+		Name:   "correct answer",
+		Update: telegramtest.TextUpdate(chatID, answer),
+		Expect: telegramtest.ExpectAll(
+			telegramtest.ExpectText(chatID, "Это правильный ответ"),
+			telegramtest.ExpectButton(chatID, "continue"),
+		),
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	result, _ := server.LastSent(chatID)
+	continueData, ok := result.Button("continue")
+	if !ok {
+		t.Fatal("no continue button after the correct answer")
+	}
+
+	err = scenario.Run(telegramtest.Step{
+		Name:   "continue",
+		Update: telegramtest.CallbackUpdate(chatID, 0, continueData),
+		Expect: telegramtest.ExpectAll(
+			telegramtest.ExpectText(chatID, "Столица"),
+			telegramtest.ExpectButton(chatID, "skip"),
+		),
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+}
+
+// expectPreCheckoutAnswer проверяет, что на pre_checkout_query ответили подтверждением или отказом
+func expectPreCheckoutAnswer(ok bool) func(calls []telegramtest.Call) error {
+	return func(calls []telegramtest.Call) error {
+		for _, call := range calls {
+			if call.Method != "answerPreCheckoutQuery" {
+				continue
+			}
+			if got := call.Params["ok"]; got != fmt.Sprint(ok) {
+				return fmt.Errorf("answerPreCheckoutQuery ok=%s, want %t", got, ok)
+			}
+			if !ok && call.Params["error_message"] == "" {
+				return fmt.Errorf("answerPreCheckoutQuery rejected without error_message")
+			}
+			return nil
+		}
+		return fmt.Errorf("no answerPreCheckoutQuery among %d calls", len(calls))
+	}
+}
+
+// expectNoText проверяет, что в чат не отправлено сообщение с подстрокой
+func expectNoText(chatID int64, substring string) func(calls []telegramtest.Call) error {
+	return func(calls []telegramtest.Call) error {
+		if telegramtest.ExpectText(chatID, substring)(calls) == nil {
+			return fmt.Errorf("unexpected message to chat %d containing %q", chatID, substring)
+		}
+		return nil
+	}
+}
+
+func TestHandlerPurchaseFlow(t *testing.T) {
+	const (
+		chatID   = 1001
+		payload  = "coins:10"
+		chargeID = "charge-1"
+	)
+
+	// Без провайдера пакет продается за Telegram Stars: 10 монет по 5 звезд
+	t.Setenv("PAYMENT_PROVIDER_TOKEN", "")
+	t.Setenv("PAYMENT_STARS_PER_COIN", "5")
+
+	scenario, _, stores := newTestScenario(t)
+
+	chat, err := stores.Chats.GetOrCreate(chatID, nil)
+	if err != nil {
+		t.Fatalf("failed to create chat: %v", err)
+	}
+	balance := chat.Balance
+
+	err = scenario.Run(
+		telegramtest.Step{
+			Name:   "pre_checkout with the pack price",
+			Update: telegramtest.PreCheckoutUpdate(chatID, "XTR", 50, payload),
+			Expect: expectPreCheckoutAnswer(true),
+		},
+		telegramtest.Step{
+			Name:   "pre_checkout in another currency",
+			Update: telegramtest.PreCheckoutUpdate(chatID, "RUB", 50, payload),
+			Expect: expectPreCheckoutAnswer(false),
+		},
+		telegramtest.Step{
+			Name:   "pre_checkout with another amount",
+			Update: telegramtest.PreCheckoutUpdate(chatID, "XTR", 45, payload),
+			Expect: expectPreCheckoutAnswer(false),
+		},
+		telegramtest.Step{
+			Name:   "successful payment",
+			Update: telegramtest.SuccessfulPaymentUpdate(chatID, "XTR", 50, payload, chargeID),
+			Expect: telegramtest.ExpectText(chatID, "Спасибо за покупку"),
+		},
+		telegramtest.Step{
+			Name:   "repeated successful payment",
+			Update: telegramtest.SuccessfulPaymentUpdate(chatID, "XTR", 50, payload, chargeID),
+			Expect: expectNoText(chatID, "Спасибо за покупку"),
+		},
+	)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	chat, err = stores.Chats.GetOrCreate(chatID, nil)
+	if err != nil {
+		t.Fatalf("failed to get chat: %v", err)
+	}
+	if chat.Balance != balance+10 {
+		t.Fatalf("balance = %d, want %d", chat.Balance, balance+10)
+	}
+
+	history, err := stores.Ledger.GetHistory(chat.ID, 10)
+	if err != nil {
+		t.Fatalf("failed to get history: %v", err)
+	}
+	purchases := 0
+	for _, entry := range history {
+		if entry.Reason == models.ReasonPurchase {
+			purchases++
+			if entry.Delta != 10 {
+				t.Fatalf("purchase entry delta = %d, want 10", entry.Delta)
+			}
+		}
+	}
+	if purchases != 1 {
+		t.Fatalf("purchase ledger entries = %d, want 1", purchases)
+	}
+}
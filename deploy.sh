@@ -113,7 +113,24 @@ SSL_MODE="require",\
 SSL_CERT_PATH="/etc/ssl/certs/ca-certificates.crt",\
 AWS_S3_ENTRYPOINT="$AWS_S3_ENTRYPOINT",\
 AWS_S3_BUCKET="$AWS_S3_BUCKET",\
-ADMIN_CHAT_ID="$ADMIN_CHAT_ID"
+ADMIN_CHAT_ID="$ADMIN_CHAT_ID",\
+PAYMENT_PROVIDER_TOKEN="$PAYMENT_PROVIDER_TOKEN",\
+PAYMENT_STARS_PER_COIN="$PAYMENT_STARS_PER_COIN",\
+TELEGRAM_SECRET_TOKEN="$TELEGRAM_SECRET_TOKEN",\
+PROCESSED_UPDATES_TTL="$PROCESSED_UPDATES_TTL",\
+CALLBACK_SECRET="$CALLBACK_SECRET",\
+ANSWER_TYPO_RATIO="$ANSWER_TYPO_RATIO",\
+ANSWER_TYPO_MIN_LENGTH="$ANSWER_TYPO_MIN_LENGTH",\
+HINT_COST="$HINT_COST",\
+ANSWER_MAX_ATTEMPTS="${ANSWER_MAX_ATTEMPTS:-5}",\
+ANSWER_WRONG_PENALTY="$ANSWER_WRONG_PENALTY",\
+BLITZ_TIMEOUT="$BLITZ_TIMEOUT",\
+BLITZ_TICK="$BLITZ_TICK",\
+BLITZ_BONUS_WINDOW="$BLITZ_BONUS_WINDOW",\
+BLITZ_BONUS="$BLITZ_BONUS",\
+DAILY_HOUR="$DAILY_HOUR",\
+DAILY_WINDOW="$DAILY_WINDOW",\
+DAILY_RATE="$DAILY_RATE"
 
 # Получение URL и настройка webhook
 FUNCTION_ID=$(yc serverless function get $FUNCTION_NAME --folder-id=$FOLDER_ID --format=json | jq -r '.id')
@@ -121,6 +138,28 @@ INVOKE_URL="https://functions.yandexcloud.net/$FUNCTION_ID"
 
 echo "✅ Функция развернута: $INVOKE_URL"
 
+# Секрет вебхука: функция отклоняет запросы без заголовка X-Telegram-Bot-Api-Secret-Token
+if [ ! -z "$TELEGRAM_SECRET_TOKEN" ]; then
+    echo "🔐 Настройка webhook с секретом..."
+    curl -s -o /dev/null \
+        -F "url=$INVOKE_URL" \
+        -F "secret_token=$TELEGRAM_SECRET_TOKEN" \
+        "https://api.telegram.org/bot$TELEGRAM_TOKEN/setWebhook"
+fi
+
+# Таймер раз в минуту запускает отложенные задачи (истечение времени в блице, рассылка вопроса дня).
+# Чаще таймер не срабатывает, поэтому в режиме вебхука обратный отсчет блица обновляется раз в минуту
+TRIGGER_NAME="$FUNCTION_NAME-scheduler"
+if ! yc serverless trigger get $TRIGGER_NAME --folder-id=$FOLDER_ID &> /dev/null; then
+    echo "⏰ Создание таймера для отложенных задач..."
+    yc serverless trigger create timer \
+        --name=$TRIGGER_NAME \
+        --folder-id=$FOLDER_ID \
+        --cron-expression="* * * * ? *" \
+        --invoke-function-name=$FUNCTION_NAME \
+        --invoke-function-service-account-id=$SERVICE_ACCOUNT_ID > /dev/null
+fi
+
 # Очистка старых версий (оставляем только последние 3)
 echo "🧹 Очистка старых версий..."
 OLD_VERSIONS=$(yc serverless function version list --function-name=$FUNCTION_NAME --folder-id=$FOLDER_ID --format=json | jq -r '.[3:] | .[].id')
@@ -0,0 +1,29 @@
+package answer
+
+// AttemptPolicy ограничивает неверные ответы на один вопрос
+type AttemptPolicy struct {
+	// MaxAttempts число неверных ответов, после которого ответ показывается автоматически; 0 — без ограничения
+	MaxAttempts int
+	// Penalty списание в монетах за каждый неверный ответ
+	Penalty int
+}
+
+// NewAttemptPolicy создает ограничение попыток с настройками из переменных окружения
+func NewAttemptPolicy() *AttemptPolicy {
+	return &AttemptPolicy{
+		MaxAttempts: getEnvInt("ANSWER_MAX_ATTEMPTS", 0),
+		Penalty:     getEnvInt("ANSWER_WRONG_PENALTY", 0),
+	}
+}
+
+// Limits возвращает лимит попыток и штраф с учетом настроек конкретного вопроса (nil — общее значение)
+func (p *AttemptPolicy) Limits(maxAttempts, penalty *int) (int, int) {
+	limit, charge := p.MaxAttempts, p.Penalty
+	if maxAttempts != nil && *maxAttempts >= 0 {
+		limit = *maxAttempts
+	}
+	if penalty != nil && *penalty >= 0 {
+		charge = *penalty
+	}
+	return limit, charge
+}
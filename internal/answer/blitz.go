@@ -0,0 +1,56 @@
+package answer
+
+import (
+	"os"
+	"time"
+)
+
+// BlitzPolicy настройки блица: короткое время на вопрос и бонус за быстрый ответ
+type BlitzPolicy struct {
+	// Timeout время на ответ
+	Timeout time.Duration
+	// Tick как часто обновлять обратный отсчет в сообщении с вопросом
+	Tick time.Duration
+	// BonusWindow за сколько времени с показа вопроса нужно ответить, чтобы получить бонус
+	BonusWindow time.Duration
+	// Bonus сколько монет вернуть за быстрый ответ
+	Bonus int
+}
+
+// NewBlitzPolicy создает настройки блица из переменных окружения
+func NewBlitzPolicy() *BlitzPolicy {
+	return &BlitzPolicy{
+		Timeout:     getEnvDuration("BLITZ_TIMEOUT", 60*time.Second),
+		Tick:        getEnvDuration("BLITZ_TICK", 15*time.Second),
+		BonusWindow: getEnvDuration("BLITZ_BONUS_WINDOW", 15*time.Second),
+		Bonus:       getEnvInt("BLITZ_BONUS", 1),
+	}
+}
+
+// Ticks возвращает моменты обновления отсчета, отсчитанные от показа вопроса (без момента истечения)
+func (p *BlitzPolicy) Ticks() []time.Duration {
+	var ticks []time.Duration
+	if p.Tick <= 0 {
+		return ticks
+	}
+	for at := p.Tick; at < p.Timeout; at += p.Tick {
+		ticks = append(ticks, at)
+	}
+	return ticks
+}
+
+// SpeedBonus возвращает бонус за ответ, данный через elapsed после показа вопроса
+func (p *BlitzPolicy) SpeedBonus(elapsed time.Duration) int {
+	if elapsed < 0 || elapsed > p.BonusWindow {
+		return 0
+	}
+	return p.Bonus
+}
+
+// getEnvDuration читает длительность (например, "60s") из переменной окружения
+func getEnvDuration(key string, defaultValue time.Duration) time.Duration {
+	if value, err := time.ParseDuration(os.Getenv(key)); err == nil && value > 0 {
+		return value
+	}
+	return defaultValue
+}
@@ -0,0 +1,56 @@
+package answer
+
+import (
+	"time"
+)
+
+// Ограничения вопроса дня: окно для ответа не длиннее суток, чтобы закрыться до следующего вопроса,
+// а скорость рассылки не выше общего лимита Telegram (30 сообщений в секунду)
+const (
+	dailyMaxWindow = 24 * time.Hour
+	dailyMaxRate   = 30
+)
+
+// DailyPolicy настройки вопроса дня
+type DailyPolicy struct {
+	// Hour час (UTC), в который рассылается вопрос дня
+	Hour int
+	// Window сколько после начала рассылки принимаются ответы
+	Window time.Duration
+	// Rate сколько сообщений в секунду отправлять при рассылке
+	Rate int
+}
+
+// NewDailyPolicy создает настройки вопроса дня из переменных окружения
+func NewDailyPolicy() *DailyPolicy {
+	hour := getEnvInt("DAILY_HOUR", 9)
+	if hour > 23 {
+		hour = 9
+	}
+
+	rate := getEnvInt("DAILY_RATE", 25)
+	if rate <= 0 || rate > dailyMaxRate {
+		rate = dailyMaxRate
+	}
+
+	return &DailyPolicy{
+		Hour:   hour,
+		Window: min(getEnvDuration("DAILY_WINDOW", 12*time.Hour), dailyMaxWindow),
+		Rate:   rate,
+	}
+}
+
+// NextStart возвращает ближайшее после after время рассылки
+func (p *DailyPolicy) NextStart(after time.Time) time.Time {
+	after = after.UTC()
+	start := time.Date(after.Year(), after.Month(), after.Day(), p.Hour, 0, 0, 0, time.UTC)
+	if !start.After(after) {
+		start = start.AddDate(0, 0, 1)
+	}
+	return start
+}
+
+// SendInterval минимальный промежуток между сообщениями рассылки
+func (p *DailyPolicy) SendInterval() time.Duration {
+	return time.Second / time.Duration(p.Rate)
+}
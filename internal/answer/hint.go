@@ -0,0 +1,99 @@
+package answer
+
+import (
+	"math"
+	"strings"
+	"unicode"
+)
+
+// costEpsilon погрешность при сложении дробной стоимости подсказок
+const costEpsilon = 1e-9
+
+// Hinter строит последовательность подсказок к ответу и считает их стоимость
+type Hinter struct {
+	// Cost стоимость одной подсказки в монетах, может быть дробной (например, 0.5)
+	Cost float64
+}
+
+// NewHinter создает построитель подсказок с настройками из переменных окружения
+func NewHinter() *Hinter {
+	return &Hinter{
+		Cost: getEnvFloat("HINT_COST", 0.5),
+	}
+}
+
+// Hints возвращает подсказки по порядку: длина ответа прочерками, затем первая буква,
+// затем по одной дополнительной букве, пока не открыта половина букв ответа
+func (h *Hinter) Hints(answer string) []string {
+	runes := []rune(strings.TrimSpace(answer))
+
+	// Порядок открытия букв: первые буквы слов, последняя буква, затем остальные слева направо
+	var order, rest []int
+	wordStart := true
+	for i, r := range runes {
+		if !isHintLetter(r) {
+			wordStart = true
+			continue
+		}
+		if wordStart {
+			order = append(order, i)
+		} else {
+			rest = append(rest, i)
+		}
+		wordStart = false
+	}
+	if len(order) == 0 {
+		return nil
+	}
+
+	letters := len(order) + len(rest)
+	if len(rest) > 0 {
+		last := rest[len(rest)-1]
+		order = append(order, last)
+		order = append(order, rest[:len(rest)-1]...)
+	}
+
+	revealed := make(map[int]bool, letters)
+	hints := []string{mask(runes, revealed)}
+	for _, i := range order[:letters/2] {
+		revealed[i] = true
+		hints = append(hints, mask(runes, revealed))
+	}
+	return hints
+}
+
+// Coins возвращает, сколько целых монет списано за подсказки общей стоимостью spent
+func (h *Hinter) Coins(spent float64) int {
+	coins := int(math.Ceil(spent - costEpsilon))
+	if coins < 0 {
+		return 0
+	}
+	return coins
+}
+
+// Charge возвращает, сколько целых монет списать за подсказку, после которой
+// суммарная стоимость подсказок стала spent: дробные доли копятся и списываются при переходе через целое
+func (h *Hinter) Charge(spent float64) int {
+	return h.Coins(spent) - h.Coins(spent-h.Cost)
+}
+
+// mask заменяет неоткрытые буквы прочерками; символы разделены пробелами, чтобы была видна длина
+func mask(runes []rune, revealed map[int]bool) string {
+	parts := make([]string, len(runes))
+	for i, r := range runes {
+		switch {
+		case unicode.IsSpace(r):
+			parts[i] = " "
+		case isHintLetter(r) && !revealed[i]:
+			parts[i] = "_"
+		default:
+			parts[i] = string(r)
+		}
+	}
+	return strings.Join(parts, " ")
+}
+
+// isHintLetter проверяет, скрывается ли символ в подсказке
+func isHintLetter(r rune) bool {
+	return unicode.IsLetter(r) || unicode.IsDigit(r)
+}
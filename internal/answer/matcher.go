@@ -0,0 +1,136 @@
+package answer
+
+import (
+	"os"
+	"strconv"
+	"strings"
+	"unicode"
+)
+
+// Verdict результат проверки ответа
+type Verdict int
+
+const (
+	// Wrong ответ не принят
+	Wrong Verdict = iota
+	// Exact ответ совпал с одним из вариантов после нормализации
+	Exact
+	// Close ответ принят с опечатками
+	Close
+)
+
+// Result результат сравнения ответа с вариантами
+type Result struct {
+	Verdict Verdict
+	// Expected вариант ответа, с которым совпал ввод
+	Expected string
+	// Distance число исправлений между вводом и вариантом
+	Distance int
+}
+
+// Accepted возвращает true, если ответ засчитан
+func (r Result) Accepted() bool {
+	return r.Verdict != Wrong
+}
+
+// Matcher сравнивает ответы с допустимыми вариантами
+type Matcher struct {
+	// TypoRatio допустимое число опечаток на одну букву ответа
+	TypoRatio float64
+	// MinLength минимальная длина ответа, начиная с которой прощаются опечатки
+	MinLength int
+}
+
+// NewMatcher создает сравниватель ответов с настройками из переменных окружения
+func NewMatcher() *Matcher {
+	return &Matcher{
+		TypoRatio: getEnvFloat("ANSWER_TYPO_RATIO", 0.2),
+		MinLength: getEnvInt("ANSWER_TYPO_MIN_LENGTH", 4),
+	}
+}
+
+// Match сравнивает ответ пользователя со списком допустимых вариантов
+func (m *Matcher) Match(input string, variants ...string) Result {
+	normalizedInput := Normalize(input)
+	if normalizedInput == "" {
+		return Result{Verdict: Wrong}
+	}
+
+	best := Result{Verdict: Wrong}
+	for _, variant := range variants {
+		normalizedVariant := Normalize(variant)
+		if normalizedVariant == "" {
+			continue
+		}
+
+		if normalizedInput == normalizedVariant {
+			return Result{Verdict: Exact, Expected: variant}
+		}
+
+		// Числа (годы, количества) должны совпасть точно: опечатки прощаются только в словах
+		inputWords, inputNumbers := splitNumbers(normalizedInput)
+		variantWords, variantNumbers := splitNumbers(normalizedVariant)
+		if inputNumbers != variantNumbers {
+			continue
+		}
+
+		distance := Distance(inputWords, variantWords)
+		if distance > m.Tolerance(variantWords) {
+			continue
+		}
+
+		if best.Verdict == Wrong || distance < best.Distance {
+			best = Result{Verdict: Close, Expected: variant, Distance: distance}
+		}
+	}
+
+	return best
+}
+
+// Tolerance возвращает допустимое число опечаток для нормализованного ответа
+func (m *Matcher) Tolerance(normalized string) int {
+	length := len([]rune(normalized))
+	if length < m.MinLength {
+		return 0
+	}
+	return int(float64(length) * m.TypoRatio)
+}
+
+// splitNumbers разделяет нормализованный ответ на слова и числа — токены только из цифр — с сохранением порядка
+func splitNumbers(normalized string) (words string, numbers string) {
+	var wordTokens, numberTokens []string
+	for _, token := range strings.Fields(normalized) {
+		if isNumber(token) {
+			numberTokens = append(numberTokens, token)
+		} else {
+			wordTokens = append(wordTokens, token)
+		}
+	}
+	return strings.Join(wordTokens, " "), strings.Join(numberTokens, " ")
+}
+
+// isNumber проверяет, что токен состоит только из цифр
+func isNumber(token string) bool {
+	for _, r := range token {
+		if !unicode.IsDigit(r) {
+			return false
+		}
+	}
+	return token != ""
+}
+
+// getEnvFloat читает дробное число из переменной окружения
+func getEnvFloat(key string, defaultValue float64) float64 {
+	if value, err := strconv.ParseFloat(os.Getenv(key), 64); err == nil && value >= 0 {
+		return value
+	}
+	return defaultValue
+}
+
+// getEnvInt читает целое число из переменной окружения
+func getEnvInt(key string, defaultValue int) int {
+	if value, err := strconv.Atoi(os.Getenv(key)); err == nil && value >= 0 {
+		return value
+	}
+	return defaultValue
+}
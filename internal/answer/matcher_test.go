@@ -0,0 +1,133 @@
+package answer
+
+import (
+	"qweasley/internal/models"
+	"testing"
+)
+
+func TestNormalize(t *testing.T) {
+	tests := []struct {
+		name  string
+		input string
+		want  string
+	}{
+		{name: "yo becomes ye", input: "Ёлка и ёж", want: "елка и еж"},
+		{name: "punctuation and quotes", input: "«Война и мир»!", want: "война и мир"},
+		{name: "straight quotes", input: `"Тихий Дон"?..`, want: "тихий дон"},
+		{name: "hyphens split words", input: "Санкт-Петербург", want: "санкт петербург"},
+		{name: "extra spaces", input: "  много   пробелов ", want: "много пробелов"},
+		{name: "latin lookalikes in russian word", input: "Mосквa", want: "москва"},
+		{name: "word of lookalikes only", input: "OK", want: "ок"},
+		{name: "latin word stays latin", input: "Paris", want: "paris"},
+		{name: "only punctuation", input: "?!", want: ""},
+	}
+
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			if got := Normalize(tc.input); got != tc.want {
+				t.Fatalf("Normalize(%q) = %q, want %q", tc.input, got, tc.want)
+			}
+		})
+	}
+}
+
+func TestTolerance(t *testing.T) {
+	m := &Matcher{TypoRatio: 0.2, MinLength: 4}
+
+	tests := []struct {
+		answer string
+		want   int
+	}{
+		{answer: "кот", want: 0},
+		{answer: "слон", want: 0},
+		{answer: "париж", want: 1},
+		{answer: "екатерина", want: 1},
+		{answer: "пушкинский", want: 2},
+	}
+
+	for _, tc := range tests {
+		t.Run(tc.answer, func(t *testing.T) {
+			if got := m.Tolerance(tc.answer); got != tc.want {
+				t.Fatalf("Tolerance(%q) = %d, want %d", tc.answer, got, tc.want)
+			}
+		})
+	}
+}
+
+func TestMatch(t *testing.T) {
+	m := &Matcher{TypoRatio: 0.2, MinLength: 4}
+
+	tests := []struct {
+		name     string
+		input    string
+		variants []string
+		want     Verdict
+	}{
+		{name: "exact", input: "Париж", variants: []string{"Париж"}, want: Exact},
+		{name: "exact after yo", input: "ежик", variants: []string{"Ёжик"}, want: Exact},
+		{name: "exact after punctuation", input: "война и мир!", variants: []string{"«Война и мир»"}, want: Exact},
+		{name: "exact with latin lookalikes", input: "Пapиж", variants: []string{"Париж"}, want: Exact},
+		{name: "empty input", input: "...", variants: []string{"Париж"}, want: Wrong},
+
+		// До MinLength букв опечатки не прощаются
+		{name: "3 letters, 1 typo", input: "кит", variants: []string{"кот"}, want: Wrong},
+		{name: "4 letters, 1 typo", input: "слог", variants: []string{"слон"}, want: Wrong},
+		// 5-9 букв — одна опечатка, с 10 — две
+		{name: "5 letters, 1 typo", input: "парис", variants: []string{"Париж"}, want: Close},
+		{name: "5 letters, 2 typos", input: "пориш", variants: []string{"Париж"}, want: Wrong},
+		{name: "9 letters, 1 typo", input: "екатирина", variants: []string{"Екатерина"}, want: Close},
+		{name: "9 letters, 2 typos", input: "икатирина", variants: []string{"Екатерина"}, want: Wrong},
+		{name: "10 letters, 2 typos", input: "пушкенскей", variants: []string{"Пушкинский"}, want: Close},
+		{name: "10 letters, 3 typos", input: "пушкенскэя", variants: []string{"Пушкинский"}, want: Wrong},
+
+		{name: "transposition counts as one typo", input: "Праиж", variants: []string{"Париж"}, want: Close},
+		{name: "transposition in a long answer", input: "Мнеделеев", variants: []string{"Менделеев"}, want: Close},
+		{name: "two transpositions in 9 letters", input: "Кеатеирна", variants: []string{"Екатерина"}, want: Wrong},
+		{name: "two transpositions in 10 letters", input: "пушикнксий", variants: []string{"Пушкинский"}, want: Close},
+
+		// Числа должны совпасть точно, даже когда расстояние укладывается в допуск
+		{name: "exact number", input: "1812", variants: []string{"1812"}, want: Exact},
+		{name: "swapped digits", input: "1821", variants: []string{"1812"}, want: Wrong},
+		{name: "typo in words, same number", input: "Бородина 1812", variants: []string{"Бородино 1812"}, want: Close},
+		{name: "typo in number", input: "Бородино 1821", variants: []string{"Бородино 1812"}, want: Wrong},
+	}
+
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			if got := m.Match(tc.input, tc.variants...); got.Verdict != tc.want {
+				t.Fatalf("Match(%q, %q) = %+v, want verdict %d", tc.input, tc.variants, got, tc.want)
+			}
+		})
+	}
+}
+
+func TestMatchAcceptedAnswers(t *testing.T) {
+	m := &Matcher{TypoRatio: 0.2, MinLength: 4}
+	question := &models.Question{
+		Answer:   "Лев Толстой",
+		Variants: []models.AnswerVariant{{Text: "Толстой"}, {Text: "Л. Н. Толстой"}},
+	}
+
+	tests := []struct {
+		name     string
+		input    string
+		want     Verdict
+		expected string
+	}{
+		{name: "main answer", input: "лев толстой", want: Exact, expected: "Лев Толстой"},
+		{name: "variant", input: "ТОЛСТОЙ", want: Exact, expected: "Толстой"},
+		{name: "variant with initials", input: "Л Н Толстой", want: Exact, expected: "Л. Н. Толстой"},
+		{name: "typo in variant", input: "Толстый", want: Close, expected: "Толстой"},
+		{name: "typo in main answer", input: "Лев Тослтой", want: Close, expected: "Лев Толстой"},
+		{name: "another writer", input: "Достоевский", want: Wrong},
+	}
+
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			got := m.Match(tc.input, question.AcceptedAnswers()...)
+			if got.Verdict != tc.want || got.Expected != tc.expected {
+				t.Fatalf("Match(%q) = %+v, want verdict %d for %q", tc.input, got, tc.want, tc.expected)
+			}
+		})
+	}
+}
@@ -0,0 +1,89 @@
+package answer
+
+import (
+	"strings"
+	"unicode"
+)
+
+// lookalikes латинские буквы, которые выглядят как кириллические
+var lookalikes = map[rune]rune{
+	'A': 'А', 'a': 'а',
+	'B': 'В',
+	'C': 'С', 'c': 'с',
+	'E': 'Е', 'e': 'е',
+	'H': 'Н',
+	'K': 'К', 'k': 'к',
+	'M': 'М',
+	'O': 'О', 'o': 'о',
+	'P': 'Р', 'p': 'р',
+	'T': 'Т',
+	'X': 'Х', 'x': 'х',
+	'Y': 'У', 'y': 'у',
+}
+
+// Normalize приводит ответ к каноническому виду для сравнения:
+// нижний регистр, ё→е, без знаков препинания и лишних пробелов,
+// латинские буквы-двойники внутри русских слов заменяются кириллицей
+func Normalize(text string) string {
+	// Дефисы и прочие разделители превращаем в пробелы, остальную пунктуацию убираем
+	var cleaned strings.Builder
+	for _, r := range text {
+		switch {
+		case unicode.IsLetter(r) || unicode.IsDigit(r):
+			cleaned.WriteRune(r)
+		case unicode.IsSpace(r) || unicode.Is(unicode.Pd, r) || r == '/':
+			cleaned.WriteRune(' ')
+		}
+	}
+
+	words := strings.Fields(cleaned.String())
+	for i, word := range words {
+		words[i] = normalizeWord(word)
+	}
+
+	return strings.Join(words, " ")
+}
+
+// normalizeWord нормализует одно слово
+func normalizeWord(word string) string {
+	runes := []rune(word)
+
+	// Заменяем двойники, если слово явно русское или целиком состоит из двойников
+	if hasCyrillic(runes) || onlyLookalikes(runes) {
+		for i, r := range runes {
+			if replacement, ok := lookalikes[r]; ok {
+				runes[i] = replacement
+			}
+		}
+	}
+
+	for i, r := range runes {
+		r = unicode.ToLower(r)
+		if r == 'ё' {
+			r = 'е'
+		}
+		runes[i] = r
+	}
+
+	return string(runes)
+}
+
+// hasCyrillic проверяет, есть ли в слове кириллические буквы
+func hasCyrillic(runes []rune) bool {
+	for _, r := range runes {
+		if unicode.Is(unicode.Cyrillic, r) {
+			return true
+		}
+	}
+	return false
+}
+
+// onlyLookalikes проверяет, состоит ли слово только из букв-двойников
+func onlyLookalikes(runes []rune) bool {
+	for _, r := range runes {
+		if _, ok := lookalikes[r]; !ok {
+			return false
+		}
+	}
+	return len(runes) > 0
+}
@@ -0,0 +1,5 @@
+DROP TABLE IF EXISTS reactions;
+DROP TABLE IF EXISTS feedbacks;
+DROP TABLE IF EXISTS questions;
+DROP TABLE IF EXISTS pictures;
+DROP TABLE IF EXISTS chats;
@@ -0,0 +1,57 @@
+-- Исходная схема, которую раньше поддерживали вручную.
+-- IF NOT EXISTS позволяет применить миграцию к уже существующей базе
+CREATE TABLE IF NOT EXISTS chats (
+    id                  SERIAL PRIMARY KEY,
+    created_at          TIMESTAMPTZ NOT NULL DEFAULT NOW(),
+    balance             INTEGER     NOT NULL DEFAULT 0,
+    telegram_id         BIGINT      NOT NULL,
+    title               VARCHAR(255),
+    last_question_id    INTEGER,
+    expires_at          TIMESTAMPTZ,
+    feedback_expires_at TIMESTAMPTZ
+);
+
+CREATE UNIQUE INDEX IF NOT EXISTS idx_chats_telegram_id ON chats (telegram_id);
+
+CREATE TABLE IF NOT EXISTS pictures (
+    id         SERIAL PRIMARY KEY,
+    created_at TIMESTAMPTZ NOT NULL DEFAULT NOW(),
+    path       VARCHAR(255)
+);
+
+CREATE TABLE IF NOT EXISTS questions (
+    id                  SERIAL PRIMARY KEY,
+    text                TEXT    NOT NULL,
+    answer              TEXT    NOT NULL,
+    comment             TEXT,
+    author_id           INTEGER REFERENCES chats (id) ON DELETE SET NULL,
+    is_published        BOOLEAN NOT NULL DEFAULT FALSE,
+    question_picture_id INTEGER REFERENCES pictures (id) ON DELETE SET NULL,
+    answer_picture_id   INTEGER REFERENCES pictures (id) ON DELETE SET NULL,
+    approved_at         TIMESTAMPTZ,
+    rating              INTEGER DEFAULT 0
+);
+
+CREATE INDEX IF NOT EXISTS idx_questions_is_published ON questions (is_published);
+
+CREATE TABLE IF NOT EXISTS feedbacks (
+    id         SERIAL PRIMARY KEY,
+    created_at TIMESTAMPTZ NOT NULL DEFAULT NOW(),
+    text       TEXT        NOT NULL,
+    response   TEXT,
+    chat_id    INTEGER     NOT NULL REFERENCES chats (id) ON DELETE CASCADE
+);
+
+CREATE TABLE IF NOT EXISTS reactions (
+    id           SERIAL PRIMARY KEY,
+    created_at   TIMESTAMPTZ NOT NULL DEFAULT NOW(),
+    responsed_at TIMESTAMPTZ,
+    skipped_at   TIMESTAMPTZ,
+    failed_at    TIMESTAMPTZ,
+    chat_id      INTEGER     NOT NULL REFERENCES chats (id) ON DELETE CASCADE,
+    question_id  INTEGER     NOT NULL REFERENCES questions (id) ON DELETE CASCADE
+);
+
+-- На этот индекс опирается ON CONFLICT в Reaction.BeforeCreate
+CREATE UNIQUE INDEX IF NOT EXISTS idx_reactions_chat_question ON reactions (chat_id, question_id);
+CREATE INDEX IF NOT EXISTS idx_reactions_question_id ON reactions (question_id);
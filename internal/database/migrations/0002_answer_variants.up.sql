@@ -0,0 +1,7 @@
+CREATE TABLE IF NOT EXISTS answer_variants (
+    id          SERIAL PRIMARY KEY,
+    question_id INTEGER NOT NULL REFERENCES questions (id) ON DELETE CASCADE,
+    text        TEXT    NOT NULL
+);
+
+CREATE INDEX IF NOT EXISTS idx_answer_variants_question_id ON answer_variants (question_id);
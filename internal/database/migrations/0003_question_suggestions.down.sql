@@ -0,0 +1,13 @@
+ALTER TABLE pictures
+    DROP COLUMN IF EXISTS telegram_file_id;
+
+ALTER TABLE questions
+    DROP COLUMN IF EXISTS rejected_at;
+
+ALTER TABLE chats
+    DROP COLUMN IF EXISTS suggestion_question_id,
+    DROP COLUMN IF EXISTS suggestion_step,
+    DROP COLUMN IF EXISTS suggestion_expires_at,
+    DROP COLUMN IF EXISTS moderation_question_id,
+    DROP COLUMN IF EXISTS moderation_action,
+    DROP COLUMN IF EXISTS moderation_expires_at;
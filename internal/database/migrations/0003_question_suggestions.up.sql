@@ -0,0 +1,13 @@
+ALTER TABLE chats
+    ADD COLUMN IF NOT EXISTS suggestion_question_id INTEGER,
+    ADD COLUMN IF NOT EXISTS suggestion_step        VARCHAR(16),
+    ADD COLUMN IF NOT EXISTS suggestion_expires_at  TIMESTAMPTZ,
+    ADD COLUMN IF NOT EXISTS moderation_question_id INTEGER,
+    ADD COLUMN IF NOT EXISTS moderation_action      VARCHAR(16),
+    ADD COLUMN IF NOT EXISTS moderation_expires_at  TIMESTAMPTZ;
+
+ALTER TABLE questions
+    ADD COLUMN IF NOT EXISTS rejected_at TIMESTAMPTZ;
+
+ALTER TABLE pictures
+    ADD COLUMN IF NOT EXISTS telegram_file_id VARCHAR(255);
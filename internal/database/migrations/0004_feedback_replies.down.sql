@@ -0,0 +1,10 @@
+DROP INDEX IF EXISTS idx_feedbacks_unanswered;
+DROP INDEX IF EXISTS idx_feedbacks_admin_message_id;
+
+ALTER TABLE feedbacks
+    DROP COLUMN IF EXISTS admin_message_id,
+    DROP COLUMN IF EXISTS responded_at;
+
+ALTER TABLE chats
+    DROP COLUMN IF EXISTS reply_feedback_id,
+    DROP COLUMN IF EXISTS reply_feedback_expires_at;
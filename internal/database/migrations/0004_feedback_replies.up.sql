@@ -0,0 +1,10 @@
+ALTER TABLE chats
+    ADD COLUMN IF NOT EXISTS reply_feedback_id         INTEGER,
+    ADD COLUMN IF NOT EXISTS reply_feedback_expires_at TIMESTAMPTZ;
+
+ALTER TABLE feedbacks
+    ADD COLUMN IF NOT EXISTS admin_message_id INTEGER,
+    ADD COLUMN IF NOT EXISTS responded_at     TIMESTAMPTZ;
+
+CREATE INDEX IF NOT EXISTS idx_feedbacks_admin_message_id ON feedbacks (admin_message_id);
+CREATE INDEX IF NOT EXISTS idx_feedbacks_unanswered ON feedbacks (created_at) WHERE response IS NULL;
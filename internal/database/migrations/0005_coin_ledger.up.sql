@@ -0,0 +1,20 @@
+CREATE TABLE IF NOT EXISTS coin_transactions (
+    id              SERIAL PRIMARY KEY,
+    created_at      TIMESTAMPTZ NOT NULL DEFAULT NOW(),
+    chat_id         INTEGER     NOT NULL REFERENCES chats (id) ON DELETE CASCADE,
+    delta           INTEGER     NOT NULL,
+    balance_after   INTEGER     NOT NULL,
+    reason          VARCHAR(32) NOT NULL,
+    question_id     INTEGER,
+    related_chat_id INTEGER,
+    external_id     VARCHAR(255)
+);
+
+CREATE INDEX IF NOT EXISTS idx_coin_transactions_chat_id ON coin_transactions (chat_id, id);
+
+-- Переносим текущие балансы в журнал, чтобы сумма операций сходилась с балансом
+INSERT INTO coin_transactions (chat_id, delta, balance_after, reason)
+SELECT c.id, c.balance, c.balance, 'opening_balance'
+FROM chats c
+WHERE c.balance <> 0
+  AND NOT EXISTS (SELECT 1 FROM coin_transactions t WHERE t.chat_id = c.id);
@@ -0,0 +1,12 @@
+CREATE TABLE IF NOT EXISTS coin_transfers (
+    id           SERIAL PRIMARY KEY,
+    created_at   TIMESTAMPTZ  NOT NULL DEFAULT NOW(),
+    code         VARCHAR(64)  NOT NULL,
+    amount       INTEGER      NOT NULL CHECK (amount > 0),
+    from_chat_id INTEGER      NOT NULL REFERENCES chats (id) ON DELETE CASCADE,
+    to_chat_id   INTEGER      REFERENCES chats (id) ON DELETE SET NULL,
+    expires_at   TIMESTAMPTZ  NOT NULL,
+    redeemed_at  TIMESTAMPTZ
+);
+
+CREATE UNIQUE INDEX IF NOT EXISTS idx_coin_transfers_code ON coin_transfers (code);
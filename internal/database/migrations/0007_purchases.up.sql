@@ -0,0 +1,15 @@
+CREATE TABLE IF NOT EXISTS purchases (
+    id                 SERIAL PRIMARY KEY,
+    created_at         TIMESTAMPTZ  NOT NULL DEFAULT NOW(),
+    chat_id            INTEGER      NOT NULL REFERENCES chats (id) ON DELETE CASCADE,
+    coins              INTEGER      NOT NULL,
+    currency           VARCHAR(8)   NOT NULL,
+    total_amount       INTEGER      NOT NULL,
+    payload            VARCHAR(128) NOT NULL,
+    telegram_charge_id VARCHAR(255) NOT NULL,
+    provider_charge_id VARCHAR(255)
+);
+
+-- Идемпотентность начисления: один платеж Telegram — одна покупка
+CREATE UNIQUE INDEX IF NOT EXISTS idx_purchases_telegram_charge_id ON purchases (telegram_charge_id);
+CREATE INDEX IF NOT EXISTS idx_purchases_chat_id ON purchases (chat_id);
@@ -0,0 +1,6 @@
+CREATE TABLE IF NOT EXISTS processed_updates (
+    update_id    BIGINT      PRIMARY KEY,
+    processed_at TIMESTAMPTZ NOT NULL DEFAULT NOW()
+);
+
+CREATE INDEX IF NOT EXISTS idx_processed_updates_processed_at ON processed_updates (processed_at);
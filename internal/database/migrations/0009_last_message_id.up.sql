@@ -0,0 +1,2 @@
+ALTER TABLE chats
+    ADD COLUMN IF NOT EXISTS last_message_id INTEGER;
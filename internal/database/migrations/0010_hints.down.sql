@@ -0,0 +1,5 @@
+ALTER TABLE reactions
+    DROP COLUMN IF EXISTS hints_used,
+    DROP COLUMN IF EXISTS hint_cost;
+
+DROP TABLE IF EXISTS question_hints;
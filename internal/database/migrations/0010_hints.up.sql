@@ -0,0 +1,12 @@
+CREATE TABLE IF NOT EXISTS question_hints (
+    id          SERIAL PRIMARY KEY,
+    question_id INTEGER NOT NULL REFERENCES questions (id) ON DELETE CASCADE,
+    position    INTEGER NOT NULL,
+    text        TEXT    NOT NULL
+);
+
+CREATE INDEX IF NOT EXISTS idx_question_hints_question_id ON question_hints (question_id, position);
+
+ALTER TABLE reactions
+    ADD COLUMN IF NOT EXISTS hints_used INTEGER          NOT NULL DEFAULT 0,
+    ADD COLUMN IF NOT EXISTS hint_cost  DOUBLE PRECISION NOT NULL DEFAULT 0;
@@ -0,0 +1,6 @@
+ALTER TABLE questions
+    DROP COLUMN IF EXISTS max_attempts,
+    DROP COLUMN IF EXISTS attempt_penalty;
+
+ALTER TABLE reactions
+    DROP COLUMN IF EXISTS attempts;
@@ -0,0 +1,7 @@
+ALTER TABLE reactions
+    ADD COLUMN IF NOT EXISTS attempts INTEGER NOT NULL DEFAULT 0;
+
+-- Настройки попыток для отдельного вопроса; NULL — общие значения из окружения
+ALTER TABLE questions
+    ADD COLUMN IF NOT EXISTS max_attempts    INTEGER,
+    ADD COLUMN IF NOT EXISTS attempt_penalty INTEGER;
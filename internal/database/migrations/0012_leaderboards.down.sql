@@ -0,0 +1,5 @@
+DROP TABLE IF EXISTS leaderboard_scores;
+
+ALTER TABLE chats
+    DROP COLUMN IF EXISTS display_name,
+    DROP COLUMN IF EXISTS leaderboard_hidden;
@@ -0,0 +1,46 @@
+-- Участие в таблице лидеров: имя для показа (у личных чатов нет названия) и отказ от участия
+ALTER TABLE chats
+    ADD COLUMN IF NOT EXISTS display_name       VARCHAR(64),
+    ADD COLUMN IF NOT EXISTS leaderboard_hidden BOOLEAN NOT NULL DEFAULT FALSE;
+
+-- Итоги чатов по периодам; обновляются при закрытии вопроса, чтобы /top не пересчитывал реакции
+CREATE TABLE IF NOT EXISTS leaderboard_scores (
+    chat_id        INTEGER          NOT NULL REFERENCES chats (id) ON DELETE CASCADE,
+    period         VARCHAR(8)       NOT NULL,
+    period_start   DATE             NOT NULL,
+    answered       INTEGER          NOT NULL DEFAULT 0,
+    revealed       INTEGER          NOT NULL DEFAULT 0,
+    total_weight   DOUBLE PRECISION NOT NULL DEFAULT 0,
+    correct_weight DOUBLE PRECISION NOT NULL DEFAULT 0,
+    PRIMARY KEY (chat_id, period, period_start)
+);
+
+CREATE INDEX IF NOT EXISTS idx_leaderboard_scores_period ON leaderboard_scores (period, period_start, answered DESC);
+
+-- Заполняем таблицу по уже закрытым вопросам; вес вопроса считается по его текущему рейтингу
+WITH resolved AS (
+    SELECT r.chat_id,
+           COALESCE(r.responsed_at, r.failed_at) AT TIME ZONE 'UTC' AS resolved_at,
+           r.responsed_at IS NOT NULL                                AS correct,
+           1 + (100 - COALESCE(q.rating, 0)) / 100.0                 AS weight
+    FROM reactions r
+    JOIN questions q ON q.id = r.question_id
+    WHERE r.responsed_at IS NOT NULL OR r.failed_at IS NOT NULL
+), periods AS (
+    SELECT chat_id, 'day' AS period, resolved_at::date AS period_start, correct, weight FROM resolved
+    UNION ALL
+    SELECT chat_id, 'week', date_trunc('week', resolved_at)::date, correct, weight FROM resolved
+    UNION ALL
+    SELECT chat_id, 'all', DATE '1970-01-01', correct, weight FROM resolved
+)
+INSERT INTO leaderboard_scores (chat_id, period, period_start, answered, revealed, total_weight, correct_weight)
+SELECT chat_id,
+       period,
+       period_start,
+       COUNT(*) FILTER (WHERE correct),
+       COUNT(*) FILTER (WHERE NOT correct),
+       SUM(weight),
+       COALESCE(SUM(weight) FILTER (WHERE correct), 0)
+FROM periods
+GROUP BY chat_id, period, period_start
+ON CONFLICT (chat_id, period, period_start) DO NOTHING;
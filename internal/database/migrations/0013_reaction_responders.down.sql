@@ -0,0 +1,5 @@
+DROP INDEX IF EXISTS idx_reactions_chat_responder;
+
+ALTER TABLE reactions
+    DROP COLUMN IF EXISTS responder_id,
+    DROP COLUMN IF EXISTS responder_name;
@@ -0,0 +1,6 @@
+-- Участник группы, приславший правильный ответ; имя сохраняется на момент ответа
+ALTER TABLE reactions
+    ADD COLUMN IF NOT EXISTS responder_id   BIGINT,
+    ADD COLUMN IF NOT EXISTS responder_name VARCHAR(255);
+
+CREATE INDEX IF NOT EXISTS idx_reactions_chat_responder ON reactions (chat_id, responder_id) WHERE responder_id IS NOT NULL;
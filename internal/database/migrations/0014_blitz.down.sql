@@ -0,0 +1,4 @@
+DROP TABLE IF EXISTS scheduled_jobs;
+
+ALTER TABLE chats
+    DROP COLUMN IF EXISTS blitz_mode;
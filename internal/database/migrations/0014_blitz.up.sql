@@ -0,0 +1,14 @@
+ALTER TABLE chats
+    ADD COLUMN IF NOT EXISTS blitz_mode BOOLEAN NOT NULL DEFAULT FALSE;
+
+-- Отложенные задачи (обратный отсчет и истечение времени в блице); выполненная задача удаляется
+CREATE TABLE IF NOT EXISTS scheduled_jobs (
+    id          SERIAL PRIMARY KEY,
+    created_at  TIMESTAMPTZ NOT NULL DEFAULT NOW(),
+    run_at      TIMESTAMPTZ NOT NULL,
+    kind        VARCHAR(32) NOT NULL,
+    chat_id     INTEGER     NOT NULL REFERENCES chats (id) ON DELETE CASCADE,
+    question_id INTEGER
+);
+
+CREATE INDEX IF NOT EXISTS idx_scheduled_jobs_run_at ON scheduled_jobs (run_at);
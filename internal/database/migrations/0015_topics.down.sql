@@ -0,0 +1,3 @@
+DROP TABLE IF EXISTS chat_topics;
+DROP TABLE IF EXISTS question_tags;
+DROP TABLE IF EXISTS tags;
@@ -0,0 +1,23 @@
+-- Темы вопросов; вопрос может относиться к нескольким темам
+CREATE TABLE IF NOT EXISTS tags (
+    id         SERIAL PRIMARY KEY,
+    created_at TIMESTAMPTZ NOT NULL DEFAULT NOW(),
+    name       VARCHAR(32) NOT NULL
+);
+
+CREATE UNIQUE INDEX IF NOT EXISTS idx_tags_name ON tags (LOWER(name));
+
+CREATE TABLE IF NOT EXISTS question_tags (
+    question_id INTEGER NOT NULL REFERENCES questions (id) ON DELETE CASCADE,
+    tag_id      INTEGER NOT NULL REFERENCES tags (id) ON DELETE CASCADE,
+    PRIMARY KEY (question_id, tag_id)
+);
+
+CREATE INDEX IF NOT EXISTS idx_question_tags_tag_id ON question_tags (tag_id);
+
+-- Темы, выбранные чатом; пустой набор означает все темы
+CREATE TABLE IF NOT EXISTS chat_topics (
+    chat_id INTEGER NOT NULL REFERENCES chats (id) ON DELETE CASCADE,
+    tag_id  INTEGER NOT NULL REFERENCES tags (id) ON DELETE CASCADE,
+    PRIMARY KEY (chat_id, tag_id)
+);
@@ -0,0 +1,6 @@
+ALTER TABLE questions
+    DROP COLUMN IF EXISTS difficulty;
+
+ALTER TABLE chats
+    DROP COLUMN IF EXISTS difficulty_mode,
+    DROP COLUMN IF EXISTS skill;
@@ -0,0 +1,21 @@
+-- Уровень чата и сложность вопроса по шкале Эло для подбора вопросов
+ALTER TABLE chats
+    ADD COLUMN IF NOT EXISTS skill DOUBLE PRECISION NOT NULL DEFAULT 1500,
+    ADD COLUMN IF NOT EXISTS difficulty_mode VARCHAR(16) NOT NULL DEFAULT 'random';
+
+ALTER TABLE questions
+    ADD COLUMN IF NOT EXISTS difficulty DOUBLE PRECISION NOT NULL DEFAULT 1500;
+
+-- Начальная сложность по уже закрытым вопросам: доля правильных ответов со сглаживанием,
+-- переведенная в шкалу Эло (50% — 1500); пропуски не учитываются
+UPDATE questions q
+SET difficulty = 1500 + 400 * LOG((s.total - s.answered + 1)::NUMERIC / (s.answered + 1))
+FROM (
+    SELECT question_id,
+           COUNT(*) FILTER (WHERE responsed_at IS NOT NULL) AS answered,
+           COUNT(*)                                         AS total
+    FROM reactions
+    WHERE responsed_at IS NOT NULL OR failed_at IS NOT NULL
+    GROUP BY question_id
+) s
+WHERE q.id = s.question_id;
@@ -0,0 +1,7 @@
+ALTER TABLE questions
+    ALTER COLUMN rating SET DEFAULT 0;
+
+ALTER TABLE questions
+    DROP COLUMN IF EXISTS skip_count,
+    DROP COLUMN IF EXISTS answer_count,
+    DROP COLUMN IF EXISTS correct_count;
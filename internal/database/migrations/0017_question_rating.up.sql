@@ -0,0 +1,28 @@
+-- Счетчики закрытий вопроса для сглаженного рейтинга; пропуски хранятся отдельно и в рейтинг не входят
+ALTER TABLE questions
+    ADD COLUMN IF NOT EXISTS correct_count INTEGER NOT NULL DEFAULT 0,
+    ADD COLUMN IF NOT EXISTS answer_count  INTEGER NOT NULL DEFAULT 0,
+    ADD COLUMN IF NOT EXISTS skip_count    INTEGER NOT NULL DEFAULT 0;
+
+-- Рейтинг нового вопроса — середина шкалы, пока по нему нет ответов
+ALTER TABLE questions
+    ALTER COLUMN rating SET DEFAULT 50;
+
+-- Пересчитываем счетчики и рейтинг опубликованных вопросов по новой формуле (как recompute-ratings)
+UPDATE questions q
+SET correct_count = s.correct,
+    answer_count  = s.correct + s.revealed,
+    skip_count    = s.skipped,
+    rating        = ROUND(100.0 * (s.correct + 2) / (s.correct + s.revealed + 4))
+FROM (
+    SELECT questions.id,
+           COUNT(r.id) FILTER (WHERE r.responsed_at IS NOT NULL)                          AS correct,
+           COUNT(r.id) FILTER (WHERE r.responsed_at IS NULL AND r.failed_at IS NOT NULL) AS revealed,
+           COUNT(r.id) FILTER (WHERE r.responsed_at IS NULL AND r.failed_at IS NULL
+                                 AND r.skipped_at IS NOT NULL)                            AS skipped
+    FROM questions
+    LEFT JOIN reactions r ON r.question_id = questions.id
+    WHERE questions.is_published
+    GROUP BY questions.id
+) s
+WHERE q.id = s.id;
@@ -0,0 +1,5 @@
+DROP TABLE IF EXISTS review_cards;
+
+ALTER TABLE chats
+    DROP COLUMN IF EXISTS review_expires_at,
+    DROP COLUMN IF EXISTS review_question_id;
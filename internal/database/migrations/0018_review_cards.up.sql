@@ -0,0 +1,27 @@
+ALTER TABLE chats
+    ADD COLUMN IF NOT EXISTS review_question_id INTEGER,
+    ADD COLUMN IF NOT EXISTS review_expires_at  TIMESTAMPTZ;
+
+-- Карточки интервального повторения (SM-2): пропущенные и показанные вопросы каждого чата
+CREATE TABLE IF NOT EXISTS review_cards (
+    chat_id       INTEGER          NOT NULL REFERENCES chats (id) ON DELETE CASCADE,
+    question_id   INTEGER          NOT NULL REFERENCES questions (id) ON DELETE CASCADE,
+    created_at    TIMESTAMPTZ      NOT NULL DEFAULT NOW(),
+    due_at        TIMESTAMPTZ      NOT NULL,
+    repetitions   INTEGER          NOT NULL DEFAULT 0,
+    interval_days INTEGER          NOT NULL DEFAULT 0,
+    ease          DOUBLE PRECISION NOT NULL DEFAULT 2.5,
+    reviewed_at   TIMESTAMPTZ,
+    PRIMARY KEY (chat_id, question_id)
+);
+
+CREATE INDEX IF NOT EXISTS idx_review_cards_due ON review_cards (chat_id, due_at);
+
+-- Карточки для вопросов, которые чаты уже пропустили или не смогли ответить; повторить их можно сразу
+INSERT INTO review_cards (chat_id, question_id, due_at)
+SELECT r.chat_id, r.question_id, NOW()
+FROM reactions r
+JOIN questions q ON q.id = r.question_id AND q.is_published
+WHERE r.responsed_at IS NULL
+  AND (r.failed_at IS NOT NULL OR r.skipped_at IS NOT NULL)
+ON CONFLICT DO NOTHING;
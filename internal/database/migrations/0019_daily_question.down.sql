@@ -0,0 +1,15 @@
+DROP TABLE IF EXISTS daily_deliveries;
+DROP TABLE IF EXISTS daily_questions;
+
+DROP INDEX IF EXISTS idx_scheduled_jobs_daily_start;
+
+DELETE FROM scheduled_jobs WHERE kind IN ('daily_start', 'daily_send');
+
+ALTER TABLE scheduled_jobs
+    ALTER COLUMN chat_id SET NOT NULL;
+
+ALTER TABLE questions
+    DROP COLUMN IF EXISTS daily_queued_at;
+
+ALTER TABLE chats
+    DROP COLUMN IF EXISTS daily_enabled;
@@ -0,0 +1,34 @@
+ALTER TABLE chats
+    ADD COLUMN IF NOT EXISTS daily_enabled BOOLEAN NOT NULL DEFAULT FALSE;
+
+-- Очередь вопросов дня, которую составляет администратор
+ALTER TABLE questions
+    ADD COLUMN IF NOT EXISTS daily_queued_at TIMESTAMPTZ;
+
+-- Задачи вопроса дня относятся ко всем подписанным чатам, а не к одному
+ALTER TABLE scheduled_jobs
+    ALTER COLUMN chat_id DROP NOT NULL;
+
+-- Задача выбора вопроса дня в очереди может быть только одна
+CREATE UNIQUE INDEX IF NOT EXISTS idx_scheduled_jobs_daily_start ON scheduled_jobs (kind) WHERE kind = 'daily_start';
+
+CREATE TABLE IF NOT EXISTS daily_questions (
+    id          SERIAL PRIMARY KEY,
+    created_at  TIMESTAMPTZ NOT NULL DEFAULT NOW(),
+    day         DATE        NOT NULL UNIQUE,
+    question_id INTEGER     NOT NULL UNIQUE REFERENCES questions (id) ON DELETE CASCADE,
+    expires_at  TIMESTAMPTZ NOT NULL
+);
+
+-- Отправки вопроса дня: создаются сразу для всех подписанных чатов и отправляются пачками
+CREATE TABLE IF NOT EXISTS daily_deliveries (
+    daily_question_id INTEGER     NOT NULL REFERENCES daily_questions (id) ON DELETE CASCADE,
+    chat_id           INTEGER     NOT NULL REFERENCES chats (id) ON DELETE CASCADE,
+    created_at        TIMESTAMPTZ NOT NULL DEFAULT NOW(),
+    sent_at           TIMESTAMPTZ,
+    failed_at         TIMESTAMPTZ,
+    answered_at       TIMESTAMPTZ,
+    PRIMARY KEY (daily_question_id, chat_id)
+);
+
+CREATE INDEX IF NOT EXISTS idx_daily_deliveries_chat ON daily_deliveries (chat_id, daily_question_id);
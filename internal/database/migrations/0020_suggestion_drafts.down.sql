@@ -0,0 +1,8 @@
+ALTER TABLE chats
+    DROP COLUMN IF EXISTS suggestion_draft,
+    ADD COLUMN IF NOT EXISTS suggestion_question_id INTEGER;
+
+UPDATE chats
+SET suggestion_step       = NULL,
+    suggestion_expires_at = NULL
+WHERE suggestion_step IS NOT NULL;
@@ -0,0 +1,16 @@
+-- Удаляем вопросы, которые остались от брошенных предложений: без ответа или еще не дошедшие до модерации
+DELETE FROM questions
+WHERE is_published = FALSE
+  AND approved_at IS NULL
+  AND rejected_at IS NULL
+  AND (answer = '' OR id IN (SELECT suggestion_question_id FROM chats WHERE suggestion_question_id IS NOT NULL));
+
+-- Начатое предложение вопроса хранится в чате, вопрос создается только на последнем шаге
+ALTER TABLE chats
+    DROP COLUMN IF EXISTS suggestion_question_id,
+    ADD COLUMN IF NOT EXISTS suggestion_draft JSONB;
+
+UPDATE chats
+SET suggestion_step       = NULL,
+    suggestion_expires_at = NULL
+WHERE suggestion_step IS NOT NULL;
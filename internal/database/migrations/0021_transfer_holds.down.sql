@@ -0,0 +1,25 @@
+DELETE FROM scheduled_jobs WHERE kind = 'transfer_expire';
+
+-- Без резервирования монеты списываются при получении: возвращаем зарезервированное и закрываем незакрытые коды
+WITH holds AS (
+    SELECT from_chat_id, SUM(amount) AS amount
+    FROM coin_transfers
+    WHERE redeemed_at IS NULL AND refunded_at IS NULL
+    GROUP BY from_chat_id
+), refunded AS (
+    UPDATE chats c
+    SET balance = c.balance + h.amount
+    FROM holds h
+    WHERE c.id = h.from_chat_id
+    RETURNING c.id, c.balance, h.amount
+)
+INSERT INTO coin_transactions (chat_id, delta, balance_after, reason)
+SELECT id, amount, balance, 'refund'
+FROM refunded;
+
+UPDATE coin_transfers
+SET expires_at = LEAST(expires_at, NOW())
+WHERE redeemed_at IS NULL;
+
+ALTER TABLE coin_transfers
+    DROP COLUMN IF EXISTS refunded_at;
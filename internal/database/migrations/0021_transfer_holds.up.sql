@@ -0,0 +1,9 @@
+-- Сумма перевода теперь резервируется при создании кода и возвращается отправителю, если код не получили
+ALTER TABLE coin_transfers
+    ADD COLUMN IF NOT EXISTS refunded_at TIMESTAMPTZ;
+
+-- Старые коды ничего не резервировали: закрываем их, чтобы возврат не начислил монеты, которые не списывались
+UPDATE coin_transfers
+SET expires_at  = LEAST(expires_at, NOW()),
+    refunded_at = NOW()
+WHERE redeemed_at IS NULL;
@@ -0,0 +1,10 @@
+DELETE FROM processed_updates WHERE processed_at IS NULL;
+
+DROP INDEX IF EXISTS idx_processed_updates_received_at;
+CREATE INDEX IF NOT EXISTS idx_processed_updates_processed_at ON processed_updates (processed_at);
+
+ALTER TABLE processed_updates
+    ALTER COLUMN processed_at SET DEFAULT NOW(),
+    ALTER COLUMN processed_at SET NOT NULL,
+    DROP COLUMN IF EXISTS locked_until,
+    DROP COLUMN IF EXISTS received_at;
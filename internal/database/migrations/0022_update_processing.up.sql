@@ -0,0 +1,18 @@
+-- Обновление отмечается обработанным только после обработки; до этого оно занято до locked_until,
+-- чтобы повтор от Telegram после прерванного вызова обработался заново
+ALTER TABLE processed_updates
+    ADD COLUMN IF NOT EXISTS received_at  TIMESTAMPTZ,
+    ADD COLUMN IF NOT EXISTS locked_until TIMESTAMPTZ;
+
+UPDATE processed_updates
+SET received_at = processed_at
+WHERE received_at IS NULL;
+
+ALTER TABLE processed_updates
+    ALTER COLUMN received_at SET NOT NULL,
+    ALTER COLUMN received_at SET DEFAULT NOW(),
+    ALTER COLUMN processed_at DROP NOT NULL,
+    ALTER COLUMN processed_at DROP DEFAULT;
+
+DROP INDEX IF EXISTS idx_processed_updates_processed_at;
+CREATE INDEX IF NOT EXISTS idx_processed_updates_received_at ON processed_updates (received_at);
@@ -0,0 +1,3 @@
+ALTER TABLE scheduled_jobs
+    DROP COLUMN IF EXISTS attempts,
+    DROP COLUMN IF EXISTS locked_until;
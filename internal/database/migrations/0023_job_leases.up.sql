@@ -0,0 +1,5 @@
+-- Задача остается в очереди, пока ее не выполнят: запуск занимает ее до locked_until,
+-- а после прерванного запуска или ошибки ее заберет следующий вызов планировщика
+ALTER TABLE scheduled_jobs
+    ADD COLUMN IF NOT EXISTS locked_until TIMESTAMPTZ,
+    ADD COLUMN IF NOT EXISTS attempts     INTEGER NOT NULL DEFAULT 0;
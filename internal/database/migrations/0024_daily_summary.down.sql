@@ -0,0 +1,2 @@
+ALTER TABLE daily_deliveries
+    DROP COLUMN IF EXISTS summary_sent_at;
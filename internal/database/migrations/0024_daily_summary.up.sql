@@ -0,0 +1,3 @@
+-- Итоги вопроса дня отправляются каждому чату, получившему вопрос, когда прием ответов закрывается
+ALTER TABLE daily_deliveries
+    ADD COLUMN IF NOT EXISTS summary_sent_at TIMESTAMPTZ;
@@ -3,6 +3,7 @@ package handlers
 import (
 	"fmt"
 	tgbotapi "github.com/go-telegram-bot-api/telegram-bot-api/v5"
+	"qweasley/internal/repository"
 )
 
 // BalanceHandler обработчик команды /balance
@@ -11,9 +12,9 @@ type BalanceHandler struct {
 }
 
 // NewBalanceHandler создает новый обработчик команды balance
-func NewBalanceHandler(bot *tgbotapi.BotAPI) *BalanceHandler {
+func NewBalanceHandler(bot *tgbotapi.BotAPI, stores *repository.Stores) *BalanceHandler {
 	return &BalanceHandler{
-		BaseHandler: NewBaseHandler(bot),
+		BaseHandler: NewBaseHandler(bot, stores),
 	}
 }
 
@@ -31,7 +32,7 @@ func (h *BalanceHandler) Handle(message *tgbotapi.Message) error {
 		return h.SendMessage(message.Chat.ID, "Произошла ошибка при получении баланса", nil)
 	}
 
-	text := fmt.Sprintf("*Ваш баланс: %d монет\\.*\n\nПополнить баланс вы можете, предложив свой вопрос через соответствующую команду меню\\. В случае, если вопрос пройдет модерацию, он будет опубликован в боте и ваш счет будет пополнен на 10 монет\\. Если вы готовы приобрести монеты за деньги по курсу 1 монета \\= 10 рублей, свяжитесь с администрацией через команду \\/feedback", chat.Balance)
+	text := fmt.Sprintf("*Ваш баланс: %d монет\\.*\n\nПополнить баланс вы можете, предложив свой вопрос командой \\/suggest\\. В случае, если вопрос пройдет модерацию, он будет опубликован в боте и ваш счет будет пополнен на 10 монет\\. Также монеты можно купить командой \\/buy\\.", chat.Balance)
 
 	return h.SendMessage(message.Chat.ID, text, nil)
 }
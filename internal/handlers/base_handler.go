@@ -1,11 +1,16 @@
 package handlers
 
 import (
+	"errors"
 	"fmt"
 	tgbotapi "github.com/go-telegram-bot-api/telegram-bot-api/v5"
+	"net/http"
 	"os"
+	"qweasley/internal/answer"
 	"qweasley/internal/models"
 	"qweasley/internal/repository"
+	"qweasley/internal/selection"
+	"strconv"
 	"strings"
 	"time"
 )
@@ -17,18 +22,38 @@ type TextHandler interface {
 
 // BaseHandler содержит общую логику для всех обработчиков
 type BaseHandler struct {
-	chatRepo     *repository.ChatRepository
-	questionRepo *repository.QuestionRepository
-	reactionRepo *repository.ReactionRepository
+	chatRepo     repository.ChatStore
+	questionRepo repository.QuestionStore
+	reactionRepo repository.ReactionStore
+	ledgerRepo   repository.LedgerStore
+	jobRepo      repository.JobStore
+	tagRepo      repository.TagStore
+	dailyRepo    repository.DailyStore
+	unitOfWork   repository.UnitOfWork
+	matcher      *answer.Matcher
+	hinter       *answer.Hinter
+	attempts     *answer.AttemptPolicy
+	blitz        *answer.BlitzPolicy
+	daily        *answer.DailyPolicy
 	bot          *tgbotapi.BotAPI
 }
 
 // NewBaseHandler создает новый базовый обработчик
-func NewBaseHandler(bot *tgbotapi.BotAPI) *BaseHandler {
+func NewBaseHandler(bot *tgbotapi.BotAPI, stores *repository.Stores) *BaseHandler {
 	return &BaseHandler{
-		chatRepo:     repository.NewChatRepository(),
-		questionRepo: repository.NewQuestionRepository(),
-		reactionRepo: repository.NewReactionRepository(),
+		chatRepo:     stores.Chats,
+		questionRepo: stores.Questions,
+		reactionRepo: stores.Reactions,
+		ledgerRepo:   stores.Ledger,
+		jobRepo:      stores.Jobs,
+		tagRepo:      stores.Tags,
+		dailyRepo:    stores.Daily,
+		unitOfWork:   stores.UnitOfWork,
+		matcher:      answer.NewMatcher(),
+		hinter:       answer.NewHinter(),
+		attempts:     answer.NewAttemptPolicy(),
+		blitz:        answer.NewBlitzPolicy(),
+		daily:        answer.NewDailyPolicy(),
 		bot:          bot,
 	}
 }
@@ -46,9 +71,17 @@ func (h *BaseHandler) CheckBalance(chat *models.Chat) error {
 	return nil
 }
 
-// GetQuestionForChat получает вопрос для чата
+// GetQuestionForChat получает вопрос для чата с учетом выбранных тем и режима сложности
 func (h *BaseHandler) GetQuestionForChat(chat *models.Chat) (*models.Question, error) {
-	return h.questionRepo.GetQuestion(chat, h.reactionRepo)
+	topics, err := h.tagRepo.GetChatTopics(chat.ID)
+	if err != nil {
+		// Без тем вопрос все равно найдется, просто из всех тем
+		fmt.Printf("Failed to get chat topics: %v (chat_id: %d)\n", err, chat.ID)
+		topics = nil
+	}
+
+	selector, _ := selection.ForMode(chat.DifficultyMode)
+	return h.questionRepo.GetQuestion(chat, h.reactionRepo, topics, selector)
 }
 
 // SetWaitingAnswer устанавливает ожидание ответа
@@ -71,31 +104,78 @@ func (h *BaseHandler) ClearWaitingFeedback(chatID uint) error {
 	return h.chatRepo.ClearWaitingFeedback(chatID)
 }
 
-// DecreaseBalance уменьшает баланс
-func (h *BaseHandler) DecreaseBalance(chatID uint) error {
-	return h.chatRepo.DecreaseBalance(chatID)
+// ChangeBalance изменяет баланс с записью в журнал.
+// В refs можно передать ссылки на связанные сущности
+func (h *BaseHandler) ChangeBalance(chatID uint, delta int, reason models.TransactionReason, refs *models.CoinTransaction) error {
+	entry := &models.CoinTransaction{
+		ChatID: chatID,
+		Delta:  delta,
+		Reason: reason,
+	}
+	if refs != nil {
+		entry.QuestionID = refs.QuestionID
+		entry.RelatedChatID = refs.RelatedChatID
+		entry.ExternalID = refs.ExternalID
+	}
+	return h.ledgerRepo.Apply(entry)
+}
+
+// CreateQuestionKeyboard создает клавиатуру для вопроса; кнопки привязаны к вопросу
+func (h *BaseHandler) CreateQuestionKeyboard(chatID int64, questionID uint) *tgbotapi.InlineKeyboardMarkup {
+	return &tgbotapi.InlineKeyboardMarkup{
+		InlineKeyboard: [][]tgbotapi.InlineKeyboardButton{
+			{
+				tgbotapi.NewInlineKeyboardButtonData("Пропустить", h.QuestionCallbackData("skip", chatID, questionID)),
+				tgbotapi.NewInlineKeyboardButtonData("Подсказка", h.QuestionCallbackData("hint", chatID, questionID)),
+				tgbotapi.NewInlineKeyboardButtonData("Показать ответ", h.QuestionCallbackData("fail", chatID, questionID)),
+				tgbotapi.NewInlineKeyboardButtonData("Закончить", h.QuestionCallbackData("finish", chatID, questionID)),
+			},
+		},
+	}
+}
+
+// CreateContinueKeyboard создает клавиатуру для продолжения после закрытого вопроса
+func (h *BaseHandler) CreateContinueKeyboard(chatID int64, questionID uint) *tgbotapi.InlineKeyboardMarkup {
+	return &tgbotapi.InlineKeyboardMarkup{
+		InlineKeyboard: [][]tgbotapi.InlineKeyboardButton{
+			{
+				tgbotapi.NewInlineKeyboardButtonData("Точно!", h.QuestionCallbackData("continue", chatID, questionID)),
+				tgbotapi.NewInlineKeyboardButtonData("Ладно, хватит", h.QuestionCallbackData("finish", chatID, questionID)),
+			},
+		},
+	}
+}
+
+// CreateSuggestSkipKeyboard создает клавиатуру для пропуска необязательного шага
+func (h *BaseHandler) CreateSuggestSkipKeyboard() *tgbotapi.InlineKeyboardMarkup {
+	return &tgbotapi.InlineKeyboardMarkup{
+		InlineKeyboard: [][]tgbotapi.InlineKeyboardButton{
+			{
+				tgbotapi.NewInlineKeyboardButtonData("Пропустить", "suggest_skip"),
+			},
+		},
+	}
 }
 
-// CreateQuestionKeyboard создает клавиатуру для вопроса
-func (h *BaseHandler) CreateQuestionKeyboard() *tgbotapi.InlineKeyboardMarkup {
+// CreateModerationKeyboard создает клавиатуру модерации предложенного вопроса
+func (h *BaseHandler) CreateModerationKeyboard(questionID uint) *tgbotapi.InlineKeyboardMarkup {
 	return &tgbotapi.InlineKeyboardMarkup{
 		InlineKeyboard: [][]tgbotapi.InlineKeyboardButton{
 			{
-				tgbotapi.NewInlineKeyboardButtonData("Пропустить", "skip"),
-				tgbotapi.NewInlineKeyboardButtonData("Показать ответ", "fail"),
-				tgbotapi.NewInlineKeyboardButtonData("Закончить", "finish"),
+				tgbotapi.NewInlineKeyboardButtonData("Одобрить", fmt.Sprintf("moderate:approve:%d", questionID)),
+				tgbotapi.NewInlineKeyboardButtonData("Отклонить", fmt.Sprintf("moderate:reject:%d", questionID)),
+				tgbotapi.NewInlineKeyboardButtonData("Редактировать", fmt.Sprintf("moderate:edit:%d", questionID)),
 			},
 		},
 	}
 }
 
-// CreateContinueKeyboard создает клавиатуру для продолжения
-func (h *BaseHandler) CreateContinueKeyboard() *tgbotapi.InlineKeyboardMarkup {
+// CreateFeedbackReplyKeyboard создает клавиатуру для ответа на обратную связь
+func (h *BaseHandler) CreateFeedbackReplyKeyboard(feedbackID uint) *tgbotapi.InlineKeyboardMarkup {
 	return &tgbotapi.InlineKeyboardMarkup{
 		InlineKeyboard: [][]tgbotapi.InlineKeyboardButton{
 			{
-				tgbotapi.NewInlineKeyboardButtonData("Точно!", "continue"),
-				tgbotapi.NewInlineKeyboardButtonData("Ладно, хватит", "finish"),
+				tgbotapi.NewInlineKeyboardButtonData("Ответить", fmt.Sprintf("feedback_reply:%d", feedbackID)),
 			},
 		},
 	}
@@ -117,6 +197,21 @@ func (h *BaseHandler) GetPictureURL(path string) (string, error) {
 	return endpoint + "/" + bucket + "/" + path, nil
 }
 
+// GetAdminChatID возвращает ID чата администратора или 0, если он не задан
+func (h *BaseHandler) GetAdminChatID() int64 {
+	adminID, err := strconv.ParseInt(os.Getenv("ADMIN_CHAT_ID"), 10, 64)
+	if err != nil {
+		return 0
+	}
+	return adminID
+}
+
+// IsAdminChat проверяет, является ли чат чатом администратора
+func (h *BaseHandler) IsAdminChat(chatID int64) bool {
+	adminID := h.GetAdminChatID()
+	return adminID != 0 && adminID == chatID
+}
+
 // EscapeMarkdown экранирует специальные символы для Markdown
 func (h *BaseHandler) EscapeMarkdown(text string) string {
 	specialChars := []string{"?", "!", "_", "*", "[", "]", "(", ")", "~", "`", ">", "<", "&", "#", "+", "-", "=", "|", "{", "}", "."}
@@ -132,15 +227,37 @@ func (h *BaseHandler) EscapeMarkdown(text string) string {
 func (h *BaseHandler) FormatQuestionText(question *models.Question) string {
 	text := "*" + h.EscapeMarkdown(question.Text) + "*"
 
-	// Добавляем рейтинг, если он есть
-	if question.Rating != nil && *question.Rating > 0 {
-		ratingText := fmt.Sprintf("На этот вопрос отвечают %d%% пользователей", *question.Rating)
+	// Добавляем рейтинг, когда у вопроса набралось достаточно ответов
+	if rating, ok := question.DisplayRating(); ok {
+		ratingText := fmt.Sprintf("На этот вопрос отвечают %d%% пользователей", rating)
 		text += "\n\n_" + h.EscapeMarkdown(ratingText) + "_"
 	}
 
 	return text
 }
 
+// QuestionMessageText собирает текст сообщения с вопросом: сам вопрос, взятые подсказки (reaction может быть nil)
+// и в блице оставшееся время
+func (h *BaseHandler) QuestionMessageText(chat *models.Chat, question *models.Question, reaction *models.Reaction) string {
+	text := h.FormatQuestionText(question)
+
+	if reaction != nil && reaction.HintsUsed > 0 {
+		hints, masked := h.QuestionHints(question)
+		text += "\n\n" + h.formatHints(hints[:min(reaction.HintsUsed, len(hints))], masked)
+		if spent := h.hinter.Coins(reaction.HintCost); spent > 0 {
+			text += "\n\n_" + h.EscapeMarkdown(fmt.Sprintf("Потрачено на подсказки монет: %d", spent)) + "_"
+		}
+	}
+
+	if chat.BlitzMode && chat.ExpiresAt != nil {
+		if remaining := time.Until(*chat.ExpiresAt); remaining > 0 {
+			text += "\n\n_" + h.EscapeMarkdown("Осталось времени: "+formatDuration(remaining)) + "_"
+		}
+	}
+
+	return text
+}
+
 // ProcessStartCommand обрабатывает общую логику команды start
 func (h *BaseHandler) ProcessStartCommand(telegramID int64, title *string) (*models.Chat, *models.Question, error) {
 	// Получаем или создаем чат пользователя
@@ -165,11 +282,20 @@ func (h *BaseHandler) ProcessStartCommand(telegramID int64, title *string) (*mod
 		return nil, nil, fmt.Errorf("no questions available")
 	}
 
-	// Устанавливаем ожидание ответа на вопрос (30 минут)
-	if err := h.SetWaitingAnswer(chat.ID, question.ID, 30*time.Minute); err != nil {
+	// Устанавливаем ожидание ответа на вопрос (30 минут, в блице — BLITZ_TIMEOUT)
+	timeout := 30 * time.Minute
+	if chat.BlitzMode {
+		timeout = h.blitz.Timeout
+	}
+	if err := h.SetWaitingAnswer(chat.ID, question.ID, timeout); err != nil {
 		return nil, nil, fmt.Errorf("failed to set waiting answer: %v", err)
 	}
 
+	// Возвращаем чат уже в состоянии ожидания: по нему считается обратный отсчет
+	expiresAt := time.Now().UTC().Add(timeout)
+	chat.LastQuestionID = &question.ID
+	chat.ExpiresAt = &expiresAt
+
 	return chat, question, nil
 }
 
@@ -181,6 +307,16 @@ func (h *BaseHandler) ProcessTextResponse(message *tgbotapi.Message) (string, *t
 		return "", nil, "", fmt.Errorf("failed to get or create chat: %v", err)
 	}
 
+	// Время на вопрос блица вышло, а задача истечения еще не выполнилась: закрываем вопрос сами,
+	// чтобы опоздавший ответ не засчитался и не остался без реакции
+	if chat.IsBlitzOverdue() {
+		question, err := h.questionRepo.GetByID(*chat.LastQuestionID)
+		if err != nil {
+			return "", nil, "", fmt.Errorf("failed to get question: %v", err)
+		}
+		return h.expireBlitzQuestion(chat, question)
+	}
+
 	// Проверяем, ждет ли чат ответа на вопрос
 	if !chat.IsWaitingAnswer() {
 		// Чат не ждет ответа - игнорируем сообщение
@@ -193,58 +329,158 @@ func (h *BaseHandler) ProcessTextResponse(message *tgbotapi.Message) (string, *t
 		return "", nil, "", fmt.Errorf("failed to get question: %v", err)
 	}
 
-	// Проверяем ответ
-	userAnswer := strings.ToLower(strings.TrimSpace(message.Text))
-	correctAnswer := strings.ToLower(strings.TrimSpace(question.Answer))
+	// Проверяем ответ с учетом вариантов и опечаток
+	input, addressed := h.answerText(message)
+	result := h.matcher.Match(input, question.AcceptedAnswers()...)
+
+	if result.Accepted() {
+		// В блице быстрый ответ возвращает монеты; время с показа считаем по сроку ожидания
+		bonus := 0
+		if chat.BlitzMode {
+			bonus = h.blitz.SpeedBonus(h.blitz.Timeout - time.Until(*chat.ExpiresAt))
+		}
 
-	if userAnswer == correctAnswer {
-		// Обрабатываем правильный ответ
-		err = h.ProcessUserReaction(chat.ID, question.ID, "response")
+		// Обрабатываем правильный ответ и запоминаем, кто его прислал
+		err = h.ProcessCorrectAnswer(chat.ID, question.ID, message.From, bonus)
+		if errors.Is(err, repository.ErrQuestionChanged) {
+			// Вопрос уже закрыт параллельным запросом (пропуск, показ ответа или другой ответ)
+			return "", nil, "", nil
+		}
 		if err != nil {
 			return "", nil, "", fmt.Errorf("failed to process response reaction: %v", err)
 		}
 
-		// Формируем ответ
-		responseText := "*Это правильный ответ\\!*"
+		// Убираем кнопки под вопросом, на который ответили
+		if chat.LastMessageID != nil {
+			h.ResolveQuestionMessage(message.Chat.ID, *chat.LastMessageID, question, "Ответ засчитан")
+		}
+
+		// Формируем ответ; в группе поздравляем ответившего по имени
+		congratulation := ""
+		if (message.Chat.IsGroup() || message.Chat.IsSuperGroup()) && message.From != nil {
+			congratulation = ", " + h.EscapeMarkdown(memberName(message.From))
+		}
+		responseText := "*Это правильный ответ" + congratulation + "\\!*"
+		if result.Verdict == answer.Close {
+			responseText = "*Засчитано" + congratulation + "\\!* Но правильно пишется: " + h.EscapeMarkdown(result.Expected)
+		}
+		if bonus > 0 {
+			responseText += "\n\n_" + h.EscapeMarkdown(fmt.Sprintf("Бонус за скорость: +%d", bonus)) + "_"
+		}
 		if question.Comment != nil {
 			responseText += "\n\n" + h.EscapeMarkdown(*question.Comment)
 		}
 
-		keyboard := h.CreateContinueKeyboard()
+		keyboard := h.CreateContinueKeyboard(message.Chat.ID, question.ID)
 
-		// Проверяем наличие картинки ответа
-		var photoURL string
-		if question.AnswerPicture != nil && question.AnswerPicture.Path != nil {
-			photoURL, err = h.GetPictureURL(*question.AnswerPicture.Path)
-			if err != nil {
-				fmt.Printf("Failed to get answer picture URL: %v (path: %s)\n", err, *question.AnswerPicture.Path)
-				// Если не удалось получить картинку, возвращаем пустой URL
-				photoURL = ""
-			}
+		return responseText, keyboard, h.answerPictureURL(question), nil
+	}
+
+	// В группе сообщение, не адресованное боту, — обычная переписка, а не попытка ответа
+	if !addressed {
+		return "", nil, "", nil
+	}
+
+	// Неверный ответ: считаем попытку, штрафуем и после лимита показываем ответ
+	limit, penalty := h.attempts.Limits(question.MaxAttempts, question.AttemptPenalty)
+	wrong, err := h.ProcessWrongAnswer(chat.ID, question.ID, limit, penalty)
+	if errors.Is(err, repository.ErrQuestionChanged) {
+		return "", nil, "", nil
+	}
+	if err != nil {
+		return "", nil, "", fmt.Errorf("failed to process wrong answer: %v", err)
+	}
+
+	if wrong.Revealed {
+		// Убираем кнопки под вопросом, ответ на который показан
+		if chat.LastMessageID != nil {
+			h.ResolveQuestionMessage(message.Chat.ID, *chat.LastMessageID, question, "Ответ показан: "+question.Answer)
 		}
 
-		return responseText, keyboard, photoURL, nil
+		responseText := "Попытки закончились\\.\n\n*Правильный ответ:*\n" + h.EscapeMarkdown(question.Answer)
+		if question.Comment != nil {
+			responseText += "\n\n" + h.EscapeMarkdown(*question.Comment)
+		}
+
+		keyboard := h.CreateContinueKeyboard(message.Chat.ID, question.ID)
+
+		return responseText, keyboard, h.answerPictureURL(question), nil
+	}
+
+	responseText := "Ответ неверный\\."
+	if wrong.Charged > 0 {
+		responseText += fmt.Sprintf(" Списано монет: %d\\.", wrong.Charged)
+	}
+	if limit > 0 {
+		responseText += fmt.Sprintf(" Осталось попыток: %d\\.", limit-wrong.Attempts)
+	}
+	return responseText + " Попробуйте еще раз", nil, "", nil
+}
+
+// answerPictureURL возвращает URL картинки ответа или пустую строку, если картинки нет или URL не сформировать
+func (h *BaseHandler) answerPictureURL(question *models.Question) string {
+	if question.AnswerPicture == nil || question.AnswerPicture.Path == nil {
+		return ""
 	}
 
-	return "Ответ неверный\\. Попробуйте еще раз", nil, "", nil
+	photoURL, err := h.GetPictureURL(*question.AnswerPicture.Path)
+	if err != nil {
+		fmt.Printf("Failed to get answer picture URL: %v (path: %s)\n", err, *question.AnswerPicture.Path)
+		return ""
+	}
+	return photoURL
 }
 
 // GetNextQuestion получает следующий вопрос для чата
-func (h *BaseHandler) GetNextQuestion(telegramID int64, title *string) (*models.Question, *tgbotapi.InlineKeyboardMarkup, error) {
+func (h *BaseHandler) GetNextQuestion(telegramID int64, title *string) (*models.Chat, *models.Question, *tgbotapi.InlineKeyboardMarkup, error) {
 	// Обрабатываем общую логику команды start
-	_, question, err := h.ProcessStartCommand(telegramID, title)
+	chat, question, err := h.ProcessStartCommand(telegramID, title)
 	if err != nil {
-		return nil, nil, err
+		return nil, nil, nil, err
 	}
 
 	// Создаем клавиатуру
-	keyboard := h.CreateQuestionKeyboard()
+	keyboard := h.CreateQuestionKeyboard(telegramID, question.ID)
+
+	return chat, question, keyboard, nil
+}
+
+// SendQuestion отправляет вопрос и запоминает его сообщение, чтобы после ответа убрать клавиатуру
+func (h *BaseHandler) SendQuestion(chat *models.Chat, question *models.Question, keyboard *tgbotapi.InlineKeyboardMarkup) error {
+	messageID, err := h.sendQuestion(chat, question, keyboard)
+	if err != nil {
+		return err
+	}
+
+	if err := h.chatRepo.SetLastMessage(chat.ID, messageID); err != nil {
+		fmt.Printf("Failed to save question message: %v (chat_id: %d, message_id: %d)\n", err, chat.ID, messageID)
+	}
+	// Отмечаем показ вопроса: от него считается время ответа в /stats
+	if err := h.reactionRepo.MarkShown(chat.ID, question.ID); err != nil {
+		fmt.Printf("Failed to mark question as shown: %v (chat_id: %d, question_id: %d)\n", err, chat.ID, question.ID)
+	}
+
+	// В блице планируем обратный отсчет и истечение времени
+	if chat.BlitzMode {
+		if err := h.scheduleBlitz(chat.ID, question.ID); err != nil {
+			fmt.Printf("Failed to schedule blitz jobs: %v (chat_id: %d, question_id: %d)\n", err, chat.ID, question.ID)
+		}
+	}
+	return nil
+}
 
-	return question, keyboard, nil
+// sendQuestion отправляет вопрос (с картинкой или без) и возвращает ID сообщения
+func (h *BaseHandler) sendQuestion(chat *models.Chat, question *models.Question, keyboard *tgbotapi.InlineKeyboardMarkup) (int, error) {
+	return h.sendQuestionText(chat.TelegramID, question, h.QuestionMessageText(chat, question, nil), keyboard)
 }
 
-// SendQuestion отправляет вопрос (с картинкой или без)
-func (h *BaseHandler) SendQuestion(chatID int64, question *models.Question, keyboard *tgbotapi.InlineKeyboardMarkup) error {
+// sendQuestionText отправляет готовый текст вопроса: подписью к картинке вопроса, если она есть, иначе сообщением
+func (h *BaseHandler) sendQuestionText(chatID int64, question *models.Question, questionText string, keyboard *tgbotapi.InlineKeyboardMarkup) (int, error) {
+	// Картинку, присланную пользователем, отправляем по идентификатору файла Telegram
+	if question.QuestionPicture != nil && question.QuestionPicture.TelegramFileID != nil {
+		return h.SendPhotoFileWithID(chatID, tgbotapi.FileID(*question.QuestionPicture.TelegramFileID), questionText, keyboard)
+	}
+
 	// Проверяем наличие картинки вопроса
 	if question.QuestionPicture != nil && question.QuestionPicture.Path != nil {
 		// Формируем URL картинки
@@ -252,49 +488,250 @@ func (h *BaseHandler) SendQuestion(chatID int64, question *models.Question, keyb
 		if err != nil {
 			fmt.Printf("Failed to get picture URL: %v (path: %s)\n", err, *question.QuestionPicture.Path)
 			// Если не удалось получить картинку, отправляем текстовое сообщение
-			questionText := h.FormatQuestionText(question)
-			return h.SendMessage(chatID, questionText, keyboard)
+			return h.SendMessageWithID(chatID, questionText, keyboard)
 		}
 
-		// Формируем текст вопроса
-		questionText := h.FormatQuestionText(question)
-
 		// Отправляем фото с подписью
-		return h.SendPhoto(chatID, photoURL, questionText, keyboard)
-	} else {
-		// Формируем текст вопроса
-		questionText := h.FormatQuestionText(question)
+		return h.SendPhotoFileWithID(chatID, tgbotapi.FileURL(photoURL), questionText, keyboard)
+	}
+
+	// Отправляем текстовое сообщение
+	return h.SendMessageWithID(chatID, questionText, keyboard)
+}
 
-		// Отправляем текстовое сообщение
-		return h.SendMessage(chatID, questionText, keyboard)
+// SendModerationCard отправляет предложенный вопрос в чат администратора
+func (h *BaseHandler) SendModerationCard(question *models.Question) error {
+	adminID := h.GetAdminChatID()
+	if adminID == 0 {
+		return fmt.Errorf("ADMIN_CHAT_ID environment variable is not set")
 	}
+
+	text := fmt.Sprintf("*Предложен вопрос \\#%d*\n\n*Вопрос:* %s\n*Ответ:* %s", question.ID, h.EscapeMarkdown(question.Text), h.EscapeMarkdown(question.Answer))
+	if question.Comment != nil {
+		text += "\n*Комментарий:* " + h.EscapeMarkdown(*question.Comment)
+	}
+	for _, hint := range question.Hints {
+		text += fmt.Sprintf("\n*Подсказка %d:* %s", hint.Position, h.EscapeMarkdown(hint.Text))
+	}
+	if question.Author != nil {
+		text += fmt.Sprintf("\n*Автор:* чат %s", h.EscapeMarkdown(fmt.Sprint(question.Author.TelegramID)))
+	}
+
+	keyboard := h.CreateModerationKeyboard(question.ID)
+
+	if question.QuestionPicture != nil && question.QuestionPicture.TelegramFileID != nil {
+		return h.SendPhotoFile(adminID, tgbotapi.FileID(*question.QuestionPicture.TelegramFileID), text, keyboard)
+	}
+
+	return h.SendMessage(adminID, text, keyboard)
+}
+
+// reactionCost сколько монет списывается при закрытии вопроса любой реакцией
+const reactionCost = 1
+
+// reactionReasons причины списания монет для типов реакций
+var reactionReasons = map[string]models.TransactionReason{
+	"response": models.ReasonAnswer,
+	"skip":     models.ReasonSkip,
+	"fail":     models.ReasonReveal,
 }
 
-// ProcessUserReaction обрабатывает реакцию пользователя на вопрос
+// ProcessUserReaction обрабатывает реакцию пользователя на вопрос.
+// Реакция, списание, снятие ожидания и пересчет рейтинга выполняются в одной транзакции
+// с блокировкой чата; если чат уже не ждет ответа на этот вопрос (повторное нажатие,
+// параллельный ответ), возвращается repository.ErrQuestionChanged и ничего не меняется
 func (h *BaseHandler) ProcessUserReaction(chatID uint, questionID uint, reactionType string) error {
-	// Создаем реакцию
-	err := h.reactionRepo.CreateOrUpdateReaction(chatID, questionID, reactionType)
+	return h.unitOfWork.Do(func(tx repository.Tx) error {
+		if _, err := lockActiveQuestion(tx, chatID, questionID); err != nil {
+			return err
+		}
+		return resolveQuestion(tx, chatID, questionID, reactionType)
+	})
+}
+
+// ProcessCorrectAnswer закрывает вопрос правильным ответом, как ProcessUserReaction, и в той же транзакции
+// начисляет бонус за скорость и запоминает в реакции пользователя, приславшего ответ
+// (from может быть nil, например для сообщений от имени канала)
+func (h *BaseHandler) ProcessCorrectAnswer(chatID uint, questionID uint, from *tgbotapi.User, bonus int) error {
+	return h.unitOfWork.Do(func(tx repository.Tx) error {
+		if _, err := lockActiveQuestion(tx, chatID, questionID); err != nil {
+			return err
+		}
+		if err := resolveQuestion(tx, chatID, questionID, "response"); err != nil {
+			return err
+		}
+
+		if bonus > 0 {
+			err := tx.ApplyTransaction(&models.CoinTransaction{
+				ChatID:     chatID,
+				Delta:      bonus,
+				Reason:     models.ReasonSpeedBonus,
+				QuestionID: &questionID,
+			})
+			if err != nil {
+				return fmt.Errorf("failed to credit speed bonus: %w", err)
+			}
+		}
+
+		if from == nil {
+			return nil
+		}
+		if err := tx.SetResponder(chatID, questionID, from.ID, memberName(from)); err != nil {
+			return fmt.Errorf("failed to save responder: %w", err)
+		}
+		return nil
+	})
+}
+
+// answerText возвращает текст ответа без упоминания бота и признак того, что сообщение адресовано боту:
+// в личном чате это любое сообщение, а в группе — ответ на сообщение бота или сообщение с упоминанием бота.
+// Неверными в группе считаются только адресованные боту сообщения, чтобы обычная переписка не тратила попытки
+func (h *BaseHandler) answerText(message *tgbotapi.Message) (string, bool) {
+	if !message.Chat.IsGroup() && !message.Chat.IsSuperGroup() {
+		return message.Text, true
+	}
+
+	mention := "@" + h.bot.Self.UserName
+	mentioned := false
+	var words []string
+	for _, word := range strings.Fields(message.Text) {
+		if h.bot.Self.UserName != "" && strings.EqualFold(strings.TrimRight(word, ",.:;!?"), mention) {
+			mentioned = true
+			continue
+		}
+		words = append(words, word)
+	}
+	if mentioned {
+		return strings.Join(words, " "), true
+	}
+
+	replyToBot := message.ReplyToMessage != nil && message.ReplyToMessage.From != nil && message.ReplyToMessage.From.ID == h.bot.Self.ID
+	return message.Text, replyToBot
+}
+
+// memberName возвращает имя пользователя Telegram для показа в чате
+func memberName(user *tgbotapi.User) string {
+	if name := strings.TrimSpace(user.FirstName + " " + user.LastName); name != "" {
+		return name
+	}
+	if user.UserName != "" {
+		return "@" + user.UserName
+	}
+	return fmt.Sprintf("Участник %d", user.ID)
+}
+
+// WrongAnswer итог обработки неверного ответа
+type WrongAnswer struct {
+	// Attempts сколько неверных ответов уже дано на вопрос
+	Attempts int
+	// Charged сколько монет списано за этот ответ
+	Charged int
+	// Revealed попытки закончились, и вопрос закрыт с показом ответа
+	Revealed bool
+}
+
+// ProcessWrongAnswer в одной транзакции считает неверный ответ, списывает штраф (не больше баланса
+// за вычетом монеты за закрытие вопроса) и, если попытки закончились, закрывает вопрос как показ ответа.
+// limit 0 означает без ограничения
+func (h *BaseHandler) ProcessWrongAnswer(chatID uint, questionID uint, limit int, penalty int) (*WrongAnswer, error) {
+	result := &WrongAnswer{}
+	err := h.unitOfWork.Do(func(tx repository.Tx) error {
+		chat, err := lockActiveQuestion(tx, chatID, questionID)
+		if err != nil {
+			return err
+		}
+
+		reaction, err := tx.AddAttempt(chatID, questionID)
+		if err != nil {
+			return fmt.Errorf("failed to save attempt: %w", err)
+		}
+		result.Attempts = reaction.Attempts
+		result.Revealed = limit > 0 && reaction.Attempts >= limit
+
+		// Закрытие вопроса, в том числе автоматический показ ответа, спишет еще монету: штраф ее не трогает,
+		// чтобы баланс не ушел ниже нуля
+		result.Charged = min(penalty, max(chat.Balance-reactionCost, 0))
+		if result.Charged > 0 {
+			err = tx.ApplyTransaction(&models.CoinTransaction{
+				ChatID:     chatID,
+				Delta:      -result.Charged,
+				Reason:     models.ReasonWrongAnswer,
+				QuestionID: &questionID,
+			})
+			if err != nil {
+				return fmt.Errorf("failed to charge for wrong answer: %w", err)
+			}
+		}
+
+		if result.Revealed {
+			return resolveQuestion(tx, chatID, questionID, "fail")
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	return result, nil
+}
+
+// lockActiveQuestion блокирует чат и проверяет, что он все еще ждет ответа на этот вопрос
+func lockActiveQuestion(tx repository.Tx, chatID uint, questionID uint) (*models.Chat, error) {
+	chat, err := tx.LockChat(chatID)
 	if err != nil {
-		return fmt.Errorf("failed to create reaction: %v", err)
+		return nil, fmt.Errorf("failed to lock chat: %w", err)
+	}
+
+	if chat.LastQuestionID == nil || *chat.LastQuestionID != questionID {
+		return nil, repository.ErrQuestionChanged
+	}
+	return chat, nil
+}
+
+// resolveQuestion закрывает вопрос внутри транзакции: реакция, списание, снятие ожидания,
+// карточка повторения, итоги таблиц лидеров и пересчет рейтинга
+func resolveQuestion(tx repository.Tx, chatID uint, questionID uint, reactionType string) error {
+	// Создаем реакцию
+	if err := tx.SaveReaction(chatID, questionID, reactionType); err != nil {
+		return fmt.Errorf("failed to create reaction: %w", err)
 	}
 
 	// Уменьшаем баланс
-	err = h.DecreaseBalance(chatID)
+	err := tx.ApplyTransaction(&models.CoinTransaction{
+		ChatID:     chatID,
+		Delta:      -reactionCost,
+		Reason:     reactionReasons[reactionType],
+		QuestionID: &questionID,
+	})
 	if err != nil {
-		return fmt.Errorf("failed to decrease balance: %v", err)
+		return fmt.Errorf("failed to decrease balance: %w", err)
 	}
 
 	// Очищаем ожидание ответа
-	err = h.ClearWaitingAnswer(chatID)
-	if err != nil {
-		fmt.Printf("Failed to clear waiting answer: %v (chat_id: %d)\n", err, chatID)
+	if err := tx.ClearWaitingAnswer(chatID, questionID); err != nil {
+		return err
+	}
+
+	// Пропущенный вопрос можно повторить сразу, а после показа ответа — на следующий день
+	if dueIn, ok := reviewDelays[reactionType]; ok {
+		if err := tx.AddReviewCard(chatID, questionID, time.Now().UTC().Add(dueIn)); err != nil {
+			return fmt.Errorf("failed to add review card: %w", err)
+		}
+	}
+
+	// Учитываем ответ или показ ответа в таблицах лидеров до пересчета рейтинга и в уровне чата;
+	// пропуск ничего не говорит ни о чате, ни о сложности вопроса
+	if reactionType != "skip" {
+		if err := tx.AddScore(chatID, questionID, reactionType == "response"); err != nil {
+			return fmt.Errorf("failed to update leaderboard: %w", err)
+		}
+		if err := tx.UpdateElo(chatID, questionID, reactionType == "response"); err != nil {
+			return fmt.Errorf("failed to update skill: %w", err)
+		}
 	}
 
 	// Обновляем рейтинг вопроса после любой реакции
-	err = h.questionRepo.UpdateQuestionRating(questionID)
-	if err != nil {
-		fmt.Printf("Failed to update question rating: %v (question_id: %d)\n", err, questionID)
-		// Не возвращаем ошибку, так как основная логика уже выполнена
+	if err := tx.UpdateQuestionRating(questionID); err != nil {
+		return fmt.Errorf("failed to update question rating: %w", err)
 	}
 
 	return nil
@@ -318,6 +755,12 @@ func (h *BaseHandler) ProcessFinishReaction(chatID uint, questionID uint) error
 
 // SendMessage отправляет текстовое сообщение
 func (h *BaseHandler) SendMessage(chatID int64, text string, keyboard *tgbotapi.InlineKeyboardMarkup) error {
+	_, err := h.SendMessageWithID(chatID, text, keyboard)
+	return err
+}
+
+// SendMessageWithID отправляет текстовое сообщение и возвращает его ID
+func (h *BaseHandler) SendMessageWithID(chatID int64, text string, keyboard *tgbotapi.InlineKeyboardMarkup) (int, error) {
 	msg := tgbotapi.NewMessage(chatID, text)
 	msg.ParseMode = "MarkdownV2"
 
@@ -325,13 +768,24 @@ func (h *BaseHandler) SendMessage(chatID int64, text string, keyboard *tgbotapi.
 		msg.ReplyMarkup = keyboard
 	}
 
-	_, err := h.bot.Send(msg)
-	return err
+	sent, err := h.bot.Send(msg)
+	return sent.MessageID, err
 }
 
 // SendPhoto отправляет фото с подписью
 func (h *BaseHandler) SendPhoto(chatID int64, photoURL string, caption string, keyboard *tgbotapi.InlineKeyboardMarkup) error {
-	photoConfig := tgbotapi.NewPhoto(chatID, tgbotapi.FileURL(photoURL))
+	return h.SendPhotoFile(chatID, tgbotapi.FileURL(photoURL), caption, keyboard)
+}
+
+// SendPhotoFile отправляет фото из любого источника (URL или файл Telegram) с подписью
+func (h *BaseHandler) SendPhotoFile(chatID int64, file tgbotapi.RequestFileData, caption string, keyboard *tgbotapi.InlineKeyboardMarkup) error {
+	_, err := h.SendPhotoFileWithID(chatID, file, caption, keyboard)
+	return err
+}
+
+// SendPhotoFileWithID отправляет фото с подписью и возвращает ID сообщения
+func (h *BaseHandler) SendPhotoFileWithID(chatID int64, file tgbotapi.RequestFileData, caption string, keyboard *tgbotapi.InlineKeyboardMarkup) (int, error) {
+	photoConfig := tgbotapi.NewPhoto(chatID, file)
 	photoConfig.Caption = caption
 	photoConfig.ParseMode = "MarkdownV2"
 
@@ -339,13 +793,85 @@ func (h *BaseHandler) SendPhoto(chatID int64, photoURL string, caption string, k
 		photoConfig.ReplyMarkup = keyboard
 	}
 
-	_, err := h.bot.Send(photoConfig)
+	sent, err := h.bot.Send(photoConfig)
+	return sent.MessageID, err
+}
+
+// EditMessageText заменяет текст сообщения; без клавиатуры inline-кнопки у сообщения убираются
+func (h *BaseHandler) EditMessageText(chatID int64, messageID int, text string, keyboard *tgbotapi.InlineKeyboardMarkup) error {
+	edit := tgbotapi.NewEditMessageText(chatID, messageID, text)
+	edit.ParseMode = "MarkdownV2"
+	edit.ReplyMarkup = keyboard
+
+	_, err := h.bot.Send(edit)
+	return err
+}
+
+// EditMessageCaption заменяет подпись к фото; без клавиатуры inline-кнопки у сообщения убираются
+func (h *BaseHandler) EditMessageCaption(chatID int64, messageID int, caption string, keyboard *tgbotapi.InlineKeyboardMarkup) error {
+	edit := tgbotapi.NewEditMessageCaption(chatID, messageID, caption)
+	edit.ParseMode = "MarkdownV2"
+	edit.ReplyMarkup = keyboard
+
+	_, err := h.bot.Send(edit)
 	return err
 }
 
+// EditMessageReplyMarkup заменяет inline-клавиатуру сообщения, не трогая текст
+func (h *BaseHandler) EditMessageReplyMarkup(chatID int64, messageID int, keyboard tgbotapi.InlineKeyboardMarkup) error {
+	_, err := h.bot.Send(tgbotapi.NewEditMessageReplyMarkup(chatID, messageID, keyboard))
+	return err
+}
+
+// RemoveKeyboard убирает inline-клавиатуру у сообщения
+func (h *BaseHandler) RemoveKeyboard(chatID int64, messageID int) error {
+	// Пустой список строк, а не nil: null в reply_markup Telegram не принимает
+	return h.EditMessageReplyMarkup(chatID, messageID, tgbotapi.InlineKeyboardMarkup{
+		InlineKeyboard: [][]tgbotapi.InlineKeyboardButton{},
+	})
+}
+
+// EditQuestionMessage заменяет текст сообщения с вопросом: подпись, если вопрос отправлен с картинкой, иначе текст
+func (h *BaseHandler) EditQuestionMessage(chatID int64, messageID int, question *models.Question, text string, keyboard *tgbotapi.InlineKeyboardMarkup) error {
+	if question.QuestionPicture != nil {
+		return h.EditMessageCaption(chatID, messageID, text, keyboard)
+	}
+	return h.EditMessageText(chatID, messageID, text, keyboard)
+}
+
+// isMessageNotModified проверяет, что Telegram отклонил правку, потому что сообщение уже такое
+func isMessageNotModified(err error) bool {
+	var apiErr *tgbotapi.Error
+	return errors.As(err, &apiErr) && apiErr.Code == http.StatusBadRequest && strings.Contains(apiErr.Message, "message is not modified")
+}
+
+// ResolveQuestionMessage убирает клавиатуру под сообщением с вопросом и дописывает к нему итог,
+// например "Вопрос пропущен". Ошибки только логируются: ответ пользователю важнее оформления
+func (h *BaseHandler) ResolveQuestionMessage(chatID int64, messageID int, question *models.Question, note string) {
+	text := h.FormatQuestionText(question) + "\n\n_" + h.EscapeMarkdown(note) + "_"
+
+	err := h.EditQuestionMessage(chatID, messageID, question, text, nil)
+	if err == nil {
+		return
+	}
+
+	// Например, картинка не загрузилась и вопрос ушел текстом: убираем хотя бы клавиатуру
+	fmt.Printf("Failed to annotate question message: %v (chat_id: %d, message_id: %d)\n", err, chatID, messageID)
+	if err := h.RemoveKeyboard(chatID, messageID); err != nil {
+		fmt.Printf("Failed to remove question keyboard: %v (chat_id: %d, message_id: %d)\n", err, chatID, messageID)
+	}
+}
+
 // AnswerCallbackQuery отвечает на callback query
 func (h *BaseHandler) AnswerCallbackQuery(callbackID string) error {
 	callbackConfig := tgbotapi.NewCallback(callbackID, "")
-	_, err := h.bot.Send(callbackConfig)
+	// answerCallbackQuery возвращает true, а не сообщение, поэтому Send здесь не подходит
+	_, err := h.bot.Request(callbackConfig)
+	return err
+}
+
+// AnswerCallbackAlert отвечает на callback query всплывающим предупреждением
+func (h *BaseHandler) AnswerCallbackAlert(callbackID string, text string) error {
+	_, err := h.bot.Request(tgbotapi.NewCallbackWithAlert(callbackID, text))
 	return err
 }
@@ -0,0 +1,222 @@
+package handlers
+
+import (
+	"errors"
+	"fmt"
+	tgbotapi "github.com/go-telegram-bot-api/telegram-bot-api/v5"
+	"qweasley/internal/models"
+	"qweasley/internal/repository"
+	"strings"
+	"time"
+)
+
+// BlitzHandler обработчик команды /blitz
+type BlitzHandler struct {
+	*BaseHandler
+	startHandler *StartHandler
+}
+
+// NewBlitzHandler создает новый обработчик команды blitz
+func NewBlitzHandler(startHandler *StartHandler, bot *tgbotapi.BotAPI, stores *repository.Stores) *BlitzHandler {
+	return &BlitzHandler{
+		BaseHandler:  NewBaseHandler(bot, stores),
+		startHandler: startHandler,
+	}
+}
+
+// GetCommand возвращает название команды
+func (h *BlitzHandler) GetCommand() string {
+	return "blitz"
+}
+
+// Handle обрабатывает команду /blitz: включает блиц и задает первый вопрос; /blitz off выключает блиц
+func (h *BlitzHandler) Handle(message *tgbotapi.Message) error {
+	chat, err := h.GetOrCreateChat(message.Chat.ID, &message.Chat.Title)
+	if err != nil {
+		fmt.Printf("Failed to get or create chat: %v\n", err)
+		return h.SendMessage(message.Chat.ID, "Произошла ошибка при обработке команды", nil)
+	}
+
+	enabled := strings.ToLower(strings.TrimSpace(message.CommandArguments())) != "off"
+	if err := h.chatRepo.SetBlitzMode(chat.ID, enabled); err != nil {
+		fmt.Printf("Failed to set blitz mode: %v (chat_id: %d)\n", err, chat.ID)
+		return h.SendMessage(message.Chat.ID, "Произошла ошибка при обработке команды", nil)
+	}
+
+	if !enabled {
+		return h.SendMessage(message.Chat.ID, "Блиц выключен\\. Следующие вопросы — без спешки: /start", nil)
+	}
+
+	text := fmt.Sprintf("*Блиц\\!* На каждый вопрос — %s\\. Не успели — ответ показывается, как при «Показать ответ»\\.",
+		h.EscapeMarkdown(formatDuration(h.blitz.Timeout)))
+	if h.blitz.Bonus > 0 {
+		text += fmt.Sprintf(" Ответ быстрее чем за %s возвращает монет: %d\\.", h.EscapeMarkdown(formatDuration(h.blitz.BonusWindow)), h.blitz.Bonus)
+	}
+	text += "\n\nВыключить: /blitz off"
+	if err := h.SendMessage(message.Chat.ID, text, nil); err != nil {
+		return err
+	}
+
+	// Задаем первый вопрос так же, как по кнопке "Точно!"
+	return h.startHandler.Handle(&tgbotapi.Message{
+		From: message.From,
+		Chat: message.Chat,
+	})
+}
+
+// scheduleBlitz планирует обновления обратного отсчета и истечение времени на только что заданный вопрос
+func (h *BaseHandler) scheduleBlitz(chatID uint, questionID uint) error {
+	shownAt := time.Now().UTC()
+
+	var jobs []*models.ScheduledJob
+	for _, tick := range h.blitz.Ticks() {
+		jobs = append(jobs, &models.ScheduledJob{
+			RunAt:      shownAt.Add(tick),
+			Kind:       models.JobBlitzTick,
+			ChatID:     &chatID,
+			QuestionID: &questionID,
+		})
+	}
+	jobs = append(jobs, &models.ScheduledJob{
+		RunAt:      shownAt.Add(h.blitz.Timeout),
+		Kind:       models.JobBlitzExpire,
+		ChatID:     &chatID,
+		QuestionID: &questionID,
+	})
+
+	return h.jobRepo.Schedule(jobs...)
+}
+
+// blitzJobChat возвращает чат задачи, если вопрос задачи все еще ждет ответа, иначе nil
+func (h *BaseHandler) blitzJobChat(job *models.ScheduledJob) (*models.Chat, error) {
+	if job.ChatID == nil {
+		return nil, nil
+	}
+
+	chat, err := h.chatRepo.GetByID(*job.ChatID)
+	if err != nil {
+		return nil, err
+	}
+
+	if job.QuestionID == nil || chat.LastQuestionID == nil || *chat.LastQuestionID != *job.QuestionID || chat.ExpiresAt == nil {
+		return nil, nil
+	}
+	return chat, nil
+}
+
+// BlitzTickJob обработчик задачи "blitz_tick": обновляет обратный отсчет в сообщении с вопросом
+type BlitzTickJob struct {
+	*BaseHandler
+}
+
+// NewBlitzTickJob создает новый обработчик задачи blitz_tick
+func NewBlitzTickJob(bot *tgbotapi.BotAPI, stores *repository.Stores) *BlitzTickJob {
+	return &BlitzTickJob{
+		BaseHandler: NewBaseHandler(bot, stores),
+	}
+}
+
+// GetJobKind возвращает тип задачи
+func (h *BlitzTickJob) GetJobKind() models.JobKind {
+	return models.JobBlitzTick
+}
+
+// Handle обрабатывает задачу "blitz_tick"; оставшееся время берется из срока ожидания, поэтому опоздавшая задача покажет верное значение
+func (h *BlitzTickJob) Handle(job *models.ScheduledJob) error {
+	chat, err := h.blitzJobChat(job)
+	if err != nil {
+		return fmt.Errorf("failed to get chat: %w", err)
+	}
+	if chat == nil || !chat.IsWaitingAnswer() || chat.LastMessageID == nil {
+		return nil
+	}
+
+	question, err := h.questionRepo.GetByID(*job.QuestionID)
+	if err != nil {
+		return fmt.Errorf("failed to get question: %w", err)
+	}
+
+	// Реакция нужна, чтобы сохранить в сообщении уже взятые подсказки
+	reaction, err := h.reactionRepo.GetReaction(chat.ID, question.ID)
+	if err != nil {
+		reaction = nil
+	}
+
+	text := h.QuestionMessageText(chat, question, reaction)
+	keyboard := h.CreateQuestionKeyboard(chat.TelegramID, question.ID)
+	err = h.EditQuestionMessage(chat.TelegramID, *chat.LastMessageID, question, text, keyboard)
+	// Несколько опоздавших задач, выполненных подряд, показывают одно и то же время: повторять их незачем
+	if isMessageNotModified(err) {
+		return nil
+	}
+	return err
+}
+
+// BlitzExpireJob обработчик задачи "blitz_expire": закрывает неотвеченный вопрос как показ ответа
+type BlitzExpireJob struct {
+	*BaseHandler
+}
+
+// NewBlitzExpireJob создает новый обработчик задачи blitz_expire
+func NewBlitzExpireJob(bot *tgbotapi.BotAPI, stores *repository.Stores) *BlitzExpireJob {
+	return &BlitzExpireJob{
+		BaseHandler: NewBaseHandler(bot, stores),
+	}
+}
+
+// GetJobKind возвращает тип задачи
+func (h *BlitzExpireJob) GetJobKind() models.JobKind {
+	return models.JobBlitzExpire
+}
+
+// Handle обрабатывает задачу "blitz_expire"
+func (h *BlitzExpireJob) Handle(job *models.ScheduledJob) error {
+	chat, err := h.blitzJobChat(job)
+	if err != nil {
+		return fmt.Errorf("failed to get chat: %w", err)
+	}
+	// Вопрос мог быть задан заново (например, после пропуска): у нового показа своя задача с более поздним сроком
+	if chat == nil || chat.ExpiresAt.After(job.RunAt.Add(time.Second)) {
+		return nil
+	}
+
+	question, err := h.questionRepo.GetByID(*job.QuestionID)
+	if err != nil {
+		return fmt.Errorf("failed to get question: %w", err)
+	}
+
+	text, keyboard, photoURL, err := h.expireBlitzQuestion(chat, question)
+	if err != nil || text == "" {
+		return err
+	}
+	if photoURL != "" {
+		return h.SendPhoto(chat.TelegramID, photoURL, text, keyboard)
+	}
+	return h.SendMessage(chat.TelegramID, text, keyboard)
+}
+
+// expireBlitzQuestion закрывает вопрос блица по истечении времени и возвращает сообщение с ответом.
+// Пустой текст значит, что вопрос уже закрыт параллельным запросом
+func (h *BaseHandler) expireBlitzQuestion(chat *models.Chat, question *models.Question) (string, *tgbotapi.InlineKeyboardMarkup, string, error) {
+	// Истечение времени — это показ ответа: списание, реакция и рейтинг как у кнопки
+	err := h.ProcessFailReaction(chat.ID, question.ID)
+	if errors.Is(err, repository.ErrQuestionChanged) {
+		// Ответ пришел одновременно с истечением времени
+		return "", nil, "", nil
+	}
+	if err != nil {
+		return "", nil, "", fmt.Errorf("failed to process fail reaction: %w", err)
+	}
+
+	if chat.LastMessageID != nil {
+		h.ResolveQuestionMessage(chat.TelegramID, *chat.LastMessageID, question, "Время вышло. Ответ: "+question.Answer)
+	}
+
+	text := "*Время вышло\\!*\n\n*Правильный ответ:*\n" + h.EscapeMarkdown(question.Answer)
+	if question.Comment != nil {
+		text += "\n\n" + h.EscapeMarkdown(*question.Comment)
+	}
+	keyboard := h.CreateContinueKeyboard(chat.TelegramID, question.ID)
+
+	return text, keyboard, h.answerPictureURL(question), nil
+}
@@ -3,6 +3,7 @@ package handlers
 import (
 	"fmt"
 	tgbotapi "github.com/go-telegram-bot-api/telegram-bot-api/v5"
+	"qweasley/internal/repository"
 )
 
 // ContinueCallback обработчик callback'а "continue"
@@ -12,9 +13,9 @@ type ContinueCallback struct {
 }
 
 // NewContinueCallback создает новый обработчик callback'а continue
-func NewContinueCallback(startHandler *StartHandler, bot *tgbotapi.BotAPI) *ContinueCallback {
+func NewContinueCallback(startHandler *StartHandler, bot *tgbotapi.BotAPI, stores *repository.Stores) *ContinueCallback {
 	return &ContinueCallback{
-		BaseHandler:  NewBaseHandler(bot),
+		BaseHandler:  NewBaseHandler(bot, stores),
 		startHandler: startHandler,
 	}
 }
@@ -26,11 +27,35 @@ func (h *ContinueCallback) GetCallbackData() string {
 
 // Handle обрабатывает callback "continue"
 func (h *ContinueCallback) Handle(callback *tgbotapi.CallbackQuery) error {
+	// Получаем вопрос, после которого показана кнопка
+	questionID, ok := h.QuestionFromCallback(callback)
+	if !ok {
+		return nil
+	}
+
+	// Получаем чат пользователя
+	chat, err := h.GetOrCreateChat(callback.Message.Chat.ID, &callback.Message.Chat.Title)
+	if err != nil {
+		h.AnswerCallbackQuery(callback.ID)
+		fmt.Printf("Failed to get or create chat in continue callback: %v (chat_id: %d)\n", err, callback.Message.Chat.ID)
+		return h.SendMessage(callback.Message.Chat.ID, "Произошла ошибка при обработке команды", nil)
+	}
+
+	// Старая кнопка не должна заменять вопрос, который уже задан и ждет ответа
+	if chat.IsWaitingAnswer() && *chat.LastQuestionID != questionID {
+		return h.AnswerCallbackAlert(callback.ID, activeQuestionAlert)
+	}
+
 	// Отвечаем на callback query
 	if err := h.AnswerCallbackQuery(callback.ID); err != nil {
 		fmt.Printf("Failed to answer callback query: %v\n", err)
 	}
 
+	// Убираем кнопки под ответом, чтобы их нельзя было нажать повторно
+	if err := h.RemoveKeyboard(callback.Message.Chat.ID, callback.Message.MessageID); err != nil {
+		fmt.Printf("Failed to remove keyboard: %v (chat_id: %d, message_id: %d)\n", err, callback.Message.Chat.ID, callback.Message.MessageID)
+	}
+
 	// Создаем сообщение из callback для передачи в StartHandler
 	message := &tgbotapi.Message{
 		From: callback.From,
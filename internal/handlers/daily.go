@@ -0,0 +1,407 @@
+package handlers
+
+import (
+	"errors"
+	"fmt"
+	tgbotapi "github.com/go-telegram-bot-api/telegram-bot-api/v5"
+	"net/http"
+	"qweasley/internal/models"
+	"qweasley/internal/repository"
+	"strings"
+	"time"
+)
+
+// dailyDeadlineFormat формат времени, до которого принимаются ответы на вопрос дня
+const dailyDeadlineFormat = "02.01 15:04 UTC"
+
+// dailySendBudget сколько времени один запуск daily_send тратит на отправку: функция прерывается
+// через TIMEOUT (2 секунды), и в тот же вызов должны уместиться другие задачи
+const dailySendBudget = 700 * time.Millisecond
+
+// DailyHandler обработчик команды /daily: подписка на вопрос дня, а для администратора — очередь вопросов дня
+type DailyHandler struct {
+	*BaseHandler
+}
+
+// NewDailyHandler создает новый обработчик команды daily
+func NewDailyHandler(bot *tgbotapi.BotAPI, stores *repository.Stores) *DailyHandler {
+	return &DailyHandler{
+		BaseHandler: NewBaseHandler(bot, stores),
+	}
+}
+
+// GetCommand возвращает название команды
+func (h *DailyHandler) GetCommand() string {
+	return "daily"
+}
+
+// Handle обрабатывает команды "/daily", "/daily on", "/daily off" и "/daily queue 12 15 20-25"
+func (h *DailyHandler) Handle(message *tgbotapi.Message) error {
+	chat, err := h.GetOrCreateChat(message.Chat.ID, &message.Chat.Title)
+	if err != nil {
+		fmt.Printf("Failed to get or create chat: %v\n", err)
+		return h.SendMessage(message.Chat.ID, "Произошла ошибка при обработке команды", nil)
+	}
+
+	args := strings.Fields(strings.ToLower(message.CommandArguments()))
+	if len(args) == 0 {
+		return h.SendMessage(message.Chat.ID, h.dailyStatusText(chat), nil)
+	}
+
+	switch args[0] {
+	case "on", "off":
+		enabled := args[0] == "on"
+		if err := h.chatRepo.SetDailyEnabled(chat.ID, enabled); err != nil {
+			fmt.Printf("Failed to set daily subscription: %v (chat_id: %d)\n", err, chat.ID)
+			return h.SendMessage(message.Chat.ID, "Произошла ошибка при обработке команды", nil)
+		}
+		if !enabled {
+			return h.SendMessage(message.Chat.ID, "Вопрос дня выключен\\. Включить снова: /daily on", nil)
+		}
+
+		// Задача выбора вопроса дня одна на всех; первая подписка ставит ее в очередь
+		if err := h.scheduleDailyStart(time.Now().UTC()); err != nil {
+			fmt.Printf("Failed to schedule daily question: %v\n", err)
+		}
+		text := fmt.Sprintf("Вопрос дня включен: он будет приходить каждый день в %s\\. Выключить: /daily off",
+			h.EscapeMarkdown(fmt.Sprintf("%02d:00 UTC", h.daily.Hour)))
+		return h.SendMessage(message.Chat.ID, text, nil)
+	case "queue":
+		if h.IsAdminChat(message.Chat.ID) {
+			return h.queue(message.Chat.ID, args[1:])
+		}
+	}
+
+	return h.SendMessage(message.Chat.ID, h.dailyStatusText(chat), nil)
+}
+
+// dailyStatusText описывает подписку чата на вопрос дня
+func (h *DailyHandler) dailyStatusText(chat *models.Chat) string {
+	status := "выключен"
+	if chat.DailyEnabled {
+		status = "включен"
+	}
+
+	text := fmt.Sprintf("*Вопрос дня:* %s\n\nКаждый день в %s подписанные чаты получают один и тот же вопрос\\. "+
+		"Ответы принимаются в течение %s, а после этого приходит ответ и доля чатов, ответивших правильно\\. "+
+		"Вопрос дня бесплатный\\.\n\nИспользование: /daily on\\|off",
+		status, h.EscapeMarkdown(fmt.Sprintf("%02d:00 UTC", h.daily.Hour)), h.EscapeMarkdown(formatDuration(h.daily.Window)))
+	if h.IsAdminChat(chat.TelegramID) {
+		text += "\nДобавить вопросы в очередь: /daily queue 12 15 20\\-25"
+	}
+	return text
+}
+
+// queue добавляет вопросы в очередь вопросов дня (только для администратора)
+func (h *DailyHandler) queue(adminChatID int64, args []string) error {
+	var questionIDs []uint
+	for _, arg := range args {
+		from, to, ok := parseIDRange(arg)
+		if !ok || to < from || to-from >= tagBatchMaxSize {
+			return h.SendMessage(adminChatID, "Использование: /daily queue 12 15 20\\-25", nil)
+		}
+		for id := from; id <= to; id++ {
+			questionIDs = append(questionIDs, id)
+		}
+	}
+	if len(questionIDs) == 0 || len(questionIDs) > tagBatchMaxSize {
+		return h.SendMessage(adminChatID, "Использование: /daily queue 12 15 20\\-25", nil)
+	}
+
+	queued, err := h.dailyRepo.Queue(questionIDs)
+	if err != nil {
+		fmt.Printf("Failed to queue daily questions: %v\n", err)
+		return h.SendMessage(adminChatID, "Произошла ошибка при добавлении вопросов в очередь", nil)
+	}
+
+	text := fmt.Sprintf("В очередь вопросов дня добавлено вопросов — %d из %d\\.", queued, len(questionIDs))
+	if queued < len(questionIDs) {
+		text += "\n_Остальные не опубликованы, уже в очереди или уже были вопросами дня\\._"
+	}
+	return h.SendMessage(adminChatID, text, nil)
+}
+
+// HandleAnswer проверяет, не ответ ли это на вопрос дня; false — чат не ждет ответа на вопрос дня.
+// В группах неверными считаются только сообщения, адресованные боту, чтобы не отвечать на каждое сообщение до конца дня
+func (h *DailyHandler) HandleAnswer(message *tgbotapi.Message, chat *models.Chat) (bool, error) {
+	daily, err := h.dailyRepo.GetOpen(chat.ID, time.Now().UTC())
+	if err != nil {
+		fmt.Printf("Failed to get daily question: %v (chat_id: %d)\n", err, chat.ID)
+		return false, nil
+	}
+	if daily == nil {
+		return false, nil
+	}
+
+	question, err := h.questionRepo.GetByID(daily.QuestionID)
+	if err != nil {
+		fmt.Printf("Failed to get daily question: %v (chat_id: %d, question_id: %d)\n", err, chat.ID, daily.QuestionID)
+		return false, nil
+	}
+
+	input, addressed := h.answerText(message)
+	result := h.matcher.Match(input, question.AcceptedAnswers()...)
+	if !result.Accepted() {
+		if !addressed {
+			return false, nil
+		}
+
+		text := fmt.Sprintf("Ответ на вопрос дня неверный\\. Попробуйте еще раз до %s",
+			h.EscapeMarkdown(daily.ExpiresAt.UTC().Format(dailyDeadlineFormat)))
+		return true, h.SendMessage(message.Chat.ID, text, nil)
+	}
+
+	first, err := h.dailyRepo.MarkAnswered(daily.ID, chat.ID)
+	if err != nil {
+		fmt.Printf("Failed to mark daily answer: %v (chat_id: %d, daily_id: %d)\n", err, chat.ID, daily.ID)
+		return true, h.SendMessage(message.Chat.ID, "Произошла ошибка при обработке ответа", nil)
+	}
+	if !first {
+		// Правильный ответ уже пришел параллельно
+		return true, nil
+	}
+
+	text := "*Верно, это правильный ответ на вопрос дня\\!* Сколько чатов ответили правильно, вы узнаете, когда прием ответов закончится\\."
+	if question.Comment != nil {
+		text += "\n\n" + h.EscapeMarkdown(*question.Comment)
+	}
+	return true, h.SendMessage(message.Chat.ID, text, nil)
+}
+
+// scheduleDailyStart ставит в очередь выбор вопроса дня на ближайшее после after время рассылки, если он еще не стоит
+func (h *BaseHandler) scheduleDailyStart(after time.Time) error {
+	_, err := h.jobRepo.ScheduleOnce(&models.ScheduledJob{
+		RunAt: h.daily.NextStart(after),
+		Kind:  models.JobDailyStart,
+	})
+	return err
+}
+
+// DailyStartJob обработчик задачи "daily_start": выбирает вопрос дня, запускает рассылку и ставит в очередь итоги
+type DailyStartJob struct {
+	*BaseHandler
+}
+
+// NewDailyStartJob создает новый обработчик задачи daily_start
+func NewDailyStartJob(bot *tgbotapi.BotAPI, stores *repository.Stores) *DailyStartJob {
+	return &DailyStartJob{
+		BaseHandler: NewBaseHandler(bot, stores),
+	}
+}
+
+// GetJobKind возвращает тип задачи
+func (h *DailyStartJob) GetJobKind() models.JobKind {
+	return models.JobDailyStart
+}
+
+// Handle обрабатывает задачу "daily_start". Задача не удаляется из очереди, а переносится на следующее
+// время рассылки; если запуск прервется раньше, задачу повторит следующий вызов планировщика
+func (h *DailyStartJob) Handle(job *models.ScheduledJob) error {
+	day := job.RunAt.UTC().Truncate(24 * time.Hour)
+	daily, err := h.dailyRepo.Start(day, time.Now().UTC().Add(h.daily.Window))
+	if err != nil {
+		return fmt.Errorf("failed to start daily question: %w", err)
+	}
+	if daily == nil {
+		fmt.Printf("Daily question is not started: already started, no questions left or no subscribers (day: %s)\n", day.Format("2006-01-02"))
+	} else {
+		if err := h.jobRepo.Schedule(&models.ScheduledJob{
+			RunAt:      time.Now().UTC(),
+			Kind:       models.JobDailySend,
+			QuestionID: &daily.QuestionID,
+		}); err != nil {
+			return fmt.Errorf("failed to schedule daily question delivery: %w", err)
+		}
+		if err := h.jobRepo.Schedule(&models.ScheduledJob{
+			RunAt:      daily.ExpiresAt,
+			Kind:       models.JobDailySummary,
+			QuestionID: &daily.QuestionID,
+		}); err != nil {
+			return fmt.Errorf("failed to schedule daily question summary: %w", err)
+		}
+	}
+
+	if err := h.jobRepo.Reschedule(job, h.daily.NextStart(job.RunAt)); err != nil {
+		return fmt.Errorf("failed to schedule next daily question: %w", err)
+	}
+	return nil
+}
+
+// DailySendJob обработчик задачи "daily_send": отправляет вопрос дня следующей пачке чатов
+type DailySendJob struct {
+	*BaseHandler
+}
+
+// NewDailySendJob создает новый обработчик задачи daily_send
+func NewDailySendJob(bot *tgbotapi.BotAPI, stores *repository.Stores) *DailySendJob {
+	return &DailySendJob{
+		BaseHandler: NewBaseHandler(bot, stores),
+	}
+}
+
+// GetJobKind возвращает тип задачи
+func (h *DailySendJob) GetJobKind() models.JobKind {
+	return models.JobDailySend
+}
+
+// Handle обрабатывает задачу "daily_send", отправляя пачку через sendDailyBatch.
+// Пачка каждый раз собирается из неотправленных доставок: если вызов прервали по таймауту, задачу по истечении
+// аренды повторит следующий запуск и продолжит с того же места
+func (h *DailySendJob) Handle(job *models.ScheduledJob) error {
+	if job.QuestionID == nil {
+		return nil
+	}
+
+	daily, err := h.dailyRepo.GetByQuestionID(*job.QuestionID)
+	if err != nil {
+		return fmt.Errorf("failed to get daily question: %w", err)
+	}
+	// Чатам, до которых очередь не дошла за время приема ответов, вопрос уже не отправляем
+	if !time.Now().UTC().Before(daily.ExpiresAt) {
+		return nil
+	}
+
+	question, err := h.questionRepo.GetByID(daily.QuestionID)
+	if err != nil {
+		return fmt.Errorf("failed to get question: %w", err)
+	}
+
+	deliveries, err := h.dailyRepo.GetPending(daily.ID, h.daily.Rate)
+	if err != nil {
+		return fmt.Errorf("failed to get pending deliveries: %w", err)
+	}
+	if len(deliveries) == 0 {
+		return nil
+	}
+
+	text := h.dailyMessageText(daily, question)
+	return h.sendDailyBatch(job, deliveries, func(delivery *models.DailyDelivery) (time.Duration, error) {
+		return h.deliver(daily, question, delivery, text)
+	})
+}
+
+// deliver отправляет вопрос дня одному чату и отмечает результат.
+// Возвращает, сколько ждать перед следующей отправкой, если Telegram ответил 429
+func (h *DailySendJob) deliver(daily *models.DailyQuestion, question *models.Question, delivery *models.DailyDelivery, text string) (time.Duration, error) {
+	_, err := h.sendQuestionText(delivery.Chat.TelegramID, question, text, nil)
+	if err == nil {
+		return 0, h.dailyRepo.MarkSent(daily.ID, delivery.ChatID)
+	}
+	if retryAfter := h.dailySendFailed(err, delivery); retryAfter > 0 {
+		return retryAfter, nil
+	}
+	return 0, h.dailyRepo.MarkFailed(daily.ID, delivery.ChatID)
+}
+
+// dailyMessageText собирает сообщение с вопросом дня
+func (h *DailySendJob) dailyMessageText(daily *models.DailyQuestion, question *models.Question) string {
+	deadline := "Ответьте сообщением до " + daily.ExpiresAt.UTC().Format(dailyDeadlineFormat)
+	return "*Вопрос дня*\n\n" + h.FormatQuestionText(question) + "\n\n_" + h.EscapeMarkdown(deadline) + "_"
+}
+
+// DailySummaryJob обработчик задачи "daily_summary": когда прием ответов закрыт, отправляет следующей пачке чатов,
+// получивших вопрос дня, ответ и долю чатов, ответивших правильно
+type DailySummaryJob struct {
+	*BaseHandler
+}
+
+// NewDailySummaryJob создает новый обработчик задачи daily_summary
+func NewDailySummaryJob(bot *tgbotapi.BotAPI, stores *repository.Stores) *DailySummaryJob {
+	return &DailySummaryJob{
+		BaseHandler: NewBaseHandler(bot, stores),
+	}
+}
+
+// GetJobKind возвращает тип задачи
+func (h *DailySummaryJob) GetJobKind() models.JobKind {
+	return models.JobDailySummary
+}
+
+// Handle обрабатывает задачу "daily_summary". Итоги рассылаются пачками так же, как вопрос дня в daily_send
+func (h *DailySummaryJob) Handle(job *models.ScheduledJob) error {
+	if job.QuestionID == nil {
+		return nil
+	}
+
+	daily, err := h.dailyRepo.GetByQuestionID(*job.QuestionID)
+	if err != nil {
+		return fmt.Errorf("failed to get daily question: %w", err)
+	}
+
+	deliveries, err := h.dailyRepo.GetUnsummarized(daily.ID, h.daily.Rate)
+	if err != nil {
+		return fmt.Errorf("failed to get unsummarized deliveries: %w", err)
+	}
+	if len(deliveries) == 0 {
+		return nil
+	}
+
+	question, err := h.questionRepo.GetByID(daily.QuestionID)
+	if err != nil {
+		return fmt.Errorf("failed to get question: %w", err)
+	}
+	sent, answered, err := h.dailyRepo.Summary(daily.ID)
+	if err != nil {
+		return fmt.Errorf("failed to get daily summary: %w", err)
+	}
+
+	text := fmt.Sprintf("*Итоги вопроса дня*\n%s\nОтвет: *%s*\nПравильно ответили %d%% чатов",
+		h.EscapeMarkdown(question.Text), h.EscapeMarkdown(question.Answer), models.AnsweredPercent(answered, sent))
+	return h.sendDailyBatch(job, deliveries, func(delivery *models.DailyDelivery) (time.Duration, error) {
+		if err := h.SendMessage(delivery.Chat.TelegramID, text, nil); err != nil {
+			if retryAfter := h.dailySendFailed(err, delivery); retryAfter > 0 {
+				return retryAfter, nil
+			}
+		}
+		return 0, h.dailyRepo.MarkSummarySent(daily.ID, delivery.ChatID)
+	})
+}
+
+// sendDailyBatch отправляет пачку рассылки вопроса дня через send. За один запуск отправляется не больше DAILY_RATE
+// сообщений с равными промежутками и не дольше dailySendBudget, а затем задача переносится на следующую пачку не раньше
+// чем через секунду, поэтому рассылка не превышает лимит Telegram даже при вызовах функции подряд.
+// send возвращает, сколько ждать перед следующей отправкой, если Telegram ответил 429
+func (h *BaseHandler) sendDailyBatch(job *models.ScheduledJob, deliveries []models.DailyDelivery, send func(delivery *models.DailyDelivery) (time.Duration, error)) error {
+	startedAt := time.Now()
+	for i := range deliveries {
+		delivery := &deliveries[i]
+		sendAt := startedAt.Add(time.Duration(i) * h.daily.SendInterval())
+		if sendAt.Sub(startedAt) > dailySendBudget || time.Since(startedAt) > dailySendBudget {
+			// Остальные чаты пачки получат сообщение в следующий запуск
+			break
+		}
+		time.Sleep(time.Until(sendAt))
+
+		retryAfter, err := send(delivery)
+		if err != nil {
+			fmt.Printf("Failed to save daily delivery: %v (chat_id: %d, daily_id: %d)\n", err, delivery.ChatID, delivery.DailyQuestionID)
+		}
+		if retryAfter > 0 {
+			// Telegram просит подождать: остальные чаты этой пачки получат сообщение со следующей
+			return h.jobRepo.Reschedule(job, time.Now().UTC().Add(retryAfter))
+		}
+	}
+
+	return h.jobRepo.Reschedule(job, startedAt.UTC().Add(time.Second))
+}
+
+// dailySendFailed разбирает ошибку отправки рассылки вопроса дня: чат, заблокировавший бота, отписывается.
+// Возвращает, сколько ждать перед следующей отправкой, если Telegram ответил 429; иначе отправка чату считается неудачной
+func (h *BaseHandler) dailySendFailed(err error, delivery *models.DailyDelivery) time.Duration {
+	var apiErr *tgbotapi.Error
+	if errors.As(err, &apiErr) {
+		switch apiErr.Code {
+		case http.StatusTooManyRequests:
+			return max(time.Duration(apiErr.RetryAfter)*time.Second, time.Second)
+		case http.StatusForbidden:
+			fmt.Printf("Bot is blocked, disabling daily question: %v (chat_id: %d)\n", err, delivery.ChatID)
+			if err := h.chatRepo.SetDailyEnabled(delivery.ChatID, false); err != nil {
+				fmt.Printf("Failed to disable daily question: %v (chat_id: %d)\n", err, delivery.ChatID)
+			}
+			return 0
+		}
+	}
+
+	fmt.Printf("Failed to send daily question: %v (chat_id: %d, daily_id: %d)\n", err, delivery.ChatID, delivery.DailyQuestionID)
+	return 0
+}
@@ -0,0 +1,79 @@
+package handlers
+
+import (
+	"fmt"
+	tgbotapi "github.com/go-telegram-bot-api/telegram-bot-api/v5"
+	"qweasley/internal/models"
+	"qweasley/internal/repository"
+	"qweasley/internal/selection"
+	"strings"
+)
+
+// difficultyTitles названия режимов сложности
+var difficultyTitles = map[models.DifficultyMode]string{
+	models.DifficultyRandom:   "случайные вопросы",
+	models.DifficultyEasy:     "легкие вопросы",
+	models.DifficultyNormal:   "вопросы средней сложности",
+	models.DifficultyHard:     "сложные вопросы",
+	models.DifficultyAdaptive: "вопросы под ваш уровень",
+}
+
+// difficultyUsage подсказка по аргументам команды /difficulty
+const difficultyUsage = "Использование: /difficulty easy\\|normal\\|hard\\|adaptive\\|random\n\n" +
+	"easy, normal, hard — легкие, средние или сложные вопросы\n" +
+	"adaptive — вопросы под ваш уровень, он растет с правильными ответами\n" +
+	"random — случайные вопросы \\(по умолчанию\\)"
+
+// DifficultyHandler обработчик команды /difficulty
+type DifficultyHandler struct {
+	*BaseHandler
+}
+
+// NewDifficultyHandler создает новый обработчик команды difficulty
+func NewDifficultyHandler(bot *tgbotapi.BotAPI, stores *repository.Stores) *DifficultyHandler {
+	return &DifficultyHandler{
+		BaseHandler: NewBaseHandler(bot, stores),
+	}
+}
+
+// GetCommand возвращает название команды
+func (h *DifficultyHandler) GetCommand() string {
+	return "difficulty"
+}
+
+// Handle обрабатывает команду /difficulty: без аргументов показывает режим и уровень чата, с аргументом — меняет режим
+func (h *DifficultyHandler) Handle(message *tgbotapi.Message) error {
+	chat, err := h.GetOrCreateChat(message.Chat.ID, &message.Chat.Title)
+	if err != nil {
+		fmt.Printf("Failed to get or create chat: %v\n", err)
+		return h.SendMessage(message.Chat.ID, "Произошла ошибка при обработке команды", nil)
+	}
+
+	arg := strings.ToLower(strings.TrimSpace(message.CommandArguments()))
+	if arg == "" {
+		text := fmt.Sprintf("*Сложность:* %s\nВаш уровень: %.0f\n\n%s",
+			h.EscapeMarkdown(difficultyTitle(chat.DifficultyMode)), chat.Skill, difficultyUsage)
+		return h.SendMessage(message.Chat.ID, text, nil)
+	}
+
+	mode := models.DifficultyMode(arg)
+	if _, ok := selection.ForMode(mode); !ok {
+		return h.SendMessage(message.Chat.ID, difficultyUsage, nil)
+	}
+
+	if err := h.chatRepo.SetDifficultyMode(chat.ID, mode); err != nil {
+		fmt.Printf("Failed to set difficulty mode: %v (chat_id: %d)\n", err, chat.ID)
+		return h.SendMessage(message.Chat.ID, "Произошла ошибка при обработке команды", nil)
+	}
+
+	text := fmt.Sprintf("Теперь вам задаются %s\\.", h.EscapeMarkdown(difficultyTitle(mode)))
+	return h.SendMessage(message.Chat.ID, text, nil)
+}
+
+// difficultyTitle возвращает название режима; пустой режим у старых чатов означает случайные вопросы
+func difficultyTitle(mode models.DifficultyMode) string {
+	if title, ok := difficultyTitles[mode]; ok {
+		return title
+	}
+	return difficultyTitles[models.DifficultyRandom]
+}
@@ -1,8 +1,10 @@
 package handlers
 
 import (
+	"errors"
 	"fmt"
 	tgbotapi "github.com/go-telegram-bot-api/telegram-bot-api/v5"
+	"qweasley/internal/repository"
 )
 
 // FailCallback обработчик callback'а "fail"
@@ -11,9 +13,9 @@ type FailCallback struct {
 }
 
 // NewFailCallback создает новый обработчик callback'а fail
-func NewFailCallback(bot *tgbotapi.BotAPI) *FailCallback {
+func NewFailCallback(bot *tgbotapi.BotAPI, stores *repository.Stores) *FailCallback {
 	return &FailCallback{
-		BaseHandler: NewBaseHandler(bot),
+		BaseHandler: NewBaseHandler(bot, stores),
 	}
 }
 
@@ -24,37 +26,51 @@ func (h *FailCallback) GetCallbackData() string {
 
 // Handle обрабатывает callback "fail"
 func (h *FailCallback) Handle(callback *tgbotapi.CallbackQuery) error {
-	// Отвечаем на callback query
-	if err := h.AnswerCallbackQuery(callback.ID); err != nil {
-		fmt.Printf("Failed to answer callback query: %v\n", err)
+	// Получаем вопрос, к которому привязана кнопка
+	questionID, ok := h.QuestionFromCallback(callback)
+	if !ok {
+		return nil
 	}
 
 	// Получаем чат пользователя
 	chat, err := h.GetOrCreateChat(callback.Message.Chat.ID, &callback.Message.Chat.Title)
 	if err != nil {
+		h.AnswerCallbackQuery(callback.ID)
 		fmt.Printf("Failed to get or create chat in fail callback: %v (chat_id: %d)\n", err, callback.Message.Chat.ID)
 		return h.SendMessage(callback.Message.Chat.ID, "Произошла ошибка при обработке команды", nil)
 	}
 
-	// Проверяем, есть ли активный вопрос
-	if chat.LastQuestionID == nil {
-		return h.SendMessage(callback.Message.Chat.ID, "Нет активного вопроса", nil)
+	// Кнопка под старым сообщением не должна действовать на текущий вопрос
+	if chat.LastQuestionID == nil || *chat.LastQuestionID != questionID {
+		return h.AnswerCallbackAlert(callback.ID, closedQuestionAlert)
+	}
+
+	// Отвечаем на callback query
+	if err := h.AnswerCallbackQuery(callback.ID); err != nil {
+		fmt.Printf("Failed to answer callback query: %v\n", err)
 	}
 
-	// Получаем вопрос по ID из чата
-	question, err := h.questionRepo.GetByID(*chat.LastQuestionID)
+	// Получаем вопрос
+	question, err := h.questionRepo.GetByID(questionID)
 	if err != nil {
-		fmt.Printf("Failed to get question in fail callback: %v (chat_id: %d, question_id: %d)\n", err, chat.ID, *chat.LastQuestionID)
+		fmt.Printf("Failed to get question in fail callback: %v (chat_id: %d, question_id: %d)\n", err, chat.ID, questionID)
 		return h.SendMessage(callback.Message.Chat.ID, "Произошла ошибка при обработке команды", nil)
 	}
 
 	// Обрабатываем реакцию "показать ответ"
 	err = h.ProcessFailReaction(chat.ID, question.ID)
+	if errors.Is(err, repository.ErrQuestionChanged) {
+		// Повторное нажатие: вопрос уже обработан
+		return nil
+	}
 	if err != nil {
 		fmt.Printf("Failed to process fail reaction: %v (chat_id: %d, question_id: %d)\n", err, chat.ID, question.ID)
 		return h.SendMessage(callback.Message.Chat.ID, "Произошла ошибка при обработке команды", nil)
 	}
 
+	// Убираем кнопки под вопросом, ответ на который показан
+	h.ResolveQuestionMessage(callback.Message.Chat.ID, callback.Message.MessageID, question, "Ответ показан: "+question.Answer)
+
 	// Проверяем наличие картинки ответа
 	if question.AnswerPicture != nil && question.AnswerPicture.Path != nil {
 		// Формируем URL картинки
@@ -66,7 +82,7 @@ func (h *FailCallback) Handle(callback *tgbotapi.CallbackQuery) error {
 			if question.Comment != nil {
 				answerText += "\n\n" + h.EscapeMarkdown(*question.Comment)
 			}
-			keyboard := h.CreateContinueKeyboard()
+			keyboard := h.CreateContinueKeyboard(callback.Message.Chat.ID, question.ID)
 			return h.SendMessage(callback.Message.Chat.ID, answerText, keyboard)
 		}
 
@@ -77,7 +93,7 @@ func (h *FailCallback) Handle(callback *tgbotapi.CallbackQuery) error {
 		}
 
 		// Создаем клавиатуру
-		keyboard := h.CreateContinueKeyboard()
+		keyboard := h.CreateContinueKeyboard(callback.Message.Chat.ID, question.ID)
 
 		// Отправляем фото
 		return h.SendPhoto(callback.Message.Chat.ID, photoURL, caption, keyboard)
@@ -89,7 +105,7 @@ func (h *FailCallback) Handle(callback *tgbotapi.CallbackQuery) error {
 		answerText += "\n\n" + h.EscapeMarkdown(*question.Comment)
 	}
 
-	keyboard := h.CreateContinueKeyboard()
+	keyboard := h.CreateContinueKeyboard(callback.Message.Chat.ID, question.ID)
 
 	return h.SendMessage(callback.Message.Chat.ID, answerText, keyboard)
 }
@@ -3,23 +3,24 @@ package handlers
 import (
 	"fmt"
 	tgbotapi "github.com/go-telegram-bot-api/telegram-bot-api/v5"
-	"os"
 	"qweasley/internal/models"
 	"qweasley/internal/repository"
+	"strconv"
+	"strings"
 	"time"
 )
 
 // FeedbackHandler обработчик команды /feedback
 type FeedbackHandler struct {
 	*BaseHandler
-	feedbackRepo *repository.FeedbackRepository
+	feedbackRepo repository.FeedbackStore
 }
 
 // NewFeedbackHandler создает новый обработчик команды feedback
-func NewFeedbackHandler(bot *tgbotapi.BotAPI) *FeedbackHandler {
+func NewFeedbackHandler(bot *tgbotapi.BotAPI, stores *repository.Stores) *FeedbackHandler {
 	return &FeedbackHandler{
-		BaseHandler:  NewBaseHandler(bot),
-		feedbackRepo: repository.NewFeedbackRepository(),
+		BaseHandler:  NewBaseHandler(bot, stores),
+		feedbackRepo: stores.Feedbacks,
 	}
 }
 
@@ -92,15 +93,144 @@ func (h *FeedbackHandler) HandleFeedbackMessage(message *tgbotapi.Message) error
 		return err
 	}
 
-	// Отправляем уведомление администратору
-	adminChatID := os.Getenv("ADMIN_CHAT_ID")
-	if adminChatID != "" {
-		adminID := int64(0)
-		fmt.Sscanf(adminChatID, "%d", &adminID)
-		if adminID != 0 {
-			h.SendMessage(adminID, "Новое сообщение в форме обратной связи", nil)
+	// Пересылаем сообщение администратору
+	if adminID := h.GetAdminChatID(); adminID != 0 {
+		text := h.FormatFeedbackCard(feedback, message.Chat.ID, describeChat(message.Chat))
+		messageID, err := h.SendMessageWithID(adminID, text, h.CreateFeedbackReplyKeyboard(feedback.ID))
+		if err != nil {
+			fmt.Printf("Failed to forward feedback to admin: %v (feedback_id: %d)\n", err, feedback.ID)
+			return nil
+		}
+
+		if err := h.feedbackRepo.SetAdminMessageID(feedback.ID, messageID); err != nil {
+			fmt.Printf("Failed to save admin message id: %v (feedback_id: %d)\n", err, feedback.ID)
 		}
 	}
 
 	return nil
 }
+
+// FormatFeedbackCard форматирует обратную связь для чата администратора
+func (h *FeedbackHandler) FormatFeedbackCard(feedback *models.Feedback, telegramID int64, chatName string) string {
+	text := fmt.Sprintf("*Обратная связь \\#%d*\nЧат: `%d`", feedback.ID, telegramID)
+	if chatName != "" {
+		text += " " + h.EscapeMarkdown(chatName)
+	}
+	text += "\n\n" + h.EscapeMarkdown(feedback.Text)
+	text += "\n\n_Ответьте на это сообщение или нажмите «Ответить»_"
+	return text
+}
+
+// HandleAdminReply отправляет ответ администратора автору обратной связи
+func (h *FeedbackHandler) HandleAdminReply(message *tgbotapi.Message, feedback *models.Feedback) error {
+	response := strings.TrimSpace(message.Text)
+	if response == "" {
+		return h.SendMessage(message.Chat.ID, "Ответ должен быть текстом\\.", nil)
+	}
+
+	if err := h.feedbackRepo.SaveResponse(feedback.ID, response); err != nil {
+		fmt.Printf("Failed to save feedback response: %v (feedback_id: %d)\n", err, feedback.ID)
+		return h.SendMessage(message.Chat.ID, "Произошла ошибка при сохранении ответа", nil)
+	}
+
+	text := "*Ответ на ваше сообщение:*\n" + h.EscapeMarkdown(response)
+	if err := h.SendMessage(feedback.Chat.TelegramID, text, nil); err != nil {
+		fmt.Printf("Failed to deliver feedback response: %v (feedback_id: %d)\n", err, feedback.ID)
+		return h.SendMessage(message.Chat.ID, fmt.Sprintf("Ответ на \\#%d сохранен, но не доставлен", feedback.ID), nil)
+	}
+
+	return h.SendMessage(message.Chat.ID, fmt.Sprintf("Ответ на \\#%d отправлен", feedback.ID), nil)
+}
+
+// HandleAdminMessage обрабатывает сообщение администратора, если это ответ на обратную связь.
+// Возвращает false, если сообщение к обратной связи не относится
+func (h *FeedbackHandler) HandleAdminMessage(message *tgbotapi.Message, chat *models.Chat) (bool, error) {
+	if !h.IsAdminChat(message.Chat.ID) {
+		return false, nil
+	}
+
+	// Администратор ответил на пересланное сообщение обратной связи
+	if message.ReplyToMessage != nil {
+		feedback, err := h.feedbackRepo.GetByAdminMessageID(message.ReplyToMessage.MessageID)
+		if err == nil {
+			return true, h.HandleAdminReply(message, feedback)
+		}
+	}
+
+	// Администратор нажал «Ответить» и пишет ответ следующим сообщением
+	if chat.IsReplyingFeedback() {
+		feedbackID := *chat.ReplyFeedbackID
+		if err := h.chatRepo.ClearReplyFeedback(chat.ID); err != nil {
+			fmt.Printf("Failed to clear reply feedback: %v (chat_id: %d)\n", err, chat.ID)
+		}
+
+		feedback, err := h.feedbackRepo.GetByID(feedbackID)
+		if err != nil {
+			fmt.Printf("Failed to get feedback: %v (feedback_id: %d)\n", err, feedbackID)
+			return true, h.SendMessage(message.Chat.ID, "Сообщение обратной связи не найдено", nil)
+		}
+
+		return true, h.HandleAdminReply(message, feedback)
+	}
+
+	return false, nil
+}
+
+// describeChat возвращает человекочитаемое название чата
+func describeChat(chat *tgbotapi.Chat) string {
+	if chat.Title != "" {
+		return chat.Title
+	}
+	if chat.UserName != "" {
+		return "@" + chat.UserName
+	}
+	return strings.TrimSpace(chat.FirstName + " " + chat.LastName)
+}
+
+// FeedbackReplyCallback обработчик callback'а "feedback_reply"
+type FeedbackReplyCallback struct {
+	*BaseHandler
+}
+
+// NewFeedbackReplyCallback создает новый обработчик callback'а feedback_reply
+func NewFeedbackReplyCallback(bot *tgbotapi.BotAPI, stores *repository.Stores) *FeedbackReplyCallback {
+	return &FeedbackReplyCallback{
+		BaseHandler: NewBaseHandler(bot, stores),
+	}
+}
+
+// GetCallbackData возвращает данные callback'а
+func (h *FeedbackReplyCallback) GetCallbackData() string {
+	return "feedback_reply"
+}
+
+// Handle обрабатывает callback вида "feedback_reply:<id обратной связи>"
+func (h *FeedbackReplyCallback) Handle(callback *tgbotapi.CallbackQuery) error {
+	// Отвечаем на callback query
+	if err := h.AnswerCallbackQuery(callback.ID); err != nil {
+		fmt.Printf("Failed to answer callback query: %v\n", err)
+	}
+
+	if !h.IsAdminChat(callback.Message.Chat.ID) {
+		return nil
+	}
+
+	_, idText, _ := strings.Cut(callback.Data, ":")
+	feedbackID, err := strconv.ParseUint(idText, 10, 64)
+	if err != nil {
+		return fmt.Errorf("некорректный ID обратной связи: %s", idText)
+	}
+
+	chat, err := h.GetOrCreateChat(callback.Message.Chat.ID, &callback.Message.Chat.Title)
+	if err != nil {
+		fmt.Printf("Failed to get or create chat: %v\n", err)
+		return h.SendMessage(callback.Message.Chat.ID, "Произошла ошибка при обработке команды", nil)
+	}
+
+	if err := h.chatRepo.SetReplyFeedback(chat.ID, uint(feedbackID), 30*time.Minute); err != nil {
+		fmt.Printf("Failed to set reply feedback: %v (feedback_id: %d)\n", err, feedbackID)
+		return h.SendMessage(callback.Message.Chat.ID, "Произошла ошибка при обработке команды", nil)
+	}
+
+	return h.SendMessage(callback.Message.Chat.ID, fmt.Sprintf("Напишите ответ на \\#%d следующим сообщением\\.", feedbackID), nil)
+}
@@ -1,8 +1,10 @@
 package handlers
 
 import (
+	"errors"
 	"fmt"
 	tgbotapi "github.com/go-telegram-bot-api/telegram-bot-api/v5"
+	"qweasley/internal/repository"
 )
 
 // FinishCallback обработчик callback'а "finish"
@@ -11,9 +13,9 @@ type FinishCallback struct {
 }
 
 // NewFinishCallback создает новый обработчик callback'а finish
-func NewFinishCallback(bot *tgbotapi.BotAPI) *FinishCallback {
+func NewFinishCallback(bot *tgbotapi.BotAPI, stores *repository.Stores) *FinishCallback {
 	return &FinishCallback{
-		BaseHandler: NewBaseHandler(bot),
+		BaseHandler: NewBaseHandler(bot, stores),
 	}
 }
 
@@ -24,27 +26,58 @@ func (h *FinishCallback) GetCallbackData() string {
 
 // Handle обрабатывает callback "finish"
 func (h *FinishCallback) Handle(callback *tgbotapi.CallbackQuery) error {
-	// Отвечаем на callback query
-	if err := h.AnswerCallbackQuery(callback.ID); err != nil {
-		fmt.Printf("Failed to answer callback query: %v\n", err)
+	// Получаем вопрос, к которому привязана кнопка
+	questionID, ok := h.QuestionFromCallback(callback)
+	if !ok {
+		return nil
 	}
 
 	// Получаем чат пользователя
 	chat, err := h.GetOrCreateChat(callback.Message.Chat.ID, &callback.Message.Chat.Title)
 	if err != nil {
+		h.AnswerCallbackQuery(callback.ID)
 		fmt.Printf("Failed to get or create chat in finish callback: %v (chat_id: %d)\n", err, callback.Message.Chat.ID)
 		return h.SendMessage(callback.Message.Chat.ID, "Произошла ошибка при обработке команды", nil)
 	}
 
-	// Если есть активный вопрос, обрабатываем реакцию "закончить"
+	// Кнопка под старым сообщением не должна закрывать другой, уже заданный вопрос
+	if chat.LastQuestionID != nil && *chat.LastQuestionID != questionID {
+		return h.AnswerCallbackAlert(callback.ID, closedQuestionAlert)
+	}
+
+	// Отвечаем на callback query
+	if err := h.AnswerCallbackQuery(callback.ID); err != nil {
+		fmt.Printf("Failed to answer callback query: %v\n", err)
+	}
+
+	// Если вопрос еще активен, обрабатываем реакцию "закончить"
 	if chat.LastQuestionID != nil {
-		err = h.ProcessFinishReaction(chat.ID, *chat.LastQuestionID)
-		if err != nil {
-			fmt.Printf("Failed to process finish reaction: %v (chat_id: %d, question_id: %d)\n", err, chat.ID, *chat.LastQuestionID)
+		err = h.ProcessFinishReaction(chat.ID, questionID)
+		if err != nil && !errors.Is(err, repository.ErrQuestionChanged) {
+			fmt.Printf("Failed to process finish reaction: %v (chat_id: %d, question_id: %d)\n", err, chat.ID, questionID)
 			return h.SendMessage(callback.Message.Chat.ID, "Произошла ошибка при обработке команды", nil)
 		}
 	}
 
+	// Убираем кнопки под сообщением: у вопроса дописываем итог, у ответа просто снимаем клавиатуру
+	h.closeMessage(callback, chat.LastQuestionID != nil, questionID)
+
 	text := "Приходите завтра\\! Новые интересные вопросы появляются каждый день\\!"
 	return h.SendMessage(callback.Message.Chat.ID, text, nil)
 }
+
+// closeMessage убирает клавиатуру под сообщением, на котором нажали "закончить"
+func (h *FinishCallback) closeMessage(callback *tgbotapi.CallbackQuery, questionMessage bool, questionID uint) {
+	if questionMessage {
+		question, err := h.questionRepo.GetByID(questionID)
+		if err == nil {
+			h.ResolveQuestionMessage(callback.Message.Chat.ID, callback.Message.MessageID, question, "Игра закончена")
+			return
+		}
+		fmt.Printf("Failed to get question in finish callback: %v (question_id: %d)\n", err, questionID)
+	}
+
+	if err := h.RemoveKeyboard(callback.Message.Chat.ID, callback.Message.MessageID); err != nil {
+		fmt.Printf("Failed to remove keyboard: %v (chat_id: %d, message_id: %d)\n", err, callback.Message.Chat.ID, callback.Message.MessageID)
+	}
+}
@@ -0,0 +1,63 @@
+package handlers
+
+import (
+	"fmt"
+	tgbotapi "github.com/go-telegram-bot-api/telegram-bot-api/v5"
+	"qweasley/internal/repository"
+	"strings"
+)
+
+// groupStatsLimit сколько участников показывать в /groupstats
+const groupStatsLimit = 20
+
+// GroupStatsHandler обработчик команды /groupstats
+type GroupStatsHandler struct {
+	*BaseHandler
+}
+
+// NewGroupStatsHandler создает новый обработчик команды groupstats
+func NewGroupStatsHandler(bot *tgbotapi.BotAPI, stores *repository.Stores) *GroupStatsHandler {
+	return &GroupStatsHandler{
+		BaseHandler: NewBaseHandler(bot, stores),
+	}
+}
+
+// GetCommand возвращает название команды
+func (h *GroupStatsHandler) GetCommand() string {
+	return "groupstats"
+}
+
+// Handle обрабатывает команду /groupstats: рейтинг участников группы по правильным ответам
+func (h *GroupStatsHandler) Handle(message *tgbotapi.Message) error {
+	if !message.Chat.IsGroup() && !message.Chat.IsSuperGroup() {
+		return h.SendMessage(message.Chat.ID, "Эта команда работает в групповых чатах\\. Здесь посмотрите /stats\\.", nil)
+	}
+
+	chat, err := h.GetOrCreateChat(message.Chat.ID, &message.Chat.Title)
+	if err != nil {
+		fmt.Printf("Failed to get or create chat: %v\n", err)
+		return h.SendMessage(message.Chat.ID, "Произошла ошибка при получении статистики", nil)
+	}
+
+	members, err := h.reactionRepo.GetMemberStats(chat.ID, groupStatsLimit)
+	if err != nil {
+		fmt.Printf("Failed to get member stats: %v (chat_id: %d)\n", err, chat.ID)
+		return h.SendMessage(message.Chat.ID, "Произошла ошибка при получении статистики", nil)
+	}
+
+	if len(members) == 0 {
+		return h.SendMessage(message.Chat.ID, "В этой группе еще никто не ответил правильно\\. Начните с /start\\!", nil)
+	}
+
+	lines := make([]string, 0, len(members))
+	for i, member := range members {
+		line := h.EscapeMarkdown(fmt.Sprintf("%d. %s — %d", i+1, member.Name, member.Answered))
+		if message.From != nil && member.ResponderID == message.From.ID {
+			line = "*" + line + "*"
+		}
+		lines = append(lines, line)
+	}
+
+	text := fmt.Sprintf("*Лучшие знатоки группы:*\n\n%s", strings.Join(lines, "\n"))
+	return h.SendMessage(message.Chat.ID, text, nil)
+}
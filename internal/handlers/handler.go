@@ -1,8 +1,24 @@
 package handlers
 
 import (
+	"context"
 	"fmt"
 	tgbotapi "github.com/go-telegram-bot-api/telegram-bot-api/v5"
+	"qweasley/internal/models"
+	"qweasley/internal/repository"
+	"strings"
+	"time"
+)
+
+const (
+	// jobBatchSize сколько отложенных задач забирать из очереди за один запрос
+	jobBatchSize = 20
+	// jobLease на сколько задача закрепляется за запуском; после этого ее заберет следующий запуск
+	jobLease = time.Minute
+	// jobMaxAttempts сколько раз выполнять задачу, прежде чем отказаться от нее
+	jobMaxAttempts = 5
+	// jobRetryDelay базовая задержка повтора; растет квадратично с числом попыток
+	jobRetryDelay = 10 * time.Second
 )
 
 // CommandHandler интерфейс для обработчиков команд
@@ -17,25 +33,44 @@ type CallbackHandler interface {
 	GetCallbackData() string
 }
 
+// JobHandler интерфейс для обработчиков отложенных задач
+type JobHandler interface {
+	Handle(job *models.ScheduledJob) error
+	GetJobKind() models.JobKind
+}
+
 // Registry реестр всех обработчиков
 type Registry struct {
 	commandHandlers  map[string]CommandHandler
 	CallbackHandlers map[string]CallbackHandler
+	jobHandlers      map[models.JobKind]JobHandler
 	textHandler      TextHandler
+	paymentHandler   *PaymentHandler
+	jobRepo          repository.JobStore
 }
 
 // NewRegistry создает новый реестр обработчиков
-func NewRegistry(bot *tgbotapi.BotAPI) *Registry {
+func NewRegistry(bot *tgbotapi.BotAPI, stores *repository.Stores) *Registry {
 	// Создаем обработчики команд
-	startHandler := NewStartHandler(bot)
-	balanceHandler := NewBalanceHandler(bot)
-	rulesHandler := NewRulesHandler(bot)
-	feedbackHandler := NewFeedbackHandler(bot)
+	transferHandler := NewTransferHandler(bot, stores)
+	startHandler := NewStartHandler(bot, stores, transferHandler)
+	balanceHandler := NewBalanceHandler(bot, stores)
+	rulesHandler := NewRulesHandler(bot, stores)
+	feedbackHandler := NewFeedbackHandler(bot, stores)
+	suggestHandler := NewSuggestHandler(bot, stores)
+	moderationCallback := NewModerationCallback(bot, stores)
+	paymentHandler := NewPaymentHandler(bot, stores)
+	topicsHandler := NewTopicsHandler(bot, stores)
+	reviewHandler := NewReviewHandler(bot, stores)
+	dailyHandler := NewDailyHandler(bot, stores)
 
 	registry := &Registry{
 		commandHandlers:  make(map[string]CommandHandler),
 		CallbackHandlers: make(map[string]CallbackHandler),
-		textHandler:      NewTextResponseHandler(bot, feedbackHandler),
+		jobHandlers:      make(map[models.JobKind]JobHandler),
+		textHandler:      NewTextResponseHandler(bot, stores, feedbackHandler, suggestHandler, moderationCallback, reviewHandler, dailyHandler),
+		paymentHandler:   paymentHandler,
+		jobRepo:          stores.Jobs,
 	}
 
 	// Регистрируем обработчики команд
@@ -43,12 +78,44 @@ func NewRegistry(bot *tgbotapi.BotAPI) *Registry {
 	registry.RegisterCommand(balanceHandler)
 	registry.RegisterCommand(rulesHandler)
 	registry.RegisterCommand(feedbackHandler)
+	registry.RegisterCommand(suggestHandler)
+	registry.RegisterCommand(NewInboxHandler(bot, stores))
+	registry.RegisterCommand(NewHistoryHandler(bot, stores))
+	registry.RegisterCommand(NewStatsHandler(bot, stores))
+	registry.RegisterCommand(NewTopHandler(bot, stores))
+	registry.RegisterCommand(NewGroupStatsHandler(bot, stores))
+	registry.RegisterCommand(NewBlitzHandler(startHandler, bot, stores))
+	registry.RegisterCommand(topicsHandler)
+	registry.RegisterCommand(NewDifficultyHandler(bot, stores))
+	registry.RegisterCommand(reviewHandler)
+	registry.RegisterCommand(dailyHandler)
+	registry.RegisterCommand(NewTagHandler(bot, stores))
+	registry.RegisterCommand(NewUntagHandler(bot, stores))
+	registry.RegisterCommand(transferHandler)
+	registry.RegisterCommand(paymentHandler)
 
 	// Регистрируем обработчики callback'ов
-	registry.RegisterCallback(NewSkipCallback(bot))
-	registry.RegisterCallback(NewFailCallback(bot))
-	registry.RegisterCallback(NewContinueCallback(startHandler, bot))
-	registry.RegisterCallback(NewFinishCallback(bot))
+	registry.RegisterCallback(NewSkipCallback(bot, stores))
+	registry.RegisterCallback(NewHintCallback(bot, stores))
+	registry.RegisterCallback(NewFailCallback(bot, stores))
+	registry.RegisterCallback(NewContinueCallback(startHandler, bot, stores))
+	registry.RegisterCallback(NewFinishCallback(bot, stores))
+	registry.RegisterCallback(NewSuggestSkipCallback(suggestHandler, bot, stores))
+	registry.RegisterCallback(moderationCallback)
+	registry.RegisterCallback(NewFeedbackReplyCallback(bot, stores))
+	registry.RegisterCallback(NewBuyCallback(paymentHandler, bot, stores))
+	registry.RegisterCallback(NewTopicCallback(topicsHandler, bot, stores))
+	registry.RegisterCallback(NewReviewCallback(reviewRevealAction, reviewHandler, bot, stores))
+	registry.RegisterCallback(NewReviewCallback(reviewNextAction, reviewHandler, bot, stores))
+	registry.RegisterCallback(NewReviewCallback(reviewFinishAction, reviewHandler, bot, stores))
+
+	// Регистрируем обработчики отложенных задач
+	registry.RegisterJob(NewBlitzTickJob(bot, stores))
+	registry.RegisterJob(NewBlitzExpireJob(bot, stores))
+	registry.RegisterJob(NewDailyStartJob(bot, stores))
+	registry.RegisterJob(NewDailySendJob(bot, stores))
+	registry.RegisterJob(NewDailySummaryJob(bot, stores))
+	registry.RegisterJob(NewTransferExpireJob(bot, stores))
 
 	return registry
 }
@@ -63,6 +130,144 @@ func (r *Registry) RegisterCallback(handler CallbackHandler) {
 	r.CallbackHandlers[handler.GetCallbackData()] = handler
 }
 
+// RegisterJob регистрирует обработчик отложенной задачи
+func (r *Registry) RegisterJob(handler JobHandler) {
+	r.jobHandlers[handler.GetJobKind()] = handler
+}
+
+// RunDueJobs выполняет наступившие отложенные задачи, пока очередь не опустеет или не завершится ctx.
+// Ошибки задач только логируются; возвращает число выполненных задач
+func (r *Registry) RunDueJobs(ctx context.Context) int {
+	return r.runJobs(ctx, func(now time.Time) ([]models.ScheduledJob, error) {
+		return r.jobRepo.ClaimDue(now, jobBatchSize, jobLease)
+	})
+}
+
+// RunDueChatJobs выполняет наступившие отложенные задачи одного чата.
+// Вызывается после обновления, чтобы чат не ждал таймера из-за чужих задач
+func (r *Registry) RunDueChatJobs(ctx context.Context, telegramID int64) int {
+	return r.runJobs(ctx, func(now time.Time) ([]models.ScheduledJob, error) {
+		return r.jobRepo.ClaimDueForChat(telegramID, now, jobBatchSize, jobLease)
+	})
+}
+
+// runJobs забирает задачи через claim и выполняет их пачками
+func (r *Registry) runJobs(ctx context.Context, claim func(now time.Time) ([]models.ScheduledJob, error)) int {
+	done := 0
+	for ctx.Err() == nil {
+		jobs, err := claim(time.Now().UTC())
+		if err != nil {
+			fmt.Printf("Failed to claim scheduled jobs: %v\n", err)
+			return done
+		}
+
+		for i := range jobs {
+			if ctx.Err() != nil {
+				// Оставшиеся задачи вернутся в очередь по истечении аренды
+				return done
+			}
+			if r.runJob(&jobs[i]) {
+				done++
+			}
+		}
+
+		if len(jobs) < jobBatchSize {
+			break
+		}
+	}
+	return done
+}
+
+// runJob выполняет задачу и удаляет ее из очереди только после успеха.
+// При ошибке задача возвращается в очередь с задержкой, после jobMaxAttempts попыток удаляется
+func (r *Registry) runJob(job *models.ScheduledJob) bool {
+	chatID := uint(0)
+	if job.ChatID != nil {
+		chatID = *job.ChatID
+	}
+
+	handler, exists := r.jobHandlers[job.Kind]
+	if !exists {
+		fmt.Printf("Unknown scheduled job kind: %s (job_id: %d)\n", job.Kind, job.ID)
+		if err := r.jobRepo.Complete(job); err != nil {
+			fmt.Printf("Failed to drop scheduled job: %v (job_id: %d)\n", err, job.ID)
+		}
+		return false
+	}
+
+	err := safeHandle(handler, job)
+	if err == nil {
+		if err := r.jobRepo.Complete(job); err != nil {
+			fmt.Printf("Failed to complete scheduled job %s: %v (job_id: %d, chat_id: %d)\n", job.Kind, err, job.ID, chatID)
+		}
+		return true
+	}
+
+	if job.Attempts >= jobMaxAttempts {
+		fmt.Printf("Giving up scheduled job %s after %d attempts: %v (job_id: %d, chat_id: %d)\n", job.Kind, job.Attempts, err, job.ID, chatID)
+		if err := r.jobRepo.Complete(job); err != nil {
+			fmt.Printf("Failed to drop scheduled job: %v (job_id: %d)\n", err, job.ID)
+		}
+		return false
+	}
+
+	fmt.Printf("Failed to run scheduled job %s: %v (job_id: %d, chat_id: %d, attempt: %d)\n", job.Kind, err, job.ID, chatID, job.Attempts)
+	backoff := time.Duration(job.Attempts*job.Attempts) * jobRetryDelay
+	if err := r.jobRepo.Retry(job, time.Now().UTC().Add(backoff)); err != nil {
+		fmt.Printf("Failed to retry scheduled job: %v (job_id: %d)\n", err, job.ID)
+	}
+	return false
+}
+
+// safeHandle выполняет задачу, превращая панику обработчика в ошибку
+func safeHandle(handler JobHandler, job *models.ScheduledJob) (err error) {
+	defer func() {
+		if recovered := recover(); recovered != nil {
+			err = fmt.Errorf("panic: %v", recovered)
+		}
+	}()
+	return handler.Handle(job)
+}
+
+// HandleUpdate направляет обновление подходящему обработчику.
+// Общая точка входа для вебхука и режима long polling; ошибки обработчиков только логируются
+func (r *Registry) HandleUpdate(update *tgbotapi.Update) {
+	switch {
+	case update.Message != nil:
+		r.handleMessage(update.Message)
+	case update.CallbackQuery != nil:
+		// Обрабатываем callback
+		if err := r.HandleCallback(update.CallbackQuery.Data, update.CallbackQuery); err != nil {
+			fmt.Printf("Failed to handle callback %s: %v\n", update.CallbackQuery.Data, err)
+		}
+	case update.PreCheckoutQuery != nil:
+		// Подтверждаем оплату
+		if err := r.HandlePreCheckoutQuery(update.PreCheckoutQuery); err != nil {
+			fmt.Printf("Failed to handle pre-checkout query %s: %v\n", update.PreCheckoutQuery.ID, err)
+		}
+	}
+}
+
+// handleMessage направляет входящее сообщение: оплата, команда или текст
+func (r *Registry) handleMessage(message *tgbotapi.Message) {
+	if message.SuccessfulPayment != nil {
+		// Обрабатываем успешную оплату
+		if err := r.HandleSuccessfulPayment(message); err != nil {
+			fmt.Printf("Failed to handle successful payment %s: %v\n", message.SuccessfulPayment.TelegramPaymentChargeID, err)
+		}
+	} else if message.IsCommand() {
+		// Обрабатываем команду
+		if err := r.HandleCommand(message.Command(), message); err != nil {
+			fmt.Printf("Failed to handle command %s: %v\n", message.Command(), err)
+		}
+	} else {
+		// Обрабатываем текстовое сообщение
+		if err := r.HandleTextMessage(message); err != nil {
+			fmt.Printf("Failed to handle text message: %v\n", err)
+		}
+	}
+}
+
 // HandleCommand обрабатывает команду
 func (r *Registry) HandleCommand(command string, message *tgbotapi.Message) error {
 	if handler, exists := r.commandHandlers[command]; exists {
@@ -71,11 +276,14 @@ func (r *Registry) HandleCommand(command string, message *tgbotapi.Message) erro
 	return fmt.Errorf("команда не найдена: %s", command)
 }
 
-// HandleCallback обрабатывает callback
+// HandleCallback обрабатывает callback.
+// Обработчик выбирается по действию — части данных до первого ":", например "skip" для "skip:1:42:..."
 func (r *Registry) HandleCallback(callbackData string, callback *tgbotapi.CallbackQuery) error {
-	if handler, exists := r.CallbackHandlers[callbackData]; exists {
+	action, _, _ := strings.Cut(callbackData, ":")
+	if handler, exists := r.CallbackHandlers[action]; exists {
 		return handler.Handle(callback)
 	}
+
 	return fmt.Errorf("callback не найден: %s", callbackData)
 }
 
@@ -84,6 +292,16 @@ func (r *Registry) HandleTextMessage(message *tgbotapi.Message) error {
 	return r.textHandler.Handle(message)
 }
 
+// HandlePreCheckoutQuery обрабатывает запрос подтверждения оплаты
+func (r *Registry) HandlePreCheckoutQuery(query *tgbotapi.PreCheckoutQuery) error {
+	return r.paymentHandler.HandlePreCheckoutQuery(query)
+}
+
+// HandleSuccessfulPayment обрабатывает сообщение об успешной оплате
+func (r *Registry) HandleSuccessfulPayment(message *tgbotapi.Message) error {
+	return r.paymentHandler.HandleSuccessfulPayment(message)
+}
+
 // GetStartHandler возвращает обработчик команды start
 func (r *Registry) GetStartHandler() *StartHandler {
 	if handler, exists := r.commandHandlers["start"]; exists {
@@ -0,0 +1,705 @@
+package handlers
+
+import (
+	"context"
+	"fmt"
+	tgbotapi "github.com/go-telegram-bot-api/telegram-bot-api/v5"
+	"qweasley/internal/models"
+	"qweasley/internal/repository"
+	"qweasley/internal/repository/memory"
+	"qweasley/internal/telegramtest"
+	"strings"
+	"testing"
+	"time"
+)
+
+// Чаты, от имени которых идут обновления в тестах
+const (
+	testUserChat  = 1001
+	testAdminChat = 999
+)
+
+// testEnv реестр обработчиков поверх поддельного Bot API и хранилищ в памяти
+type testEnv struct {
+	t        *testing.T
+	server   *telegramtest.Server
+	stores   *repository.Stores
+	registry *Registry
+}
+
+// newTestEnv создает окружение с опубликованными вопросами Q1?..Qn? с ответами A1..An
+func newTestEnv(t *testing.T, questions int) *testEnv {
+	t.Helper()
+	t.Setenv("ADMIN_CHAT_ID", fmt.Sprint(testAdminChat))
+
+	server := telegramtest.NewServer()
+	t.Cleanup(server.Close)
+
+	bot, err := server.NewBot()
+	if err != nil {
+		t.Fatalf("failed to create bot: %v", err)
+	}
+
+	stores := memory.NewStores()
+	for i := 1; i <= questions; i++ {
+		question := &models.Question{Text: fmt.Sprintf("Q%d?", i), Answer: fmt.Sprintf("A%d", i), IsPublished: true}
+		if err := stores.Questions.Create(question); err != nil {
+			t.Fatalf("failed to create question: %v", err)
+		}
+	}
+
+	return &testEnv{t: t, server: server, stores: stores, registry: NewRegistry(bot, stores)}
+}
+
+// dispatch передает обновление реестру и возвращает вызовы Bot API, сделанные при его обработке
+func (e *testEnv) dispatch(update tgbotapi.Update) []telegramtest.Call {
+	e.t.Helper()
+
+	before := len(e.server.Calls())
+	e.registry.HandleUpdate(&update)
+	calls := e.server.Calls()[before:]
+
+	for _, call := range calls {
+		if call.Err != "" {
+			e.t.Fatalf("%s rejected: %s", call.Method, call.Err)
+		}
+	}
+	return calls
+}
+
+// command отправляет команду от имени чата
+func (e *testEnv) command(chatID int64, command string) []telegramtest.Call {
+	return e.dispatch(telegramtest.CommandUpdate(chatID, command))
+}
+
+// text отправляет текстовое сообщение от имени чата
+func (e *testEnv) text(chatID int64, text string) []telegramtest.Call {
+	return e.dispatch(telegramtest.TextUpdate(chatID, text))
+}
+
+// button возвращает данные кнопки с действием action из последнего сообщения чата, где она есть
+func (e *testEnv) button(chatID int64, action string) string {
+	e.t.Helper()
+
+	sent := e.server.Sent(chatID)
+	for i := len(sent) - 1; i >= 0; i-- {
+		if data, ok := sent[i].Button(action); ok {
+			return data
+		}
+	}
+	e.t.Fatalf("no %q button sent to chat %d", action, chatID)
+	return ""
+}
+
+// askedQuestion возвращает номер последнего заданного чату вопроса Q<n>?
+func (e *testEnv) askedQuestion(chatID int64) int {
+	e.t.Helper()
+
+	sent := e.server.Sent(chatID)
+	for i := len(sent) - 1; i >= 0; i-- {
+		if _, ok := sent[i].Button("skip"); !ok {
+			continue
+		}
+		var n int
+		if _, err := fmt.Sscanf(strings.Trim(sent[i].Text(), "*"), "Q%d", &n); err == nil {
+			return n
+		}
+	}
+	e.t.Fatalf("no question sent to chat %d", chatID)
+	return 0
+}
+
+// expectText проверяет, что среди вызовов есть сообщение или правка с подстрокой
+func expectText(t *testing.T, calls []telegramtest.Call, substring string) {
+	t.Helper()
+
+	for _, call := range calls {
+		if strings.Contains(call.Text(), substring) {
+			return
+		}
+	}
+
+	var texts []string
+	for _, call := range calls {
+		texts = append(texts, call.Method+": "+call.Text())
+	}
+	t.Fatalf("no call containing %q among:\n%s", substring, strings.Join(texts, "\n"))
+}
+
+// expectMethod проверяет, что среди вызовов есть вызов метода Bot API
+func expectMethod(t *testing.T, calls []telegramtest.Call, method string) {
+	t.Helper()
+
+	for _, call := range calls {
+		if call.Method == method {
+			return
+		}
+	}
+	t.Fatalf("no %s call among %d calls", method, len(calls))
+}
+
+// commandCase команда, ее отправитель, подготовка состояния, ожидаемый ответ и проверка хранилищ после команды.
+// setup может вернуть команду с аргументами, которые известны только после подготовки
+type commandCase struct {
+	name    string
+	chatID  int64
+	setup   func(e *testEnv) string
+	command string
+	want    string
+	check   func(e *testEnv, chat *models.Chat)
+}
+
+// expectLastTransaction проверяет баланс чата и последнюю запись журнала монет
+func (e *testEnv) expectLastTransaction(chat *models.Chat, balance int, reason models.TransactionReason, delta int) {
+	e.t.Helper()
+
+	if chat.Balance != balance {
+		e.t.Fatalf("balance = %d, want %d", chat.Balance, balance)
+	}
+	history, err := e.stores.Ledger.GetHistory(chat.ID, 1)
+	if err != nil {
+		e.t.Fatalf("failed to get history: %v", err)
+	}
+	if len(history) == 0 || history[0].Reason != reason || history[0].Delta != delta {
+		e.t.Fatalf("last ledger entry = %+v, want %s %+d", history, reason, delta)
+	}
+}
+
+// expectTagQuestions проверяет число опубликованных вопросов темы
+func (e *testEnv) expectTagQuestions(name string, want int) {
+	e.t.Helper()
+
+	tags, err := e.stores.Tags.GetAll()
+	if err != nil {
+		e.t.Fatalf("failed to get tags: %v", err)
+	}
+	got := 0
+	for _, tag := range tags {
+		if tag.Name == name {
+			got = tag.Questions
+		}
+	}
+	if got != want {
+		e.t.Fatalf("tag %s has %d questions, want %d", name, got, want)
+	}
+}
+
+var commandCases = []commandCase{
+	{name: "start asks a question", chatID: testUserChat, command: "/start", want: "*Q", check: func(e *testEnv, chat *models.Chat) {
+		if !chat.IsWaitingAnswer() {
+			e.t.Fatal("chat is not waiting for an answer")
+		}
+	}},
+	{name: "start redeems a transfer", chatID: testUserChat, setup: func(e *testEnv) string {
+		e.command(testAdminChat, "/transfer 5")
+		return "/start " + transferCode(e.t, e.server.Sent(testAdminChat))
+	}, command: "/start", want: "Вам переведено 5 монет", check: func(e *testEnv, chat *models.Chat) {
+		e.expectLastTransaction(chat, 35, models.ReasonTransferIn, 5)
+	}},
+	{name: "balance", chatID: testUserChat, command: "/balance", want: "Ваш баланс: 30 монет"},
+	{name: "rules", chatID: testUserChat, command: "/rules", want: "*Правила*"},
+	{name: "feedback", chatID: testUserChat, command: "/feedback", want: "напишите их следующим сообщением", check: func(e *testEnv, chat *models.Chat) {
+		if !chat.IsWaitingFeedback() {
+			e.t.Fatal("chat is not waiting for feedback")
+		}
+	}},
+	{name: "suggest", chatID: testUserChat, command: "/suggest", want: "Напишите текст вопроса", check: func(e *testEnv, chat *models.Chat) {
+		if !chat.IsSuggesting() {
+			e.t.Fatal("chat is not suggesting a question")
+		}
+	}},
+	{name: "inbox for admin", chatID: testAdminChat, command: "/inbox", want: "Все сообщения обратной связи отвечены"},
+	{name: "history", chatID: testUserChat, command: "/history", want: "Бонус за знакомство"},
+	{name: "stats without answers", chatID: testUserChat, command: "/stats", want: "Статистики пока нет"},
+	{name: "top", chatID: testUserChat, command: "/top", want: "Пока никого нет"},
+	{name: "groupstats in private chat", chatID: testUserChat, command: "/groupstats", want: "работает в групповых чатах"},
+	{name: "blitz on", chatID: testUserChat, command: "/blitz", want: "*Блиц\\!*", check: func(e *testEnv, chat *models.Chat) {
+		if !chat.BlitzMode {
+			e.t.Fatal("blitz mode is not enabled")
+		}
+	}},
+	{name: "blitz off", chatID: testUserChat, command: "/blitz off", want: "Блиц выключен", check: func(e *testEnv, chat *models.Chat) {
+		if chat.BlitzMode {
+			e.t.Fatal("blitz mode is still enabled")
+		}
+	}},
+	{name: "topics without tags", chatID: testUserChat, command: "/topics", want: "Темы вопросов пока не заданы"},
+	{name: "difficulty", chatID: testUserChat, command: "/difficulty hard", want: "сложные", check: func(e *testEnv, chat *models.Chat) {
+		if chat.DifficultyMode != models.DifficultyHard {
+			e.t.Fatalf("difficulty mode = %s, want %s", chat.DifficultyMode, models.DifficultyHard)
+		}
+	}},
+	{name: "review with nothing due", chatID: testUserChat, command: "/review", want: "Повторять пока нечего"},
+	{name: "daily on", chatID: testUserChat, command: "/daily on", want: "Вопрос дня включен", check: func(e *testEnv, chat *models.Chat) {
+		if !chat.DailyEnabled {
+			e.t.Fatal("daily question is not enabled")
+		}
+	}},
+	{name: "tag by admin", chatID: testAdminChat, command: "/tag 1 history", want: "добавлено вопросов — 1 из 1", check: func(e *testEnv, chat *models.Chat) {
+		e.expectTagQuestions("history", 1)
+	}},
+	{name: "untag by admin", chatID: testAdminChat, setup: func(e *testEnv) string {
+		e.command(testAdminChat, "/tag 1 history")
+		return ""
+	}, command: "/untag 1 history", want: "убрано вопросов — 1 из 1", check: func(e *testEnv, chat *models.Chat) {
+		e.expectTagQuestions("history", 0)
+	}},
+	{name: "transfer", chatID: testUserChat, command: "/transfer 5", want: "Перевод на 5 монет создан", check: func(e *testEnv, chat *models.Chat) {
+		e.expectLastTransaction(chat, 25, models.ReasonTransferOut, -5)
+	}},
+	{name: "buy", chatID: testUserChat, command: "/buy", want: "*Покупка монет*"},
+}
+
+func TestCommands(t *testing.T) {
+	for _, tc := range commandCases {
+		t.Run(tc.name, func(t *testing.T) {
+			e := newTestEnv(t, 3)
+			command := tc.command
+			if tc.setup != nil {
+				if prepared := tc.setup(e); prepared != "" {
+					command = prepared
+				}
+			}
+
+			calls := e.command(tc.chatID, command)
+			expectText(t, calls, tc.want)
+
+			if tc.check != nil {
+				chat, err := e.stores.Chats.GetOrCreate(tc.chatID, nil)
+				if err != nil {
+					t.Fatalf("failed to get chat: %v", err)
+				}
+				tc.check(e, chat)
+			}
+			if failed := e.server.Errors(); len(failed) != 0 {
+				t.Fatalf("Bot API errors: %+v", failed)
+			}
+		})
+	}
+}
+
+// transferCode достает код перевода из сообщения о созданном переводе
+func transferCode(t *testing.T, calls []telegramtest.Call) string {
+	t.Helper()
+
+	for _, call := range calls {
+		if i := strings.Index(call.Text(), "/start tr_"); i >= 0 {
+			code := call.Text()[i+len("/start "):]
+			return code[:strings.IndexByte(code, '`')]
+		}
+	}
+	t.Fatal("no transfer code sent")
+	return ""
+}
+
+// callbackCase callback, подготовка, которая возвращает данные кнопки, и ожидаемый ответ
+type callbackCase struct {
+	name   string
+	action string
+	setup  func(e *testEnv) (chatID int64, data string)
+	want   string
+	method string
+}
+
+// askQuestion задает вопрос чату пользователя и возвращает данные кнопки action под ним
+func askQuestion(action string) func(e *testEnv) (int64, string) {
+	return func(e *testEnv) (int64, string) {
+		e.command(testUserChat, "/start")
+		return testUserChat, e.button(testUserChat, action)
+	}
+}
+
+// startReview пропускает два вопроса, заканчивает игру, открывает повторение пропущенных вопросов
+// и возвращает данные кнопки action
+func startReview(action string) func(e *testEnv) (int64, string) {
+	return func(e *testEnv) (int64, string) {
+		e.command(testUserChat, "/start")
+		e.dispatch(telegramtest.CallbackUpdate(testUserChat, 0, e.button(testUserChat, "skip")))
+		e.dispatch(telegramtest.CallbackUpdate(testUserChat, 0, e.button(testUserChat, "skip")))
+		e.dispatch(telegramtest.CallbackUpdate(testUserChat, 0, e.button(testUserChat, "finish")))
+		e.command(testUserChat, "/review")
+		return testUserChat, e.button(testUserChat, action)
+	}
+}
+
+var callbackCases = []callbackCase{
+	{name: "skip asks the next question", action: "skip", setup: askQuestion("skip"), want: "*Q"},
+	{name: "hint", action: "hint", setup: askQuestion("hint"), method: "editMessageText"},
+	{name: "fail reveals the answer", action: "fail", setup: askQuestion("fail"), want: "*Правильный ответ:*"},
+	{name: "finish", action: "finish", setup: askQuestion("finish"), want: "Приходите завтра"},
+	{name: "continue asks the next question", action: "continue", setup: func(e *testEnv) (int64, string) {
+		e.command(testUserChat, "/start")
+		e.dispatch(telegramtest.CallbackUpdate(testUserChat, 0, e.button(testUserChat, "fail")))
+		return testUserChat, e.button(testUserChat, "continue")
+	}, want: "*Q"},
+	{name: "suggest skip", action: "suggest_skip", setup: func(e *testEnv) (int64, string) {
+		e.command(testUserChat, "/suggest")
+		e.text(testUserChat, "Новый вопрос?")
+		e.text(testUserChat, "Ответ")
+		return testUserChat, e.button(testUserChat, "suggest_skip")
+	}, want: "подсказ"},
+	{name: "moderate approve", action: "moderate", setup: func(e *testEnv) (int64, string) {
+		question := &models.Question{Text: "На модерации?", Answer: "Да"}
+		if err := e.stores.Questions.Create(question); err != nil {
+			e.t.Fatalf("failed to create question: %v", err)
+		}
+		return testAdminChat, fmt.Sprintf("moderate:approve:%d", question.ID)
+	}, want: "опубликован"},
+	{name: "feedback reply", action: "feedback_reply", setup: func(e *testEnv) (int64, string) {
+		e.command(testUserChat, "/feedback")
+		e.text(testUserChat, "Спасибо за бота")
+		return testAdminChat, e.button(testAdminChat, "feedback_reply")
+	}, want: "Напишите ответ на"},
+	{name: "buy sends an invoice", action: "buy", setup: func(e *testEnv) (int64, string) {
+		return testUserChat, "buy:10"
+	}, method: "sendInvoice"},
+	{name: "topic toggles a tag", action: "topic", setup: func(e *testEnv) (int64, string) {
+		e.command(testAdminChat, "/tag 1 history")
+		e.command(testUserChat, "/topics")
+		return testUserChat, e.button(testUserChat, "topic")
+	}, want: "history"},
+	{name: "review reveal", action: reviewRevealAction, setup: startReview(reviewRevealAction), want: "Следующее повторение"},
+	{name: "review next", action: reviewNextAction, setup: func(e *testEnv) (int64, string) {
+		chatID, data := startReview(reviewRevealAction)(e)
+		e.dispatch(telegramtest.CallbackUpdate(chatID, 0, data))
+		return chatID, e.button(chatID, reviewNextAction)
+	}, want: "_Повторение_"},
+	{name: "review finish", action: reviewFinishAction, setup: startReview(reviewFinishAction), want: "Повторение закончено"},
+}
+
+func TestCallbacks(t *testing.T) {
+	for _, tc := range callbackCases {
+		t.Run(tc.name, func(t *testing.T) {
+			e := newTestEnv(t, 3)
+			chatID, data := tc.setup(e)
+
+			calls := e.dispatch(telegramtest.CallbackUpdate(chatID, 0, data))
+			if tc.method != "" {
+				expectMethod(t, calls, tc.method)
+			}
+			if tc.want != "" {
+				expectText(t, calls, tc.want)
+			}
+			if failed := e.server.Errors(); len(failed) != 0 {
+				t.Fatalf("Bot API errors: %+v", failed)
+			}
+		})
+	}
+}
+
+// TestRegistryCoverage следит, чтобы у каждой команды и каждого callback'а из NewRegistry был тест
+func TestRegistryCoverage(t *testing.T) {
+	e := newTestEnv(t, 0)
+
+	commands := make(map[string]bool)
+	for _, tc := range commandCases {
+		command := strings.TrimPrefix(strings.Fields(tc.command + " /start")[0], "/")
+		commands[command] = true
+	}
+	for command := range e.registry.commandHandlers {
+		if !commands[command] {
+			t.Errorf("command /%s has no test case", command)
+		}
+	}
+
+	callbacks := make(map[string]bool)
+	for _, tc := range callbackCases {
+		callbacks[tc.action] = true
+	}
+	for action := range e.registry.CallbackHandlers {
+		if !callbacks[action] {
+			t.Errorf("callback %s has no test case", action)
+		}
+	}
+}
+
+func TestSkippedQuestionsComeBackLast(t *testing.T) {
+	e := newTestEnv(t, 3)
+
+	e.command(testUserChat, "/start")
+	skipped := e.askedQuestion(testUserChat)
+	e.dispatch(telegramtest.CallbackUpdate(testUserChat, 0, e.button(testUserChat, "skip")))
+
+	// Пока есть новые вопросы, пропущенный не задается
+	for i := 0; i < 2; i++ {
+		asked := e.askedQuestion(testUserChat)
+		if asked == skipped {
+			t.Fatalf("skipped question Q%d asked again while new questions remain", skipped)
+		}
+		e.text(testUserChat, fmt.Sprintf("A%d", asked))
+		e.dispatch(telegramtest.CallbackUpdate(testUserChat, 0, e.button(testUserChat, "continue")))
+	}
+
+	// Новые вопросы кончились: возвращается пропущенный
+	if asked := e.askedQuestion(testUserChat); asked != skipped {
+		t.Fatalf("asked Q%d, want skipped Q%d", asked, skipped)
+	}
+	e.text(testUserChat, fmt.Sprintf("A%d", skipped))
+
+	// Отвеченные вопросы больше не задаются
+	calls := e.dispatch(telegramtest.CallbackUpdate(testUserChat, 0, e.button(testUserChat, "continue")))
+	expectText(t, calls, "вы ответили на все вопросы")
+}
+
+func TestReactionIsUniquePerQuestion(t *testing.T) {
+	tests := []struct {
+		name    string
+		actions []string
+		want    func(reaction *models.Reaction) bool
+	}{
+		{name: "hints then answer", actions: []string{"hint", "hint", "answer"}, want: func(r *models.Reaction) bool {
+			return r.HintsUsed == 2 && r.ResponsedAt != nil
+		}},
+		{name: "wrong answers then fail", actions: []string{"wrong", "wrong", "fail"}, want: func(r *models.Reaction) bool {
+			return r.Attempts == 2 && r.FailedAt != nil
+		}},
+		{name: "repeated fail press", actions: []string{"fail", "fail"}, want: func(r *models.Reaction) bool {
+			return r.FailedAt != nil
+		}},
+	}
+
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			e := newTestEnv(t, 1)
+			e.command(testUserChat, "/start")
+			fail := e.button(testUserChat, "fail")
+			hint := e.button(testUserChat, "hint")
+
+			for _, action := range tc.actions {
+				switch action {
+				case "hint":
+					e.dispatch(telegramtest.CallbackUpdate(testUserChat, 0, hint))
+				case "fail":
+					e.dispatch(telegramtest.CallbackUpdate(testUserChat, 0, fail))
+				case "wrong":
+					e.text(testUserChat, "неверно")
+				case "answer":
+					e.text(testUserChat, "A1")
+				}
+			}
+
+			chat, err := e.stores.Chats.GetOrCreate(testUserChat, nil)
+			if err != nil {
+				t.Fatalf("failed to get chat: %v", err)
+			}
+			stats, err := e.stores.Reactions.GetStats(chat.ID)
+			if err != nil {
+				t.Fatalf("failed to get stats: %v", err)
+			}
+			if resolved := stats.Answered + stats.Revealed + stats.Skipped; resolved != 1 {
+				t.Fatalf("question resolved %d times, want once", resolved)
+			}
+
+			reaction, err := e.stores.Reactions.GetReaction(chat.ID, 1)
+			if err != nil {
+				t.Fatalf("failed to get reaction: %v", err)
+			}
+			if !tc.want(reaction) {
+				t.Fatalf("unexpected reaction: %+v", reaction)
+			}
+		})
+	}
+}
+
+func TestWrongAnswersKeepBalanceNonNegative(t *testing.T) {
+	t.Setenv("ANSWER_MAX_ATTEMPTS", "3")
+	t.Setenv("ANSWER_WRONG_PENALTY", "2")
+	e := newTestEnv(t, 1)
+
+	chat, err := e.stores.Chats.GetOrCreate(testUserChat, nil)
+	if err != nil {
+		t.Fatalf("failed to create chat: %v", err)
+	}
+	err = e.stores.Ledger.Apply(&models.CoinTransaction{ChatID: chat.ID, Delta: 3 - chat.Balance, Reason: models.ReasonAdminGrant})
+	if err != nil {
+		t.Fatalf("failed to set balance: %v", err)
+	}
+
+	// Штрафы не трогают монету за закрытие вопроса: 3 → 1 → 1, а третья попытка показывает ответ за нее
+	e.command(testUserChat, "/start")
+	for i := 0; i < 3; i++ {
+		e.text(testUserChat, "неверно")
+	}
+
+	chat, err = e.stores.Chats.GetOrCreate(testUserChat, nil)
+	if err != nil {
+		t.Fatalf("failed to get chat: %v", err)
+	}
+	if chat.Balance != 0 {
+		t.Fatalf("balance = %d, want 0", chat.Balance)
+	}
+
+	reaction, err := e.stores.Reactions.GetReaction(chat.ID, 1)
+	if err != nil {
+		t.Fatalf("failed to get reaction: %v", err)
+	}
+	if reaction.FailedAt == nil || reaction.Attempts != 3 {
+		t.Fatalf("unexpected reaction: %+v", reaction)
+	}
+}
+
+func TestBlitzTickIgnoresUnmodifiedMessage(t *testing.T) {
+	e := newTestEnv(t, 1)
+	e.command(testUserChat, "/blitz")
+
+	chat, err := e.stores.Chats.GetOrCreate(testUserChat, nil)
+	if err != nil {
+		t.Fatalf("failed to get chat: %v", err)
+	}
+	if chat.LastQuestionID == nil {
+		t.Fatal("no blitz question asked")
+	}
+
+	// Опоздавший отсчет сразу после вопроса показывает то же время: Telegram отвечает "message is not modified"
+	job := &models.ScheduledJob{Kind: models.JobBlitzTick, ChatID: &chat.ID, QuestionID: chat.LastQuestionID}
+	before := len(e.server.Calls())
+	if err := e.registry.jobHandlers[models.JobBlitzTick].Handle(job); err != nil {
+		t.Fatalf("tick failed: %v", err)
+	}
+
+	calls := e.server.Calls()[before:]
+	if len(calls) != 1 || !strings.Contains(calls[0].Err, "message is not modified") {
+		t.Fatalf("calls = %+v, want one unmodified edit", calls)
+	}
+}
+
+// runJob выполняет задачу kind, не дожидаясь ее времени: задачи в очереди забираются на двое суток вперед
+func (e *testEnv) runJob(kind models.JobKind) {
+	e.t.Helper()
+
+	jobs, err := e.stores.Jobs.ClaimDue(time.Now().Add(48*time.Hour), 10, time.Minute)
+	if err != nil {
+		e.t.Fatalf("failed to claim jobs: %v", err)
+	}
+	for i := range jobs {
+		if jobs[i].Kind != kind {
+			continue
+		}
+		if err := e.registry.jobHandlers[kind].Handle(&jobs[i]); err != nil {
+			e.t.Fatalf("%s failed: %v", kind, err)
+		}
+		return
+	}
+	e.t.Fatalf("no %s job scheduled", kind)
+}
+
+// startDaily выбирает вопрос дня, не дожидаясь DAILY_HOUR, и возвращает его
+func (e *testEnv) startDaily() *models.DailyQuestion {
+	e.t.Helper()
+
+	e.runJob(models.JobDailyStart)
+	daily, err := e.stores.Daily.GetByQuestionID(1)
+	if err != nil {
+		e.t.Fatalf("daily question is not started: %v", err)
+	}
+	return daily
+}
+
+func TestDailySendSkipsBlockedChat(t *testing.T) {
+	const blockedChat = 1002
+
+	e := newTestEnv(t, 1)
+	e.command(testUserChat, "/daily on")
+	e.command(blockedChat, "/daily on")
+	e.command(testAdminChat, "/daily queue 1")
+	daily := e.startDaily()
+
+	e.server.Block(blockedChat)
+	e.registry.RunDueJobs(context.Background())
+
+	sent, ok := e.server.LastSent(testUserChat)
+	if !ok || !strings.Contains(sent.Text(), "*Вопрос дня*") {
+		t.Fatalf("daily question is not sent to chat %d: %+v", testUserChat, sent)
+	}
+
+	blocked, err := e.stores.Chats.GetOrCreate(blockedChat, nil)
+	if err != nil {
+		t.Fatalf("failed to get chat: %v", err)
+	}
+	if blocked.DailyEnabled {
+		t.Fatal("chat that blocked the bot is still subscribed")
+	}
+	if failed := e.server.Errors(); len(failed) != 1 || failed[0].ChatID() != blockedChat {
+		t.Fatalf("Bot API errors: %+v, want one for the blocked chat", failed)
+	}
+
+	delivered, _, err := e.stores.Daily.Summary(daily.ID)
+	if err != nil {
+		t.Fatalf("failed to get summary: %v", err)
+	}
+	if delivered != 1 {
+		t.Fatalf("delivered to %d chats, want 1", delivered)
+	}
+
+	// Недоставленная отправка закрыта: даже после повторной подписки вопрос не отправляется снова
+	if err := e.stores.Chats.SetDailyEnabled(blocked.ID, true); err != nil {
+		t.Fatalf("failed to subscribe chat: %v", err)
+	}
+	pending, err := e.stores.Daily.GetPending(daily.ID, 10)
+	if err != nil {
+		t.Fatalf("failed to get pending deliveries: %v", err)
+	}
+	if len(pending) != 0 {
+		t.Fatalf("pending deliveries: %+v", pending)
+	}
+}
+
+func TestDailySummaryAfterAnswersClose(t *testing.T) {
+	const otherChat = 1002
+
+	e := newTestEnv(t, 1)
+	e.command(testUserChat, "/daily on")
+	e.command(otherChat, "/daily on")
+	e.command(testAdminChat, "/daily queue 1")
+	e.startDaily()
+	e.registry.RunDueJobs(context.Background())
+
+	expectText(t, e.text(testUserChat, "A1"), "когда прием ответов закончится")
+
+	e.runJob(models.JobDailySummary)
+	for _, chatID := range []int64{testUserChat, otherChat} {
+		sent := e.server.Sent(chatID)
+		expectText(t, sent, "Итоги вопроса дня")
+		expectText(t, sent, "Правильно ответили 50% чатов")
+	}
+
+	// Итоги отправляются каждому чату один раз
+	calls := len(e.server.Calls())
+	e.runJob(models.JobDailySummary)
+	if got := len(e.server.Calls()); got != calls {
+		t.Fatalf("summary is sent again: %v", e.server.Calls()[calls:])
+	}
+}
+
+func TestModerationEditOnlyPendingQuestions(t *testing.T) {
+	e := newTestEnv(t, 0)
+
+	pending := &models.Question{Text: "На модерации?", Answer: "Да"}
+	decided := &models.Question{Text: "Уже одобрен?", Answer: "Да"}
+	for _, question := range []*models.Question{pending, decided} {
+		if err := e.stores.Questions.Create(question); err != nil {
+			t.Fatalf("failed to create question: %v", err)
+		}
+	}
+
+	e.dispatch(telegramtest.CallbackUpdate(testAdminChat, 0, fmt.Sprintf("moderate:edit:%d", pending.ID)))
+	calls := e.text(testAdminChat, "Исправленный вопрос?\nОтвет: Нет")
+	expectText(t, calls, "Предложен вопрос")
+	if question, _ := e.stores.Questions.GetByID(pending.ID); question.Answer != "Нет" {
+		t.Fatalf("pending question answer = %q, want edited", question.Answer)
+	}
+
+	// Вопрос одобрили, пока администратор писал правки
+	e.dispatch(telegramtest.CallbackUpdate(testAdminChat, 0, fmt.Sprintf("moderate:edit:%d", decided.ID)))
+	e.dispatch(telegramtest.CallbackUpdate(testAdminChat, 0, fmt.Sprintf("moderate:approve:%d", decided.ID)))
+	calls = e.text(testAdminChat, "Исправленный вопрос?\nОтвет: Нет")
+	expectText(t, calls, "уже прошел модерацию")
+	for _, call := range calls {
+		if strings.Contains(call.Params["reply_markup"], "moderate:") {
+			t.Fatalf("moderation buttons are sent for a decided question: %+v", call)
+		}
+	}
+	if question, _ := e.stores.Questions.GetByID(decided.ID); question.Text != "Уже одобрен?" {
+		t.Fatalf("decided question text = %q, want unchanged", question.Text)
+	}
+}
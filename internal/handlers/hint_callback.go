@@ -0,0 +1,167 @@
+package handlers
+
+import (
+	"errors"
+	"fmt"
+	tgbotapi "github.com/go-telegram-bot-api/telegram-bot-api/v5"
+	"qweasley/internal/models"
+	"qweasley/internal/repository"
+	"strings"
+)
+
+// Ошибки при взятии подсказки
+var (
+	errNoMoreHints       = errors.New("no more hints")
+	errHintNotAffordable = errors.New("insufficient balance for hint")
+)
+
+// codeEscaper экранирует текст внутри `code` в MarkdownV2
+var codeEscaper = strings.NewReplacer("\\", "\\\\", "`", "\\`")
+
+// HintCallback обработчик callback'а "hint"
+type HintCallback struct {
+	*BaseHandler
+}
+
+// NewHintCallback создает новый обработчик callback'а hint
+func NewHintCallback(bot *tgbotapi.BotAPI, stores *repository.Stores) *HintCallback {
+	return &HintCallback{
+		BaseHandler: NewBaseHandler(bot, stores),
+	}
+}
+
+// GetCallbackData возвращает данные callback'а
+func (h *HintCallback) GetCallbackData() string {
+	return "hint"
+}
+
+// Handle обрабатывает callback "hint": открывает следующую подсказку и дописывает ее к сообщению с вопросом
+func (h *HintCallback) Handle(callback *tgbotapi.CallbackQuery) error {
+	// Получаем вопрос, к которому привязана кнопка
+	questionID, ok := h.QuestionFromCallback(callback)
+	if !ok {
+		return nil
+	}
+
+	// Получаем чат пользователя
+	chat, err := h.GetOrCreateChat(callback.Message.Chat.ID, &callback.Message.Chat.Title)
+	if err != nil {
+		h.AnswerCallbackQuery(callback.ID)
+		fmt.Printf("Failed to get or create chat in hint callback: %v (chat_id: %d)\n", err, callback.Message.Chat.ID)
+		return h.SendMessage(callback.Message.Chat.ID, "Произошла ошибка при обработке команды", nil)
+	}
+
+	// Подсказка к закрытому вопросу не нужна
+	if chat.LastQuestionID == nil || *chat.LastQuestionID != questionID {
+		return h.AnswerCallbackAlert(callback.ID, closedQuestionAlert)
+	}
+
+	// Получаем вопрос
+	question, err := h.questionRepo.GetByID(questionID)
+	if err != nil {
+		h.AnswerCallbackQuery(callback.ID)
+		fmt.Printf("Failed to get question in hint callback: %v (chat_id: %d, question_id: %d)\n", err, chat.ID, questionID)
+		return h.SendMessage(callback.Message.Chat.ID, "Произошла ошибка при обработке команды", nil)
+	}
+
+	hints, masked := h.QuestionHints(question)
+	reaction, err := h.ProcessHint(chat.ID, questionID, len(hints))
+	switch {
+	case errors.Is(err, repository.ErrQuestionChanged):
+		return h.AnswerCallbackAlert(callback.ID, closedQuestionAlert)
+	case errors.Is(err, errNoMoreHints):
+		return h.AnswerCallbackAlert(callback.ID, "Подсказок больше нет")
+	case errors.Is(err, errHintNotAffordable):
+		return h.AnswerCallbackAlert(callback.ID, "Не хватает монет на подсказку. Пополните баланс командой /balance")
+	case err != nil:
+		h.AnswerCallbackQuery(callback.ID)
+		fmt.Printf("Failed to process hint: %v (chat_id: %d, question_id: %d)\n", err, chat.ID, questionID)
+		return h.SendMessage(callback.Message.Chat.ID, "Произошла ошибка при обработке команды", nil)
+	}
+
+	// Отвечаем на callback query
+	if err := h.AnswerCallbackQuery(callback.ID); err != nil {
+		fmt.Printf("Failed to answer callback query: %v\n", err)
+	}
+
+	// Дописываем подсказки к вопросу, оставляя клавиатуру
+	hintsText := h.formatHints(hints[:reaction.HintsUsed], masked)
+	text := h.QuestionMessageText(chat, question, reaction)
+
+	keyboard := h.CreateQuestionKeyboard(callback.Message.Chat.ID, questionID)
+	if err := h.EditQuestionMessage(callback.Message.Chat.ID, callback.Message.MessageID, question, text, keyboard); err != nil {
+		// Не удалось изменить сообщение с вопросом: присылаем подсказку отдельно
+		fmt.Printf("Failed to edit question message with hint: %v (chat_id: %d, message_id: %d)\n", err, chat.ID, callback.Message.MessageID)
+		return h.SendMessage(callback.Message.Chat.ID, hintsText, nil)
+	}
+	return nil
+}
+
+// QuestionHints возвращает подсказки к вопросу: подсказки автора, а если их нет — автоматические.
+// masked сообщает, что подсказки автоматические (ответ с прочерками)
+func (h *BaseHandler) QuestionHints(question *models.Question) (hints []string, masked bool) {
+	if len(question.Hints) > 0 {
+		for _, hint := range question.Hints {
+			hints = append(hints, hint.Text)
+		}
+		return hints, false
+	}
+	return h.hinter.Hints(question.Answer), true
+}
+
+// ProcessHint в одной транзакции отмечает взятую подсказку в реакции и списывает ее стоимость.
+// Дробная стоимость копится в реакции, а с баланса списываются только целые монеты
+func (h *BaseHandler) ProcessHint(chatID uint, questionID uint, available int) (*models.Reaction, error) {
+	var reaction *models.Reaction
+	err := h.unitOfWork.Do(func(tx repository.Tx) error {
+		chat, err := lockActiveQuestion(tx, chatID, questionID)
+		if err != nil {
+			return err
+		}
+
+		reaction, err = tx.AddHint(chatID, questionID, h.hinter.Cost)
+		if err != nil {
+			return fmt.Errorf("failed to save hint: %w", err)
+		}
+		if reaction.HintsUsed > available {
+			return errNoMoreHints
+		}
+
+		charge := h.hinter.Charge(reaction.HintCost)
+		if charge == 0 {
+			return nil
+		}
+		if chat.Balance < charge {
+			return errHintNotAffordable
+		}
+
+		err = tx.ApplyTransaction(&models.CoinTransaction{
+			ChatID:     chatID,
+			Delta:      -charge,
+			Reason:     models.ReasonHint,
+			QuestionID: &questionID,
+		})
+		if err != nil {
+			return fmt.Errorf("failed to charge for hint: %w", err)
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	return reaction, nil
+}
+
+// formatHints форматирует подсказки для MarkdownV2; автоматические подсказки выводятся моноширинно
+func (h *BaseHandler) formatHints(hints []string, masked bool) string {
+	lines := make([]string, len(hints))
+	for i, hint := range hints {
+		if masked {
+			hint = "`" + codeEscaper.Replace(hint) + "`"
+		} else {
+			hint = h.EscapeMarkdown(hint)
+		}
+		lines[i] = fmt.Sprintf("*Подсказка %d:* %s", i+1, hint)
+	}
+	return strings.Join(lines, "\n")
+}
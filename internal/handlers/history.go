@@ -0,0 +1,99 @@
+package handlers
+
+import (
+	"fmt"
+	tgbotapi "github.com/go-telegram-bot-api/telegram-bot-api/v5"
+	"qweasley/internal/models"
+	"qweasley/internal/repository"
+	"strconv"
+	"strings"
+)
+
+// Количество операций в истории по умолчанию и максимум
+const (
+	historyDefaultLimit = 10
+	historyMaxLimit     = 50
+)
+
+// transactionReasonTitles названия причин изменения баланса для пользователя
+var transactionReasonTitles = map[models.TransactionReason]string{
+	models.ReasonOpeningBalance:   "Остаток на счете",
+	models.ReasonSignupBonus:      "Бонус за знакомство",
+	models.ReasonAnswer:           "Правильный ответ",
+	models.ReasonSkip:             "Пропуск вопроса",
+	models.ReasonReveal:           "Показ ответа",
+	models.ReasonQuestionApproved: "Вопрос одобрен",
+	models.ReasonPurchase:         "Покупка монет",
+	models.ReasonAdminGrant:       "Начисление администрацией",
+	models.ReasonTransferIn:       "Перевод от другого чата",
+	models.ReasonTransferOut:      "Перевод другому чату",
+	models.ReasonRefund:           "Возврат",
+	models.ReasonHint:             "Подсказка",
+	models.ReasonWrongAnswer:      "Неверный ответ",
+	models.ReasonSpeedBonus:       "Бонус за скорость",
+}
+
+// HistoryHandler обработчик команды /history
+type HistoryHandler struct {
+	*BaseHandler
+}
+
+// NewHistoryHandler создает новый обработчик команды history
+func NewHistoryHandler(bot *tgbotapi.BotAPI, stores *repository.Stores) *HistoryHandler {
+	return &HistoryHandler{
+		BaseHandler: NewBaseHandler(bot, stores),
+	}
+}
+
+// GetCommand возвращает название команды
+func (h *HistoryHandler) GetCommand() string {
+	return "history"
+}
+
+// Handle обрабатывает команду /history [количество]
+func (h *HistoryHandler) Handle(message *tgbotapi.Message) error {
+	chat, err := h.GetOrCreateChat(message.Chat.ID, &message.Chat.Title)
+	if err != nil {
+		fmt.Printf("Failed to get or create chat: %v\n", err)
+		return h.SendMessage(message.Chat.ID, "Произошла ошибка при получении истории", nil)
+	}
+
+	limit := historyDefaultLimit
+	if n, err := strconv.Atoi(strings.TrimSpace(message.CommandArguments())); err == nil && n > 0 {
+		limit = min(n, historyMaxLimit)
+	}
+
+	transactions, err := h.ledgerRepo.GetHistory(chat.ID, limit)
+	if err != nil {
+		fmt.Printf("Failed to get coin history: %v (chat_id: %d)\n", err, chat.ID)
+		return h.SendMessage(message.Chat.ID, "Произошла ошибка при получении истории", nil)
+	}
+
+	// Сверяем баланс с журналом, расхождения только логируем
+	if reconciliation, err := h.ledgerRepo.Reconcile(chat.ID); err == nil && !reconciliation.Consistent() {
+		fmt.Printf("Balance mismatch: balance %d, ledger %d (chat_id: %d)\n", reconciliation.Balance, reconciliation.LedgerSum, chat.ID)
+	}
+
+	if len(transactions) == 0 {
+		return h.SendMessage(message.Chat.ID, "История операций пока пуста\\.", nil)
+	}
+
+	lines := make([]string, 0, len(transactions))
+	for _, transaction := range transactions {
+		title, ok := transactionReasonTitles[transaction.Reason]
+		if !ok {
+			title = string(transaction.Reason)
+		}
+
+		line := fmt.Sprintf("%s  *%s*  %s \\(баланс %s\\)",
+			h.EscapeMarkdown(transaction.CreatedAt.Format("02.01 15:04")),
+			h.EscapeMarkdown(fmt.Sprintf("%+d", transaction.Delta)),
+			h.EscapeMarkdown(title),
+			h.EscapeMarkdown(strconv.Itoa(transaction.BalanceAfter)),
+		)
+		lines = append(lines, line)
+	}
+
+	text := fmt.Sprintf("*Последние операции:*\n\n%s\n\n*Текущий баланс: %d монет\\.*", strings.Join(lines, "\n"), chat.Balance)
+	return h.SendMessage(message.Chat.ID, text, nil)
+}
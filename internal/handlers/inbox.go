@@ -0,0 +1,80 @@
+package handlers
+
+import (
+	"fmt"
+	tgbotapi "github.com/go-telegram-bot-api/telegram-bot-api/v5"
+	"qweasley/internal/repository"
+	"strings"
+)
+
+// inboxLimit сколько сообщений без ответа показывать за раз
+const inboxLimit = 10
+
+// InboxHandler обработчик команды /inbox (только для администратора)
+type InboxHandler struct {
+	*BaseHandler
+	feedbackRepo repository.FeedbackStore
+}
+
+// NewInboxHandler создает новый обработчик команды inbox
+func NewInboxHandler(bot *tgbotapi.BotAPI, stores *repository.Stores) *InboxHandler {
+	return &InboxHandler{
+		BaseHandler:  NewBaseHandler(bot, stores),
+		feedbackRepo: stores.Feedbacks,
+	}
+}
+
+// GetCommand возвращает название команды
+func (h *InboxHandler) GetCommand() string {
+	return "inbox"
+}
+
+// Handle обрабатывает команду /inbox
+func (h *InboxHandler) Handle(message *tgbotapi.Message) error {
+	if !h.IsAdminChat(message.Chat.ID) {
+		return nil
+	}
+
+	feedbacks, err := h.feedbackRepo.GetUnanswered(inboxLimit)
+	if err != nil {
+		fmt.Printf("Failed to get unanswered feedback: %v\n", err)
+		return h.SendMessage(message.Chat.ID, "Произошла ошибка при получении сообщений", nil)
+	}
+
+	if len(feedbacks) == 0 {
+		return h.SendMessage(message.Chat.ID, "Все сообщения обратной связи отвечены\\.", nil)
+	}
+
+	total, err := h.feedbackRepo.CountUnanswered()
+	if err != nil {
+		fmt.Printf("Failed to count unanswered feedback: %v\n", err)
+		total = int64(len(feedbacks))
+	}
+
+	var lines []string
+	var keyboard [][]tgbotapi.InlineKeyboardButton
+	for _, feedback := range feedbacks {
+		line := fmt.Sprintf("*\\#%d* %s, чат `%d`", feedback.ID, h.EscapeMarkdown(feedback.CreatedAt.Format("02.01.2006 15:04")), feedback.Chat.TelegramID)
+		if feedback.Chat.Title != nil && *feedback.Chat.Title != "" {
+			line += " " + h.EscapeMarkdown(*feedback.Chat.Title)
+		}
+		line += "\n" + h.EscapeMarkdown(truncateText(feedback.Text, 200))
+		lines = append(lines, line)
+
+		keyboard = append(keyboard, []tgbotapi.InlineKeyboardButton{
+			tgbotapi.NewInlineKeyboardButtonData(fmt.Sprintf("Ответить на #%d", feedback.ID), fmt.Sprintf("feedback_reply:%d", feedback.ID)),
+		})
+	}
+
+	text := fmt.Sprintf("*Без ответа: %d*\n\n%s", total, strings.Join(lines, "\n\n"))
+	return h.SendMessage(message.Chat.ID, text, &tgbotapi.InlineKeyboardMarkup{InlineKeyboard: keyboard})
+}
+
+// truncateText обрезает текст до указанного числа символов
+func truncateText(text string, limit int) string {
+	runes := []rune(text)
+	if len(runes) <= limit {
+		return text
+	}
+	return string(runes[:limit]) + "…"
+}
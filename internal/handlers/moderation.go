@@ -0,0 +1,227 @@
+package handlers
+
+import (
+	"fmt"
+	tgbotapi "github.com/go-telegram-bot-api/telegram-bot-api/v5"
+	"qweasley/internal/repository"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// Действия модерации, требующие ввода от администратора
+const (
+	moderationActionReject = "reject"
+	moderationActionEdit   = "edit"
+)
+
+// questionApprovalReward награда автору за одобренный вопрос
+const questionApprovalReward = 10
+
+// ModerationCallback обработчик callback'ов модерации предложенных вопросов
+type ModerationCallback struct {
+	*BaseHandler
+}
+
+// NewModerationCallback создает новый обработчик callback'ов модерации
+func NewModerationCallback(bot *tgbotapi.BotAPI, stores *repository.Stores) *ModerationCallback {
+	return &ModerationCallback{
+		BaseHandler: NewBaseHandler(bot, stores),
+	}
+}
+
+// GetCallbackData возвращает данные callback'а
+func (h *ModerationCallback) GetCallbackData() string {
+	return "moderate"
+}
+
+// Handle обрабатывает callback вида "moderate:<действие>:<id вопроса>"
+func (h *ModerationCallback) Handle(callback *tgbotapi.CallbackQuery) error {
+	// Отвечаем на callback query
+	if err := h.AnswerCallbackQuery(callback.ID); err != nil {
+		fmt.Printf("Failed to answer callback query: %v\n", err)
+	}
+
+	chatID := callback.Message.Chat.ID
+	if !h.IsAdminChat(chatID) {
+		return nil
+	}
+
+	parts := strings.Split(callback.Data, ":")
+	if len(parts) != 3 {
+		return fmt.Errorf("некорректные данные модерации: %s", callback.Data)
+	}
+
+	questionID, err := strconv.ParseUint(parts[2], 10, 64)
+	if err != nil {
+		return fmt.Errorf("некорректный ID вопроса: %s", parts[2])
+	}
+
+	switch parts[1] {
+	case "approve":
+		return h.approve(chatID, uint(questionID))
+	case moderationActionReject:
+		return h.requestInput(chatID, &callback.Message.Chat.Title, uint(questionID), moderationActionReject,
+			fmt.Sprintf("Напишите причину отклонения вопроса \\#%d следующим сообщением\\.", questionID))
+	case moderationActionEdit:
+		return h.requestInput(chatID, &callback.Message.Chat.Title, uint(questionID), moderationActionEdit,
+			fmt.Sprintf("Пришлите исправленный вопрос \\#%d в формате:\n\nтекст вопроса\nОтвет: ответ\nКомментарий: комментарий \\(необязательно\\)", questionID))
+	}
+
+	return fmt.Errorf("неизвестное действие модерации: %s", parts[1])
+}
+
+// approve публикует вопрос и начисляет автору награду
+func (h *ModerationCallback) approve(adminChatID int64, questionID uint) error {
+	approved, err := h.questionRepo.Approve(questionID, questionApprovalReward)
+	if err != nil {
+		fmt.Printf("Failed to approve question: %v (question_id: %d)\n", err, questionID)
+		return h.SendMessage(adminChatID, "Произошла ошибка при публикации вопроса", nil)
+	}
+
+	if !approved {
+		return h.SendMessage(adminChatID, fmt.Sprintf("Вопрос \\#%d уже прошел модерацию", questionID), nil)
+	}
+
+	question, err := h.questionRepo.GetByID(questionID)
+	if err != nil {
+		fmt.Printf("Failed to get approved question: %v (question_id: %d)\n", err, questionID)
+		return h.SendMessage(adminChatID, "Вопрос опубликован, но не удалось уведомить автора", nil)
+	}
+
+	if question.Author != nil {
+		text := fmt.Sprintf("*Ваш вопрос одобрен и опубликован\\!*\n\n%s\n\nНа ваш счет начислено %d монет\\.", h.EscapeMarkdown(question.Text), questionApprovalReward)
+		if err := h.SendMessage(question.Author.TelegramID, text, nil); err != nil {
+			fmt.Printf("Failed to notify question author: %v (question_id: %d)\n", err, questionID)
+		}
+	}
+
+	return h.SendMessage(adminChatID, fmt.Sprintf("Вопрос \\#%d опубликован", questionID), nil)
+}
+
+// requestInput переводит чат администратора в ожидание ввода по вопросу
+func (h *ModerationCallback) requestInput(adminChatID int64, title *string, questionID uint, action string, prompt string) error {
+	chat, err := h.GetOrCreateChat(adminChatID, title)
+	if err != nil {
+		fmt.Printf("Failed to get or create chat: %v\n", err)
+		return h.SendMessage(adminChatID, "Произошла ошибка при обработке команды", nil)
+	}
+
+	if err := h.chatRepo.SetModeration(chat.ID, questionID, action, 30*time.Minute); err != nil {
+		fmt.Printf("Failed to set moderation: %v (question_id: %d)\n", err, questionID)
+		return h.SendMessage(adminChatID, "Произошла ошибка при обработке команды", nil)
+	}
+
+	return h.SendMessage(adminChatID, prompt, nil)
+}
+
+// HandleModerationMessage обрабатывает ввод администратора по модерации
+func (h *ModerationCallback) HandleModerationMessage(message *tgbotapi.Message) error {
+	chat, err := h.GetOrCreateChat(message.Chat.ID, &message.Chat.Title)
+	if err != nil {
+		fmt.Printf("Failed to get or create chat: %v\n", err)
+		return h.SendMessage(message.Chat.ID, "Произошла ошибка при обработке сообщения", nil)
+	}
+
+	if !chat.IsModerating() || !h.IsAdminChat(message.Chat.ID) {
+		return nil
+	}
+
+	questionID := *chat.ModerationQuestionID
+	action := *chat.ModerationAction
+
+	if err := h.chatRepo.ClearModeration(chat.ID); err != nil {
+		fmt.Printf("Failed to clear moderation: %v (chat_id: %d)\n", err, chat.ID)
+	}
+
+	switch action {
+	case moderationActionReject:
+		return h.reject(message.Chat.ID, questionID, strings.TrimSpace(message.Text))
+	case moderationActionEdit:
+		return h.edit(message.Chat.ID, questionID, message.Text)
+	}
+
+	return nil
+}
+
+// reject отклоняет вопрос и сообщает автору причину
+func (h *ModerationCallback) reject(adminChatID int64, questionID uint, reason string) error {
+	rejected, err := h.questionRepo.Reject(questionID)
+	if err != nil {
+		fmt.Printf("Failed to reject question: %v (question_id: %d)\n", err, questionID)
+		return h.SendMessage(adminChatID, "Произошла ошибка при отклонении вопроса", nil)
+	}
+
+	if !rejected {
+		return h.SendMessage(adminChatID, fmt.Sprintf("Вопрос \\#%d уже прошел модерацию", questionID), nil)
+	}
+
+	question, err := h.questionRepo.GetByID(questionID)
+	if err != nil {
+		fmt.Printf("Failed to get rejected question: %v (question_id: %d)\n", err, questionID)
+		return h.SendMessage(adminChatID, "Вопрос отклонен, но не удалось уведомить автора", nil)
+	}
+
+	if question.Author != nil {
+		text := fmt.Sprintf("*К сожалению, ваш вопрос не прошел модерацию\\.*\n\n%s", h.EscapeMarkdown(question.Text))
+		if reason != "" {
+			text += "\n\n*Причина:* " + h.EscapeMarkdown(reason)
+		}
+		if err := h.SendMessage(question.Author.TelegramID, text, nil); err != nil {
+			fmt.Printf("Failed to notify question author: %v (question_id: %d)\n", err, questionID)
+		}
+	}
+
+	return h.SendMessage(adminChatID, fmt.Sprintf("Вопрос \\#%d отклонен", questionID), nil)
+}
+
+// edit применяет правки администратора к вопросу, еще не прошедшему модерацию, и заново показывает его карточку
+func (h *ModerationCallback) edit(adminChatID int64, questionID uint, input string) error {
+	question, err := h.questionRepo.GetByID(questionID)
+	if err != nil {
+		fmt.Printf("Failed to get edited question: %v (question_id: %d)\n", err, questionID)
+		return h.SendMessage(adminChatID, "Произошла ошибка при редактировании вопроса", nil)
+	}
+
+	text, answerText, comment := parseQuestionEdit(input)
+	if text == "" || answerText == "" {
+		return h.SendMessage(adminChatID, "Не удалось разобрать вопрос: нужны текст и строка «Ответ: …»\\. Нажмите «Редактировать» еще раз\\.", nil)
+	}
+
+	question.Text = text
+	question.Answer = answerText
+	if comment != "" {
+		question.Comment = &comment
+	}
+
+	updated, err := h.questionRepo.UpdateContent(question)
+	if err != nil {
+		fmt.Printf("Failed to update edited question: %v (question_id: %d)\n", err, questionID)
+		return h.SendMessage(adminChatID, "Произошла ошибка при редактировании вопроса", nil)
+	}
+
+	// Опубликованный или отклоненный вопрос не правится, и кнопки модерации для него не показываются
+	if !updated {
+		return h.SendMessage(adminChatID, fmt.Sprintf("Вопрос \\#%d уже прошел модерацию", questionID), nil)
+	}
+
+	return h.SendModerationCard(question)
+}
+
+// parseQuestionEdit разбирает исправленный вопрос на текст, ответ и комментарий
+func parseQuestionEdit(input string) (text string, answerText string, comment string) {
+	var textLines []string
+	for _, line := range strings.Split(input, "\n") {
+		trimmed := strings.TrimSpace(line)
+		switch {
+		case strings.HasPrefix(trimmed, "Ответ:"):
+			answerText = strings.TrimSpace(strings.TrimPrefix(trimmed, "Ответ:"))
+		case strings.HasPrefix(trimmed, "Комментарий:"):
+			comment = strings.TrimSpace(strings.TrimPrefix(trimmed, "Комментарий:"))
+		default:
+			textLines = append(textLines, line)
+		}
+	}
+
+	return strings.TrimSpace(strings.Join(textLines, "\n")), answerText, comment
+}
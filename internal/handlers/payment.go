@@ -0,0 +1,230 @@
+package handlers
+
+import (
+	"fmt"
+	tgbotapi "github.com/go-telegram-bot-api/telegram-bot-api/v5"
+	"os"
+	"qweasley/internal/models"
+	"qweasley/internal/repository"
+	"strconv"
+	"strings"
+)
+
+// coinPacks доступные для покупки пакеты монет
+var coinPacks = []int{10, 50, 100}
+
+// purchasePayloadPrefix префикс полезной нагрузки счета на покупку монет
+const purchasePayloadPrefix = "coins:"
+
+// PaymentHandler обработчик покупки монет через Telegram Payments
+type PaymentHandler struct {
+	*BaseHandler
+	purchaseRepo repository.PurchaseStore
+}
+
+// NewPaymentHandler создает новый обработчик покупки монет
+func NewPaymentHandler(bot *tgbotapi.BotAPI, stores *repository.Stores) *PaymentHandler {
+	return &PaymentHandler{
+		BaseHandler:  NewBaseHandler(bot, stores),
+		purchaseRepo: stores.Purchases,
+	}
+}
+
+// GetCommand возвращает название команды
+func (h *PaymentHandler) GetCommand() string {
+	return "buy"
+}
+
+// Handle обрабатывает команду /buy
+func (h *PaymentHandler) Handle(message *tgbotapi.Message) error {
+	var rows [][]tgbotapi.InlineKeyboardButton
+	for _, coins := range coinPacks {
+		label := fmt.Sprintf("%d монет — %s", coins, formatPrice(packPrice(coins)))
+		rows = append(rows, []tgbotapi.InlineKeyboardButton{
+			tgbotapi.NewInlineKeyboardButtonData(label, fmt.Sprintf("buy:%d", coins)),
+		})
+	}
+
+	text := "*Покупка монет*\n\nВыберите пакет, и бот пришлет счет для оплаты\\. Монеты поступят на счет чата сразу после оплаты\\."
+	return h.SendMessage(message.Chat.ID, text, &tgbotapi.InlineKeyboardMarkup{InlineKeyboard: rows})
+}
+
+// SendInvoice отправляет счет на покупку пакета монет
+func (h *PaymentHandler) SendInvoice(chatID int64, coins int) error {
+	price := packPrice(coins)
+	invoice := tgbotapi.NewInvoice(
+		chatID,
+		fmt.Sprintf("%d монет", coins),
+		fmt.Sprintf("Пакет из %d монет для квиза Qweasley", coins),
+		purchasePayloadPrefix+strconv.Itoa(coins),
+		os.Getenv("PAYMENT_PROVIDER_TOKEN"),
+		"",
+		price.Currency,
+		[]tgbotapi.LabeledPrice{{Label: fmt.Sprintf("%d монет", coins), Amount: price.Amount}},
+	)
+	// Без этого поля библиотека отправляет null, который Telegram не принимает
+	invoice.SuggestedTipAmounts = []int{}
+
+	_, err := h.bot.Request(invoice)
+	return err
+}
+
+// HandlePreCheckoutQuery подтверждает или отклоняет оплату перед списанием денег
+func (h *PaymentHandler) HandlePreCheckoutQuery(query *tgbotapi.PreCheckoutQuery) error {
+	coins, ok := parsePurchasePayload(query.InvoicePayload)
+	if !ok {
+		return h.answerPreCheckoutQuery(query.ID, "Такого пакета монет больше нет")
+	}
+
+	price := packPrice(coins)
+	if query.Currency != price.Currency || query.TotalAmount != price.Amount {
+		return h.answerPreCheckoutQuery(query.ID, "Цена пакета изменилась, запросите счет заново командой /buy")
+	}
+
+	return h.answerPreCheckoutQuery(query.ID, "")
+}
+
+// HandleSuccessfulPayment начисляет монеты после успешной оплаты
+func (h *PaymentHandler) HandleSuccessfulPayment(message *tgbotapi.Message) error {
+	payment := message.SuccessfulPayment
+
+	coins, ok := parsePurchasePayload(payment.InvoicePayload)
+	if !ok {
+		fmt.Printf("Unknown purchase payload: %s (charge_id: %s)\n", payment.InvoicePayload, payment.TelegramPaymentChargeID)
+		return h.SendMessage(message.Chat.ID, "Оплата получена, но пакет не распознан\\. Напишите нам через /feedback", nil)
+	}
+
+	chat, err := h.GetOrCreateChat(message.Chat.ID, &message.Chat.Title)
+	if err != nil {
+		fmt.Printf("Failed to get or create chat: %v (charge_id: %s)\n", err, payment.TelegramPaymentChargeID)
+		return err
+	}
+
+	purchase := &models.Purchase{
+		ChatID:           chat.ID,
+		Coins:            coins,
+		Currency:         payment.Currency,
+		TotalAmount:      payment.TotalAmount,
+		Payload:          payment.InvoicePayload,
+		TelegramChargeID: payment.TelegramPaymentChargeID,
+	}
+	if payment.ProviderPaymentChargeID != "" {
+		purchase.ProviderChargeID = &payment.ProviderPaymentChargeID
+	}
+
+	credited, err := h.purchaseRepo.Credit(purchase)
+	if err != nil {
+		fmt.Printf("Failed to credit purchase: %v (charge_id: %s)\n", err, payment.TelegramPaymentChargeID)
+		return h.SendMessage(message.Chat.ID, "Оплата получена, но монеты не начислены\\. Мы уже разбираемся, при необходимости напишите через /feedback", nil)
+	}
+
+	// Повторное уведомление о том же платеже
+	if !credited {
+		return nil
+	}
+
+	return h.SendMessage(message.Chat.ID, fmt.Sprintf("*Спасибо за покупку\\!* На счет начислено %d монет\\.", coins), nil)
+}
+
+// answerPreCheckoutQuery отвечает на pre_checkout_query, пустой текст ошибки означает подтверждение
+func (h *PaymentHandler) answerPreCheckoutQuery(queryID string, errorMessage string) error {
+	// PreCheckoutConfig не передает ok=false, поэтому параметры собираем вручную
+	params := tgbotapi.Params{
+		"pre_checkout_query_id": queryID,
+		"ok":                    strconv.FormatBool(errorMessage == ""),
+	}
+	params.AddNonEmpty("error_message", errorMessage)
+
+	_, err := h.bot.MakeRequest("answerPreCheckoutQuery", params)
+	return err
+}
+
+// BuyCallback обработчик callback'а выбора пакета монет
+type BuyCallback struct {
+	*BaseHandler
+	paymentHandler *PaymentHandler
+}
+
+// NewBuyCallback создает новый обработчик callback'а buy
+func NewBuyCallback(paymentHandler *PaymentHandler, bot *tgbotapi.BotAPI, stores *repository.Stores) *BuyCallback {
+	return &BuyCallback{
+		BaseHandler:    NewBaseHandler(bot, stores),
+		paymentHandler: paymentHandler,
+	}
+}
+
+// GetCallbackData возвращает данные callback'а
+func (h *BuyCallback) GetCallbackData() string {
+	return "buy"
+}
+
+// Handle обрабатывает callback вида "buy:<количество монет>"
+func (h *BuyCallback) Handle(callback *tgbotapi.CallbackQuery) error {
+	// Отвечаем на callback query
+	if err := h.AnswerCallbackQuery(callback.ID); err != nil {
+		fmt.Printf("Failed to answer callback query: %v\n", err)
+	}
+
+	_, coinsText, _ := strings.Cut(callback.Data, ":")
+	coins, err := strconv.Atoi(coinsText)
+	if err != nil || !isCoinPack(coins) {
+		return h.SendMessage(callback.Message.Chat.ID, "Такого пакета монет нет", nil)
+	}
+
+	if err := h.paymentHandler.SendInvoice(callback.Message.Chat.ID, coins); err != nil {
+		fmt.Printf("Failed to send invoice: %v (chat_id: %d)\n", err, callback.Message.Chat.ID)
+		return h.SendMessage(callback.Message.Chat.ID, "Не удалось выставить счет, попробуйте позже", nil)
+	}
+
+	return nil
+}
+
+// Price цена пакета в минимальных единицах валюты
+type Price struct {
+	Currency string
+	Amount   int
+}
+
+// packPrice возвращает цену пакета: в рублях при подключенном провайдере, иначе в Telegram Stars
+func packPrice(coins int) Price {
+	if os.Getenv("PAYMENT_PROVIDER_TOKEN") == "" {
+		starsPerCoin, err := strconv.Atoi(os.Getenv("PAYMENT_STARS_PER_COIN"))
+		if err != nil || starsPerCoin <= 0 {
+			starsPerCoin = 5
+		}
+		return Price{Currency: "XTR", Amount: coins * starsPerCoin}
+	}
+
+	// 1 монета = 10 рублей, сумма в копейках
+	return Price{Currency: "RUB", Amount: coins * 10 * 100}
+}
+
+// formatPrice форматирует цену для кнопки
+func formatPrice(price Price) string {
+	if price.Currency == "XTR" {
+		return fmt.Sprintf("%d ⭐", price.Amount)
+	}
+	return fmt.Sprintf("%d ₽", price.Amount/100)
+}
+
+// parsePurchasePayload извлекает размер пакета из полезной нагрузки счета
+func parsePurchasePayload(payload string) (int, bool) {
+	if !strings.HasPrefix(payload, purchasePayloadPrefix) {
+		return 0, false
+	}
+	coins, err := strconv.Atoi(strings.TrimPrefix(payload, purchasePayloadPrefix))
+	if err != nil || !isCoinPack(coins) {
+		return 0, false
+	}
+	return coins, true
+}
+
+// isCoinPack проверяет, есть ли пакет с таким количеством монет
+func isCoinPack(coins int) bool {
+	for _, pack := range coinPacks {
+		if pack == coins {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,85 @@
+package handlers
+
+import (
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	tgbotapi "github.com/go-telegram-bot-api/telegram-bot-api/v5"
+	"os"
+	"strconv"
+	"strings"
+)
+
+// questionCallbackVersion версия формата данных кнопок, привязанных к вопросу
+const questionCallbackVersion = "1"
+
+// signatureLength длина подписи в данных кнопки (в hex-символах); callback_data ограничен 64 байтами
+const signatureLength = 12
+
+// Тексты всплывающих предупреждений на нажатие устаревших кнопок
+const (
+	staleButtonAlert    = "Эта кнопка устарела. Отправьте /start, чтобы получить новый вопрос"
+	closedQuestionAlert = "Эта кнопка относится к вопросу, который уже закрыт"
+	activeQuestionAlert = "Сначала ответьте на текущий вопрос или пропустите его"
+)
+
+// ErrStaleCallback кнопка создана в старом формате (без привязки к вопросу) или другой версии
+var ErrStaleCallback = errors.New("stale callback data")
+
+// QuestionCallbackData формирует данные кнопки вида "действие:версия:вопрос:подпись".
+// Подпись связывает действие, чат и вопрос, поэтому кнопку нельзя подделать или перенести в другой чат
+func (h *BaseHandler) QuestionCallbackData(action string, chatID int64, questionID uint) string {
+	return fmt.Sprintf("%s:%s:%d:%s", action, questionCallbackVersion, questionID, h.callbackSignature(action, chatID, questionID))
+}
+
+// ParseQuestionCallback проверяет версию и подпись данных кнопки и возвращает ID вопроса
+func (h *BaseHandler) ParseQuestionCallback(callback *tgbotapi.CallbackQuery) (uint, error) {
+	parts := strings.Split(callback.Data, ":")
+	if len(parts) != 4 || parts[1] != questionCallbackVersion {
+		return 0, ErrStaleCallback
+	}
+
+	questionID, err := strconv.ParseUint(parts[2], 10, 64)
+	if err != nil {
+		return 0, fmt.Errorf("некорректный ID вопроса в callback: %s", callback.Data)
+	}
+
+	expected := h.callbackSignature(parts[0], callback.Message.Chat.ID, uint(questionID))
+	if !hmac.Equal([]byte(parts[3]), []byte(expected)) {
+		return 0, fmt.Errorf("неверная подпись callback: %s", callback.Data)
+	}
+
+	return uint(questionID), nil
+}
+
+// QuestionFromCallback возвращает ID вопроса, к которому привязана кнопка.
+// На кнопку старого формата или с неверной подписью сразу отвечает предупреждением и возвращает false
+func (h *BaseHandler) QuestionFromCallback(callback *tgbotapi.CallbackQuery) (uint, bool) {
+	questionID, err := h.ParseQuestionCallback(callback)
+	if err == nil {
+		return questionID, true
+	}
+
+	if !errors.Is(err, ErrStaleCallback) {
+		fmt.Printf("Rejected callback: %v (chat_id: %d)\n", err, callback.Message.Chat.ID)
+	}
+	if err := h.AnswerCallbackAlert(callback.ID, staleButtonAlert); err != nil {
+		fmt.Printf("Failed to answer callback query: %v\n", err)
+	}
+	return 0, false
+}
+
+// callbackSignature вычисляет HMAC данных кнопки.
+// Ключ берется из CALLBACK_SECRET, а если он не задан — из токена бота
+func (h *BaseHandler) callbackSignature(action string, chatID int64, questionID uint) string {
+	secret := os.Getenv("CALLBACK_SECRET")
+	if secret == "" {
+		secret = h.bot.Token
+	}
+
+	mac := hmac.New(sha256.New, []byte(secret))
+	fmt.Fprintf(mac, "%s:%s:%d:%d", action, questionCallbackVersion, chatID, questionID)
+	return hex.EncodeToString(mac.Sum(nil))[:signatureLength]
+}
@@ -0,0 +1,317 @@
+package handlers
+
+import (
+	"errors"
+	"fmt"
+	tgbotapi "github.com/go-telegram-bot-api/telegram-bot-api/v5"
+	"qweasley/internal/answer"
+	"qweasley/internal/models"
+	"qweasley/internal/repository"
+	"time"
+)
+
+// reviewAnswerTimeout сколько чат может отвечать на вопрос повторения
+const reviewAnswerTimeout = 30 * time.Minute
+
+// reviewDelays через сколько после пропуска или показа ответа вопрос попадает в повторение
+var reviewDelays = map[string]time.Duration{
+	"skip": 0,
+	"fail": 24 * time.Hour,
+}
+
+// Действия кнопок под вопросом повторения и под ответом на него
+const (
+	reviewRevealAction = "review_reveal"
+	reviewNextAction   = "review_next"
+	reviewFinishAction = "review_finish"
+)
+
+// ReviewHandler обработчик команды /review: повторение пропущенных вопросов и вопросов, ответ на которые
+// пришлось показать. Повторение бесплатно и не влияет ни на рейтинг, ни на уровень чата
+type ReviewHandler struct {
+	*BaseHandler
+	reviewRepo repository.ReviewStore
+}
+
+// NewReviewHandler создает новый обработчик команды review
+func NewReviewHandler(bot *tgbotapi.BotAPI, stores *repository.Stores) *ReviewHandler {
+	return &ReviewHandler{
+		BaseHandler: NewBaseHandler(bot, stores),
+		reviewRepo:  stores.Reviews,
+	}
+}
+
+// GetCommand возвращает название команды
+func (h *ReviewHandler) GetCommand() string {
+	return "review"
+}
+
+// Handle обрабатывает команду /review
+func (h *ReviewHandler) Handle(message *tgbotapi.Message) error {
+	chat, err := h.GetOrCreateChat(message.Chat.ID, &message.Chat.Title)
+	if err != nil {
+		fmt.Printf("Failed to get or create chat: %v\n", err)
+		return h.SendMessage(message.Chat.ID, "Произошла ошибка при обработке команды", nil)
+	}
+
+	// Повторение не прерывает обычный вопрос
+	if chat.IsWaitingAnswer() {
+		return h.SendMessage(message.Chat.ID, h.EscapeMarkdown(activeQuestionAlert), nil)
+	}
+
+	return h.sendNextReview(chat)
+}
+
+// sendNextReview задает следующий вопрос повторения или сообщает, когда будет ближайшее повторение
+func (h *ReviewHandler) sendNextReview(chat *models.Chat) error {
+	card, err := h.reviewRepo.GetDue(chat.ID, time.Now().UTC())
+	if err != nil {
+		fmt.Printf("Failed to get review card: %v (chat_id: %d)\n", err, chat.ID)
+		return h.SendMessage(chat.TelegramID, "Произошла ошибка при получении вопроса", nil)
+	}
+	if card == nil {
+		return h.SendMessage(chat.TelegramID, h.nothingDueText(chat), nil)
+	}
+
+	question, err := h.questionRepo.GetByID(card.QuestionID)
+	if err != nil {
+		fmt.Printf("Failed to get review question: %v (chat_id: %d, question_id: %d)\n", err, chat.ID, card.QuestionID)
+		return h.SendMessage(chat.TelegramID, "Произошла ошибка при получении вопроса", nil)
+	}
+
+	if err := h.chatRepo.SetReview(chat.ID, question.ID, reviewAnswerTimeout); err != nil {
+		fmt.Printf("Failed to set review: %v (chat_id: %d, question_id: %d)\n", err, chat.ID, question.ID)
+		return h.SendMessage(chat.TelegramID, "Произошла ошибка при получении вопроса", nil)
+	}
+
+	keyboard := &tgbotapi.InlineKeyboardMarkup{
+		InlineKeyboard: [][]tgbotapi.InlineKeyboardButton{
+			{
+				tgbotapi.NewInlineKeyboardButtonData("Показать ответ", h.QuestionCallbackData(reviewRevealAction, chat.TelegramID, question.ID)),
+				tgbotapi.NewInlineKeyboardButtonData("Закончить", h.QuestionCallbackData(reviewFinishAction, chat.TelegramID, question.ID)),
+			},
+		},
+	}
+
+	text := "_Повторение_\n\n" + h.FormatQuestionText(question)
+	messageID, err := h.sendQuestionText(chat.TelegramID, question, text, keyboard)
+	if err != nil {
+		return err
+	}
+
+	// Сообщение запоминаем там же, где сообщение обычного вопроса: пока идет повторение, обычного вопроса нет
+	if err := h.chatRepo.SetLastMessage(chat.ID, messageID); err != nil {
+		fmt.Printf("Failed to save review message: %v (chat_id: %d, message_id: %d)\n", err, chat.ID, messageID)
+	}
+	return nil
+}
+
+// nothingDueText сообщение о том, что повторять пока нечего, с датой ближайшего повторения
+func (h *ReviewHandler) nothingDueText(chat *models.Chat) string {
+	next, err := h.reviewRepo.NextDue(chat.ID)
+	if err != nil {
+		fmt.Printf("Failed to get next review: %v (chat_id: %d)\n", err, chat.ID)
+	}
+	if next == nil {
+		return "Повторять пока нечего\\. Сюда попадают вопросы, которые вы пропустили или на которые посмотрели ответ\\."
+	}
+	return fmt.Sprintf("Повторять пока нечего\\. Следующее повторение — %s\\.", h.EscapeMarkdown(next.Format("02.01.2006")))
+}
+
+// HandleAnswer проверяет текстовый ответ на вопрос повторения. Неверный ответ ничего не стоит: можно пробовать снова
+func (h *ReviewHandler) HandleAnswer(message *tgbotapi.Message, chat *models.Chat) error {
+	question, err := h.questionRepo.GetByID(*chat.ReviewQuestionID)
+	if err != nil {
+		fmt.Printf("Failed to get review question: %v (chat_id: %d)\n", err, chat.ID)
+		return h.SendMessage(message.Chat.ID, "Произошла ошибка при обработке ответа", nil)
+	}
+
+	input, addressed := h.answerText(message)
+	result := h.matcher.Match(input, question.AcceptedAnswers()...)
+	if !result.Accepted() {
+		// В группе на переписку, не адресованную боту, не отвечаем
+		if !addressed {
+			return nil
+		}
+		return h.SendMessage(message.Chat.ID, "Ответ неверный\\. Попробуйте еще раз или нажмите «Показать ответ»", nil)
+	}
+
+	card, err := h.gradeReview(chat.ID, question.ID, models.ReviewQualityCorrect)
+	if errors.Is(err, repository.ErrQuestionChanged) {
+		return nil
+	}
+	if err != nil {
+		fmt.Printf("Failed to grade review: %v (chat_id: %d, question_id: %d)\n", err, chat.ID, question.ID)
+		return h.SendMessage(message.Chat.ID, "Произошла ошибка при обработке ответа", nil)
+	}
+
+	if chat.LastMessageID != nil {
+		h.ResolveQuestionMessage(message.Chat.ID, *chat.LastMessageID, question, "Ответ засчитан")
+	}
+
+	text := "*Верно\\!*"
+	if result.Verdict == answer.Close {
+		text += " Правильно пишется: " + h.EscapeMarkdown(result.Expected)
+	}
+	return h.sendReviewResult(chat, question, card, text)
+}
+
+// gradeReview снимает ожидание ответа и пересчитывает карточку в одной транзакции;
+// ErrQuestionChanged — вопрос повторения уже закрыт параллельным запросом
+func (h *ReviewHandler) gradeReview(chatID uint, questionID uint, quality int) (*models.ReviewCard, error) {
+	var card *models.ReviewCard
+	err := h.unitOfWork.Do(func(tx repository.Tx) error {
+		if err := tx.ClearReview(chatID, questionID); err != nil {
+			return err
+		}
+
+		var err error
+		card, err = tx.GradeReview(chatID, questionID, quality, time.Now().UTC())
+		return err
+	})
+	return card, err
+}
+
+// sendReviewResult отправляет итог повторения: дату следующего повторения, комментарий к вопросу
+// и кнопку "Дальше", если есть еще вопросы для повторения
+func (h *ReviewHandler) sendReviewResult(chat *models.Chat, question *models.Question, card *models.ReviewCard, text string) error {
+	if question.Comment != nil {
+		text += "\n\n" + h.EscapeMarkdown(*question.Comment)
+	}
+	text += "\n\n_" + h.EscapeMarkdown("Следующее повторение: "+card.DueAt.Format("02.01.2006")) + "_"
+
+	var keyboard *tgbotapi.InlineKeyboardMarkup
+	due, err := h.reviewRepo.CountDue(chat.ID, time.Now().UTC())
+	if err != nil {
+		fmt.Printf("Failed to count review cards: %v (chat_id: %d)\n", err, chat.ID)
+	}
+	if due > 0 {
+		keyboard = &tgbotapi.InlineKeyboardMarkup{
+			InlineKeyboard: [][]tgbotapi.InlineKeyboardButton{
+				{
+					tgbotapi.NewInlineKeyboardButtonData(fmt.Sprintf("Дальше (%d)", due), h.QuestionCallbackData(reviewNextAction, chat.TelegramID, question.ID)),
+				},
+			},
+		}
+	} else {
+		text += "\n\nНа сегодня все вопросы повторены\\."
+	}
+
+	if photoURL := h.answerPictureURL(question); photoURL != "" {
+		return h.SendPhoto(chat.TelegramID, photoURL, text, keyboard)
+	}
+	return h.SendMessage(chat.TelegramID, text, keyboard)
+}
+
+// ReviewCallback обработчик кнопок повторения: показать ответ, следующий вопрос и закончить
+type ReviewCallback struct {
+	*BaseHandler
+	reviewHandler *ReviewHandler
+	action        string
+}
+
+// NewReviewCallback создает новый обработчик кнопки повторения с действием action
+func NewReviewCallback(action string, reviewHandler *ReviewHandler, bot *tgbotapi.BotAPI, stores *repository.Stores) *ReviewCallback {
+	return &ReviewCallback{
+		BaseHandler:   NewBaseHandler(bot, stores),
+		reviewHandler: reviewHandler,
+		action:        action,
+	}
+}
+
+// GetCallbackData возвращает данные callback'а
+func (h *ReviewCallback) GetCallbackData() string {
+	return h.action
+}
+
+// Handle обрабатывает нажатие кнопки повторения
+func (h *ReviewCallback) Handle(callback *tgbotapi.CallbackQuery) error {
+	questionID, ok := h.QuestionFromCallback(callback)
+	if !ok {
+		return nil
+	}
+
+	chatID := callback.Message.Chat.ID
+	chat, err := h.GetOrCreateChat(chatID, &callback.Message.Chat.Title)
+	if err != nil {
+		h.AnswerCallbackQuery(callback.ID)
+		fmt.Printf("Failed to get or create chat in review callback: %v (chat_id: %d)\n", err, chatID)
+		return h.SendMessage(chatID, "Произошла ошибка при обработке команды", nil)
+	}
+
+	switch h.action {
+	case reviewNextAction:
+		return h.next(callback, chat, questionID)
+	case reviewRevealAction:
+		return h.reveal(callback, chat, questionID)
+	default:
+		return h.finish(callback, chat, questionID)
+	}
+}
+
+// next убирает кнопку под итогом и задает следующий вопрос повторения
+func (h *ReviewCallback) next(callback *tgbotapi.CallbackQuery, chat *models.Chat, questionID uint) error {
+	// Старая кнопка не должна заменять вопрос, который уже задан и ждет ответа
+	if chat.IsWaitingAnswer() || (chat.IsReviewing() && *chat.ReviewQuestionID != questionID) {
+		return h.AnswerCallbackAlert(callback.ID, activeQuestionAlert)
+	}
+
+	if err := h.AnswerCallbackQuery(callback.ID); err != nil {
+		fmt.Printf("Failed to answer callback query: %v\n", err)
+	}
+	if err := h.RemoveKeyboard(chat.TelegramID, callback.Message.MessageID); err != nil {
+		fmt.Printf("Failed to remove keyboard: %v (chat_id: %d, message_id: %d)\n", err, chat.TelegramID, callback.Message.MessageID)
+	}
+
+	return h.reviewHandler.sendNextReview(chat)
+}
+
+// reveal показывает ответ; повторение считается неудачным, и вопрос вернется на следующий день
+func (h *ReviewCallback) reveal(callback *tgbotapi.CallbackQuery, chat *models.Chat, questionID uint) error {
+	card, err := h.reviewHandler.gradeReview(chat.ID, questionID, models.ReviewQualityRevealed)
+	if errors.Is(err, repository.ErrQuestionChanged) {
+		return h.AnswerCallbackAlert(callback.ID, closedQuestionAlert)
+	}
+	if err := h.AnswerCallbackQuery(callback.ID); err != nil {
+		fmt.Printf("Failed to answer callback query: %v\n", err)
+	}
+	if err != nil {
+		fmt.Printf("Failed to grade review: %v (chat_id: %d, question_id: %d)\n", err, chat.ID, questionID)
+		return h.SendMessage(chat.TelegramID, "Произошла ошибка при обработке команды", nil)
+	}
+
+	question, err := h.questionRepo.GetByID(questionID)
+	if err != nil {
+		fmt.Printf("Failed to get review question: %v (chat_id: %d, question_id: %d)\n", err, chat.ID, questionID)
+		return h.SendMessage(chat.TelegramID, "Произошла ошибка при обработке команды", nil)
+	}
+
+	h.ResolveQuestionMessage(chat.TelegramID, callback.Message.MessageID, question, "Ответ показан: "+question.Answer)
+
+	text := "*Правильный ответ:*\n" + h.EscapeMarkdown(question.Answer)
+	return h.reviewHandler.sendReviewResult(chat, question, card, text)
+}
+
+// finish заканчивает повторение; карточка остается в очереди
+func (h *ReviewCallback) finish(callback *tgbotapi.CallbackQuery, chat *models.Chat, questionID uint) error {
+	err := h.unitOfWork.Do(func(tx repository.Tx) error {
+		return tx.ClearReview(chat.ID, questionID)
+	})
+	if errors.Is(err, repository.ErrQuestionChanged) {
+		return h.AnswerCallbackAlert(callback.ID, closedQuestionAlert)
+	}
+	if err := h.AnswerCallbackQuery(callback.ID); err != nil {
+		fmt.Printf("Failed to answer callback query: %v\n", err)
+	}
+	if err != nil {
+		fmt.Printf("Failed to finish review: %v (chat_id: %d, question_id: %d)\n", err, chat.ID, questionID)
+		return h.SendMessage(chat.TelegramID, "Произошла ошибка при обработке команды", nil)
+	}
+
+	question, err := h.questionRepo.GetByID(questionID)
+	if err == nil {
+		h.ResolveQuestionMessage(chat.TelegramID, callback.Message.MessageID, question, "Повторение закончено")
+	}
+
+	return h.SendMessage(chat.TelegramID, "Повторение закончено\\. Вернуться к нему можно командой /review", nil)
+}
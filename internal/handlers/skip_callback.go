@@ -1,8 +1,10 @@
 package handlers
 
 import (
+	"errors"
 	"fmt"
 	tgbotapi "github.com/go-telegram-bot-api/telegram-bot-api/v5"
+	"qweasley/internal/repository"
 )
 
 // SkipCallback обработчик callback'а "skip"
@@ -11,9 +13,9 @@ type SkipCallback struct {
 }
 
 // NewSkipCallback создает новый обработчик callback'а skip
-func NewSkipCallback(bot *tgbotapi.BotAPI) *SkipCallback {
+func NewSkipCallback(bot *tgbotapi.BotAPI, stores *repository.Stores) *SkipCallback {
 	return &SkipCallback{
-		BaseHandler: NewBaseHandler(bot),
+		BaseHandler: NewBaseHandler(bot, stores),
 	}
 }
 
@@ -24,39 +26,53 @@ func (h *SkipCallback) GetCallbackData() string {
 
 // Handle обрабатывает callback "skip"
 func (h *SkipCallback) Handle(callback *tgbotapi.CallbackQuery) error {
-	// Отвечаем на callback query
-	if err := h.AnswerCallbackQuery(callback.ID); err != nil {
-		fmt.Printf("Failed to answer callback query: %v\n", err)
+	// Получаем вопрос, к которому привязана кнопка
+	questionID, ok := h.QuestionFromCallback(callback)
+	if !ok {
+		return nil
 	}
 
 	// Получаем чат пользователя
 	chat, err := h.GetOrCreateChat(callback.Message.Chat.ID, &callback.Message.Chat.Title)
 	if err != nil {
+		h.AnswerCallbackQuery(callback.ID)
 		fmt.Printf("Failed to get or create chat in skip callback: %v (chat_id: %d)\n", err, callback.Message.Chat.ID)
 		return h.SendMessage(callback.Message.Chat.ID, "Произошла ошибка при обработке команды", nil)
 	}
 
-	// Проверяем, есть ли активный вопрос
-	if chat.LastQuestionID == nil {
-		return h.SendMessage(callback.Message.Chat.ID, "Нет активного вопроса для пропуска", nil)
+	// Кнопка под старым сообщением не должна действовать на текущий вопрос
+	if chat.LastQuestionID == nil || *chat.LastQuestionID != questionID {
+		return h.AnswerCallbackAlert(callback.ID, closedQuestionAlert)
+	}
+
+	// Отвечаем на callback query
+	if err := h.AnswerCallbackQuery(callback.ID); err != nil {
+		fmt.Printf("Failed to answer callback query: %v\n", err)
 	}
 
-	// Получаем вопрос по ID из чата
-	question, err := h.questionRepo.GetByID(*chat.LastQuestionID)
+	// Получаем вопрос
+	question, err := h.questionRepo.GetByID(questionID)
 	if err != nil {
-		fmt.Printf("Failed to get question in skip callback: %v (chat_id: %d, question_id: %d)\n", err, chat.ID, *chat.LastQuestionID)
+		fmt.Printf("Failed to get question in skip callback: %v (chat_id: %d, question_id: %d)\n", err, chat.ID, questionID)
 		return h.SendMessage(callback.Message.Chat.ID, "Произошла ошибка при обработке команды", nil)
 	}
 
 	// Обрабатываем реакцию "пропустить"
 	err = h.ProcessSkipReaction(chat.ID, question.ID)
+	if errors.Is(err, repository.ErrQuestionChanged) {
+		// Повторное нажатие: вопрос уже обработан
+		return nil
+	}
 	if err != nil {
 		fmt.Printf("Failed to process skip reaction: %v (chat_id: %d, question_id: %d)\n", err, chat.ID, question.ID)
 		return h.SendMessage(callback.Message.Chat.ID, "Произошла ошибка при обработке команды", nil)
 	}
 
+	// Убираем кнопки под пропущенным вопросом
+	h.ResolveQuestionMessage(callback.Message.Chat.ID, callback.Message.MessageID, question, "Вопрос пропущен")
+
 	// Получаем следующий вопрос
-	nextQuestion, keyboard, err := h.GetNextQuestion(callback.Message.Chat.ID, &callback.Message.Chat.Title)
+	chat, nextQuestion, keyboard, err := h.GetNextQuestion(callback.Message.Chat.ID, &callback.Message.Chat.Title)
 	if err != nil {
 		switch err.Error() {
 		case "insufficient balance":
@@ -70,5 +86,5 @@ func (h *SkipCallback) Handle(callback *tgbotapi.CallbackQuery) error {
 	}
 
 	// Отправляем следующий вопрос (с картинкой или без)
-	return h.SendQuestion(callback.Message.Chat.ID, nextQuestion, keyboard)
+	return h.SendQuestion(chat, nextQuestion, keyboard)
 }
@@ -3,17 +3,20 @@ package handlers
 import (
 	"fmt"
 	tgbotapi "github.com/go-telegram-bot-api/telegram-bot-api/v5"
+	"qweasley/internal/repository"
 )
 
 // StartHandler обработчик команды /start
 type StartHandler struct {
 	*BaseHandler
+	transferHandler *TransferHandler
 }
 
 // NewStartHandler создает новый обработчик команды start
-func NewStartHandler(bot *tgbotapi.BotAPI) *StartHandler {
+func NewStartHandler(bot *tgbotapi.BotAPI, stores *repository.Stores, transferHandler *TransferHandler) *StartHandler {
 	return &StartHandler{
-		BaseHandler: NewBaseHandler(bot),
+		BaseHandler:     NewBaseHandler(bot, stores),
+		transferHandler: transferHandler,
 	}
 }
 
@@ -24,8 +27,13 @@ func (h *StartHandler) GetCommand() string {
 
 // Handle обрабатывает команду /start
 func (h *StartHandler) Handle(message *tgbotapi.Message) error {
+	// Ссылка вида /start tr_... погашает перевод монет
+	if payload := message.CommandArguments(); IsTransferCode(payload) {
+		return h.transferHandler.Redeem(message, payload)
+	}
+
 	// Обрабатываем общую логику команды start
-	_, question, err := h.ProcessStartCommand(message.Chat.ID, &message.Chat.Title)
+	chat, question, err := h.ProcessStartCommand(message.Chat.ID, &message.Chat.Title)
 	if err != nil {
 		switch err.Error() {
 		case "insufficient balance":
@@ -39,8 +47,8 @@ func (h *StartHandler) Handle(message *tgbotapi.Message) error {
 	}
 
 	// Создаем клавиатуру
-	keyboard := h.CreateQuestionKeyboard()
+	keyboard := h.CreateQuestionKeyboard(message.Chat.ID, question.ID)
 
 	// Отправляем вопрос (с картинкой или без)
-	return h.SendQuestion(message.Chat.ID, question, keyboard)
+	return h.SendQuestion(chat, question, keyboard)
 }
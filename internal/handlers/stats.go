@@ -0,0 +1,95 @@
+package handlers
+
+import (
+	"fmt"
+	tgbotapi "github.com/go-telegram-bot-api/telegram-bot-api/v5"
+	"qweasley/internal/repository"
+	"strings"
+	"time"
+)
+
+// StatsHandler обработчик команды /stats
+type StatsHandler struct {
+	*BaseHandler
+}
+
+// NewStatsHandler создает новый обработчик команды stats
+func NewStatsHandler(bot *tgbotapi.BotAPI, stores *repository.Stores) *StatsHandler {
+	return &StatsHandler{
+		BaseHandler: NewBaseHandler(bot, stores),
+	}
+}
+
+// GetCommand возвращает название команды
+func (h *StatsHandler) GetCommand() string {
+	return "stats"
+}
+
+// Handle обрабатывает команду /stats: личная статистика чата в сравнении со средней по всем чатам
+func (h *StatsHandler) Handle(message *tgbotapi.Message) error {
+	chat, err := h.GetOrCreateChat(message.Chat.ID, &message.Chat.Title)
+	if err != nil {
+		fmt.Printf("Failed to get or create chat: %v\n", err)
+		return h.SendMessage(message.Chat.ID, "Произошла ошибка при получении статистики", nil)
+	}
+
+	stats, err := h.reactionRepo.GetStats(chat.ID)
+	if err != nil {
+		fmt.Printf("Failed to get stats: %v (chat_id: %d)\n", err, chat.ID)
+		return h.SendMessage(message.Chat.ID, "Произошла ошибка при получении статистики", nil)
+	}
+
+	if stats.Answered+stats.Revealed+stats.Skipped == 0 {
+		return h.SendMessage(message.Chat.ID, "Статистики пока нет: ответьте хотя бы на один вопрос командой /start\\.", nil)
+	}
+
+	global, err := h.reactionRepo.GetGlobalStats()
+	if err != nil {
+		fmt.Printf("Failed to get global stats: %v (chat_id: %d)\n", err, chat.ID)
+		return h.SendMessage(message.Chat.ID, "Произошла ошибка при получении статистики", nil)
+	}
+
+	days, err := h.reactionRepo.GetAnswerDays(chat.ID)
+	if err != nil {
+		fmt.Printf("Failed to get answer days: %v (chat_id: %d)\n", err, chat.ID)
+		return h.SendMessage(message.Chat.ID, "Произошла ошибка при получении статистики", nil)
+	}
+	streak := repository.CountStreak(days, time.Now())
+
+	lines := []string{
+		fmt.Sprintf("Отвечено: %d", stats.Answered),
+		fmt.Sprintf("Показано ответов: %d", stats.Revealed),
+		fmt.Sprintf("Пропущено: %d", stats.Skipped),
+		fmt.Sprintf("Точность: %.0f%% (в среднем %.0f%%)", stats.Accuracy()*100, global.Accuracy()*100),
+		fmt.Sprintf("Серия: %d дн. подряд, лучшая — %d дн.", streak.Current, streak.Best),
+	}
+	if stats.AnswerTime > 0 {
+		line := "Среднее время ответа: " + formatDuration(stats.AnswerTime)
+		if global.AnswerTime > 0 {
+			line += " (в среднем " + formatDuration(global.AnswerTime) + ")"
+		}
+		lines = append(lines, line)
+	}
+	lines = append(lines, fmt.Sprintf("В среднем на чат отвечено: %.1f", global.AnsweredPerChat()))
+
+	text := "*Ваша статистика:*\n\n" + h.EscapeMarkdown(strings.Join(lines, "\n"))
+	return h.SendMessage(message.Chat.ID, text, nil)
+}
+
+// formatDuration форматирует длительность с точностью до секунды, например "1 мин 5 с"
+func formatDuration(d time.Duration) string {
+	d = d.Round(time.Second)
+	hours, minutes, seconds := int(d.Hours()), int(d.Minutes())%60, int(d.Seconds())%60
+
+	var parts []string
+	if hours > 0 {
+		parts = append(parts, fmt.Sprintf("%d ч", hours))
+	}
+	if minutes > 0 {
+		parts = append(parts, fmt.Sprintf("%d мин", minutes))
+	}
+	if seconds > 0 || len(parts) == 0 {
+		parts = append(parts, fmt.Sprintf("%d с", seconds))
+	}
+	return strings.Join(parts, " ")
+}
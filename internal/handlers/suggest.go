@@ -0,0 +1,255 @@
+package handlers
+
+import (
+	"fmt"
+	tgbotapi "github.com/go-telegram-bot-api/telegram-bot-api/v5"
+	"qweasley/internal/models"
+	"qweasley/internal/repository"
+	"strings"
+	"time"
+)
+
+// Шаги предложения вопроса
+const (
+	suggestionStepText    = "text"
+	suggestionStepAnswer  = "answer"
+	suggestionStepComment = "comment"
+	suggestionStepHints   = "hints"
+	suggestionStepPhoto   = "photo"
+)
+
+// suggestionTimeout время, за которое нужно пройти очередной шаг предложения
+const suggestionTimeout = 30 * time.Minute
+
+// SuggestHandler обработчик команды /suggest
+type SuggestHandler struct {
+	*BaseHandler
+}
+
+// NewSuggestHandler создает новый обработчик команды suggest
+func NewSuggestHandler(bot *tgbotapi.BotAPI, stores *repository.Stores) *SuggestHandler {
+	return &SuggestHandler{
+		BaseHandler: NewBaseHandler(bot, stores),
+	}
+}
+
+// GetCommand возвращает название команды
+func (h *SuggestHandler) GetCommand() string {
+	return "suggest"
+}
+
+// Handle обрабатывает команду /suggest
+func (h *SuggestHandler) Handle(message *tgbotapi.Message) error {
+	// Получаем или создаем чат пользователя
+	chat, err := h.GetOrCreateChat(message.Chat.ID, &message.Chat.Title)
+	if err != nil {
+		fmt.Printf("Failed to get or create chat: %v\n", err)
+		return h.SendMessage(message.Chat.ID, "Произошла ошибка при обработке команды", nil)
+	}
+
+	// Начинаем предложение с текста вопроса
+	err = h.chatRepo.SetSuggestionStep(chat.ID, nil, suggestionStepText, suggestionTimeout)
+	if err != nil {
+		fmt.Printf("Failed to set suggestion step: %v (chat_id: %d)\n", err, chat.ID)
+		return h.SendMessage(message.Chat.ID, "Произошла ошибка при обработке команды", nil)
+	}
+
+	text := "Напишите текст вопроса следующим сообщением\\. Если вопрос пройдет модерацию, он будет опубликован, а на ваш счет поступит 10 монет\\."
+	return h.SendMessage(message.Chat.ID, text, nil)
+}
+
+// HandleSuggestionMessage обрабатывает сообщение на очередном шаге предложения вопроса
+func (h *SuggestHandler) HandleSuggestionMessage(message *tgbotapi.Message) error {
+	chat, err := h.GetOrCreateChat(message.Chat.ID, &message.Chat.Title)
+	if err != nil {
+		fmt.Printf("Failed to get or create chat: %v\n", err)
+		return h.SendMessage(message.Chat.ID, "Произошла ошибка при обработке сообщения", nil)
+	}
+
+	if !chat.IsSuggesting() {
+		return nil
+	}
+
+	text := strings.TrimSpace(message.Text)
+
+	// Черновик появляется после текста вопроса; без него остальные шаги продолжать нечем
+	if *chat.SuggestionStep != suggestionStepText && chat.SuggestionDraft == nil {
+		return h.restartSuggestion(chat)
+	}
+
+	switch *chat.SuggestionStep {
+	case suggestionStepText:
+		if len([]rune(text)) < 10 {
+			return h.SendMessage(message.Chat.ID, "Слишком короткий вопрос\\. Попробуйте сформулировать подробнее\\.", nil)
+		}
+
+		draft := &models.SuggestionDraft{Text: text}
+		if err := h.chatRepo.SetSuggestionStep(chat.ID, draft, suggestionStepAnswer, suggestionTimeout); err != nil {
+			fmt.Printf("Failed to set suggestion step: %v (chat_id: %d)\n", err, chat.ID)
+			return h.SendMessage(message.Chat.ID, "Произошла ошибка при сохранении вопроса", nil)
+		}
+		return h.SendMessage(message.Chat.ID, "Отлично\\! Теперь напишите правильный ответ\\.", nil)
+
+	case suggestionStepAnswer:
+		if text == "" {
+			return h.SendMessage(message.Chat.ID, "Ответ должен быть текстом\\. Попробуйте еще раз\\.", nil)
+		}
+
+		draft := *chat.SuggestionDraft
+		draft.Answer = text
+		if err := h.chatRepo.SetSuggestionStep(chat.ID, &draft, suggestionStepComment, suggestionTimeout); err != nil {
+			fmt.Printf("Failed to set suggestion step: %v (chat_id: %d)\n", err, chat.ID)
+			return h.SendMessage(message.Chat.ID, "Произошла ошибка при сохранении вопроса", nil)
+		}
+		return h.SendMessage(message.Chat.ID, "Напишите комментарий к ответу \\(он будет показан после ответа\\) или нажмите «Пропустить»\\.", h.CreateSuggestSkipKeyboard())
+
+	case suggestionStepComment:
+		if text == "" {
+			return h.SendMessage(message.Chat.ID, "Комментарий должен быть текстом\\. Попробуйте еще раз или нажмите «Пропустить»\\.", h.CreateSuggestSkipKeyboard())
+		}
+
+		draft := *chat.SuggestionDraft
+		draft.Comment = &text
+		return h.askForHints(chat, &draft)
+
+	case suggestionStepHints:
+		// Каждая непустая строка — отдельная подсказка
+		var hints []string
+		for _, line := range strings.Split(text, "\n") {
+			if line = strings.TrimSpace(line); line != "" {
+				hints = append(hints, line)
+			}
+		}
+		if len(hints) == 0 {
+			return h.SendMessage(message.Chat.ID, "Подсказки должны быть текстом\\. Попробуйте еще раз или нажмите «Пропустить»\\.", h.CreateSuggestSkipKeyboard())
+		}
+
+		draft := *chat.SuggestionDraft
+		draft.Hints = hints
+		return h.askForPhoto(chat, &draft)
+
+	case suggestionStepPhoto:
+		if len(message.Photo) == 0 {
+			return h.SendMessage(message.Chat.ID, "Пришлите картинку или нажмите «Пропустить»\\.", h.CreateSuggestSkipKeyboard())
+		}
+
+		// Берем самый большой размер картинки
+		photo := message.Photo[len(message.Photo)-1]
+		draft := *chat.SuggestionDraft
+		draft.PictureFileID = &photo.FileID
+		return h.completeSuggestion(chat, &draft)
+	}
+
+	return nil
+}
+
+// SkipStep пропускает необязательный шаг предложения вопроса
+func (h *SuggestHandler) SkipStep(chatID int64, title *string) error {
+	chat, err := h.GetOrCreateChat(chatID, title)
+	if err != nil {
+		fmt.Printf("Failed to get or create chat: %v\n", err)
+		return h.SendMessage(chatID, "Произошла ошибка при обработке команды", nil)
+	}
+
+	if !chat.IsSuggesting() {
+		return h.SendMessage(chatID, "Нет вопроса, который вы сейчас предлагаете\\. Начните заново командой /suggest", nil)
+	}
+
+	if chat.SuggestionDraft == nil {
+		return h.restartSuggestion(chat)
+	}
+
+	switch *chat.SuggestionStep {
+	case suggestionStepComment:
+		return h.askForHints(chat, chat.SuggestionDraft)
+	case suggestionStepHints:
+		return h.askForPhoto(chat, chat.SuggestionDraft)
+	case suggestionStepPhoto:
+		return h.completeSuggestion(chat, chat.SuggestionDraft)
+	}
+
+	return h.SendMessage(chatID, "Этот шаг нельзя пропустить\\.", nil)
+}
+
+// askForHints сохраняет черновик и переводит предложение на шаг с подсказками
+func (h *SuggestHandler) askForHints(chat *models.Chat, draft *models.SuggestionDraft) error {
+	if err := h.chatRepo.SetSuggestionStep(chat.ID, draft, suggestionStepHints, suggestionTimeout); err != nil {
+		fmt.Printf("Failed to set suggestion step: %v (chat_id: %d)\n", err, chat.ID)
+		return h.SendMessage(chat.TelegramID, "Произошла ошибка при сохранении вопроса", nil)
+	}
+	text := "Напишите подсказки к вопросу, каждую с новой строки, от самой общей к самой точной\\. Без них игроки получат подсказки по буквам ответа\\. Или нажмите «Пропустить»\\."
+	return h.SendMessage(chat.TelegramID, text, h.CreateSuggestSkipKeyboard())
+}
+
+// askForPhoto сохраняет черновик и переводит предложение на шаг с картинкой
+func (h *SuggestHandler) askForPhoto(chat *models.Chat, draft *models.SuggestionDraft) error {
+	if err := h.chatRepo.SetSuggestionStep(chat.ID, draft, suggestionStepPhoto, suggestionTimeout); err != nil {
+		fmt.Printf("Failed to set suggestion step: %v (chat_id: %d)\n", err, chat.ID)
+		return h.SendMessage(chat.TelegramID, "Произошла ошибка при сохранении вопроса", nil)
+	}
+	return h.SendMessage(chat.TelegramID, "Если к вопросу нужна картинка, пришлите ее\\. Иначе нажмите «Пропустить»\\.", h.CreateSuggestSkipKeyboard())
+}
+
+// completeSuggestion создает вопрос из черновика и отправляет его на модерацию.
+// До этого шага вопроса в базе нет, поэтому брошенное предложение не оставляет пустых вопросов
+func (h *SuggestHandler) completeSuggestion(chat *models.Chat, draft *models.SuggestionDraft) error {
+	question := draft.Question(chat.ID)
+	if err := h.questionRepo.Create(question); err != nil {
+		fmt.Printf("Failed to create suggested question: %v (chat_id: %d)\n", err, chat.ID)
+		return h.SendMessage(chat.TelegramID, "Произошла ошибка при сохранении вопроса", nil)
+	}
+
+	if err := h.chatRepo.ClearSuggestion(chat.ID); err != nil {
+		fmt.Printf("Failed to clear suggestion: %v (chat_id: %d)\n", err, chat.ID)
+	}
+
+	// Перечитываем вопрос, чтобы карточка модерации получила автора и картинку
+	stored, err := h.questionRepo.GetByID(question.ID)
+	if err != nil {
+		fmt.Printf("Failed to get suggested question: %v (question_id: %d)\n", err, question.ID)
+		return h.SendMessage(chat.TelegramID, "Произошла ошибка при отправке вопроса на модерацию", nil)
+	}
+
+	if err := h.SendModerationCard(stored); err != nil {
+		fmt.Printf("Failed to send moderation card: %v (question_id: %d)\n", err, stored.ID)
+	}
+
+	return h.SendMessage(chat.TelegramID, "Спасибо\\! Вопрос отправлен на модерацию\\. Мы сообщим вам о решении\\.", nil)
+}
+
+// restartSuggestion сбрасывает предложение без черновика и просит начать заново
+func (h *SuggestHandler) restartSuggestion(chat *models.Chat) error {
+	if err := h.chatRepo.ClearSuggestion(chat.ID); err != nil {
+		fmt.Printf("Failed to clear suggestion: %v (chat_id: %d)\n", err, chat.ID)
+	}
+	return h.SendMessage(chat.TelegramID, "Предложение вопроса прервано\\. Начните заново командой /suggest", nil)
+}
+
+// SuggestSkipCallback обработчик callback'а "suggest_skip"
+type SuggestSkipCallback struct {
+	*BaseHandler
+	suggestHandler *SuggestHandler
+}
+
+// NewSuggestSkipCallback создает новый обработчик callback'а suggest_skip
+func NewSuggestSkipCallback(suggestHandler *SuggestHandler, bot *tgbotapi.BotAPI, stores *repository.Stores) *SuggestSkipCallback {
+	return &SuggestSkipCallback{
+		BaseHandler:    NewBaseHandler(bot, stores),
+		suggestHandler: suggestHandler,
+	}
+}
+
+// GetCallbackData возвращает данные callback'а
+func (h *SuggestSkipCallback) GetCallbackData() string {
+	return "suggest_skip"
+}
+
+// Handle обрабатывает callback "suggest_skip"
+func (h *SuggestSkipCallback) Handle(callback *tgbotapi.CallbackQuery) error {
+	// Отвечаем на callback query
+	if err := h.AnswerCallbackQuery(callback.ID); err != nil {
+		fmt.Printf("Failed to answer callback query: %v\n", err)
+	}
+
+	return h.suggestHandler.SkipStep(callback.Message.Chat.ID, &callback.Message.Chat.Title)
+}
@@ -0,0 +1,168 @@
+package handlers
+
+import (
+	"errors"
+	"fmt"
+	tgbotapi "github.com/go-telegram-bot-api/telegram-bot-api/v5"
+	"gorm.io/gorm"
+	"qweasley/internal/repository"
+	"strconv"
+	"strings"
+	"unicode/utf8"
+)
+
+// Ограничения на темы: длина имени (в символах) и число вопросов в одной команде
+const (
+	tagNameMaxLength = 32
+	tagBatchMaxSize  = 1000
+)
+
+// TagHandler обработчик команд /tag и /untag (только для администратора):
+// массово относит вопросы к теме или убирает их из нее
+type TagHandler struct {
+	*BaseHandler
+	remove bool
+}
+
+// NewTagHandler создает новый обработчик команды tag
+func NewTagHandler(bot *tgbotapi.BotAPI, stores *repository.Stores) *TagHandler {
+	return &TagHandler{
+		BaseHandler: NewBaseHandler(bot, stores),
+	}
+}
+
+// NewUntagHandler создает новый обработчик команды untag
+func NewUntagHandler(bot *tgbotapi.BotAPI, stores *repository.Stores) *TagHandler {
+	return &TagHandler{
+		BaseHandler: NewBaseHandler(bot, stores),
+		remove:      true,
+	}
+}
+
+// GetCommand возвращает название команды
+func (h *TagHandler) GetCommand() string {
+	if h.remove {
+		return "untag"
+	}
+	return "tag"
+}
+
+// Handle обрабатывает команду вида "/tag 12 15 20-25 Название темы"
+func (h *TagHandler) Handle(message *tgbotapi.Message) error {
+	if !h.IsAdminChat(message.Chat.ID) {
+		return nil
+	}
+
+	questionIDs, name, err := parseTagArguments(message.CommandArguments())
+	if err != nil {
+		usage := fmt.Sprintf("%s\n\nИспользование: /%s 12 15 20\\-25 Название темы", h.EscapeMarkdown(err.Error()), h.GetCommand())
+		return h.SendMessage(message.Chat.ID, usage, nil)
+	}
+
+	if h.remove {
+		return h.untag(message.Chat.ID, name, questionIDs)
+	}
+	return h.tag(message.Chat.ID, name, questionIDs)
+}
+
+// tag относит вопросы к теме, создавая тему при необходимости
+func (h *TagHandler) tag(adminChatID int64, name string, questionIDs []uint) error {
+	tag, err := h.tagRepo.GetOrCreate(name)
+	if err != nil {
+		fmt.Printf("Failed to get or create tag: %v (name: %s)\n", err, name)
+		return h.SendMessage(adminChatID, "Произошла ошибка при создании темы", nil)
+	}
+
+	added, err := h.tagRepo.TagQuestions(tag.ID, questionIDs)
+	if err != nil {
+		fmt.Printf("Failed to tag questions: %v (tag_id: %d)\n", err, tag.ID)
+		return h.SendMessage(adminChatID, "Произошла ошибка при добавлении вопросов в тему", nil)
+	}
+
+	text := fmt.Sprintf("Тема *%s*: добавлено вопросов — %d из %d\\.", h.EscapeMarkdown(tag.Name), added, len(questionIDs))
+	if added < len(questionIDs) {
+		text += "\n_Остальные уже в теме или не существуют\\._"
+	}
+	return h.SendMessage(adminChatID, text, nil)
+}
+
+// untag убирает вопросы из темы
+func (h *TagHandler) untag(adminChatID int64, name string, questionIDs []uint) error {
+	tag, err := h.tagRepo.GetByName(name)
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		return h.SendMessage(adminChatID, fmt.Sprintf("Темы *%s* нет\\.", h.EscapeMarkdown(name)), nil)
+	}
+	if err != nil {
+		fmt.Printf("Failed to get tag: %v (name: %s)\n", err, name)
+		return h.SendMessage(adminChatID, "Произошла ошибка при получении темы", nil)
+	}
+
+	removed, err := h.tagRepo.UntagQuestions(tag.ID, questionIDs)
+	if err != nil {
+		fmt.Printf("Failed to untag questions: %v (tag_id: %d)\n", err, tag.ID)
+		return h.SendMessage(adminChatID, "Произошла ошибка при удалении вопросов из темы", nil)
+	}
+
+	text := fmt.Sprintf("Тема *%s*: убрано вопросов — %d из %d\\.", h.EscapeMarkdown(tag.Name), removed, len(questionIDs))
+	return h.SendMessage(adminChatID, text, nil)
+}
+
+// parseTagArguments разбирает аргументы: сначала ID вопросов и диапазоны вида 20-25, затем название темы
+func parseTagArguments(args string) ([]uint, string, error) {
+	fields := strings.Fields(args)
+
+	seen := make(map[uint]bool)
+	var questionIDs []uint
+	i := 0
+	for ; i < len(fields); i++ {
+		from, to, ok := parseIDRange(fields[i])
+		if !ok {
+			break
+		}
+		if to < from || to-from >= tagBatchMaxSize {
+			return nil, "", fmt.Errorf("некорректный диапазон: %s", fields[i])
+		}
+
+		for id := from; id <= to; id++ {
+			if !seen[id] {
+				seen[id] = true
+				questionIDs = append(questionIDs, id)
+			}
+		}
+		if len(questionIDs) > tagBatchMaxSize {
+			return nil, "", fmt.Errorf("за раз можно указать не больше %d вопросов", tagBatchMaxSize)
+		}
+	}
+
+	if len(questionIDs) == 0 {
+		return nil, "", fmt.Errorf("не указаны номера вопросов")
+	}
+
+	name := strings.Join(fields[i:], " ")
+	if name == "" {
+		return nil, "", fmt.Errorf("не указано название темы")
+	}
+	if utf8.RuneCountInString(name) > tagNameMaxLength {
+		return nil, "", fmt.Errorf("название темы должно быть не длиннее %d символов", tagNameMaxLength)
+	}
+
+	return questionIDs, name, nil
+}
+
+// parseIDRange разбирает номер вопроса "12" или диапазон "20-25"
+func parseIDRange(field string) (uint, uint, bool) {
+	fromText, toText, isRange := strings.Cut(field, "-")
+	from, err := strconv.ParseUint(fromText, 10, 32)
+	if err != nil {
+		return 0, 0, false
+	}
+	if !isRange {
+		return uint(from), uint(from), true
+	}
+
+	to, err := strconv.ParseUint(toText, 10, 32)
+	if err != nil {
+		return 0, 0, false
+	}
+	return uint(from), uint(to), true
+}
@@ -3,19 +3,28 @@ package handlers
 import (
 	"fmt"
 	tgbotapi "github.com/go-telegram-bot-api/telegram-bot-api/v5"
+	"qweasley/internal/repository"
 )
 
 // TextResponseHandler обработчик текстовых ответов на вопросы
 type TextResponseHandler struct {
 	*BaseHandler
-	feedbackHandler *FeedbackHandler
+	feedbackHandler    *FeedbackHandler
+	suggestHandler     *SuggestHandler
+	moderationCallback *ModerationCallback
+	reviewHandler      *ReviewHandler
+	dailyHandler       *DailyHandler
 }
 
 // NewTextResponseHandler создает новый обработчик текстовых ответов
-func NewTextResponseHandler(bot *tgbotapi.BotAPI, feedbackHandler *FeedbackHandler) *TextResponseHandler {
+func NewTextResponseHandler(bot *tgbotapi.BotAPI, stores *repository.Stores, feedbackHandler *FeedbackHandler, suggestHandler *SuggestHandler, moderationCallback *ModerationCallback, reviewHandler *ReviewHandler, dailyHandler *DailyHandler) *TextResponseHandler {
 	return &TextResponseHandler{
-		BaseHandler:     NewBaseHandler(bot),
-		feedbackHandler: feedbackHandler,
+		BaseHandler:        NewBaseHandler(bot, stores),
+		feedbackHandler:    feedbackHandler,
+		suggestHandler:     suggestHandler,
+		moderationCallback: moderationCallback,
+		reviewHandler:      reviewHandler,
+		dailyHandler:       dailyHandler,
 	}
 }
 
@@ -33,7 +42,37 @@ func (h *TextResponseHandler) Handle(message *tgbotapi.Message) error {
 		return h.feedbackHandler.HandleFeedbackMessage(message)
 	}
 
-	// Если не в состоянии обратной связи, обрабатываем как обычный ответ на вопрос
+	// Администратор отвечает на обратную связь
+	if handled, err := h.feedbackHandler.HandleAdminMessage(message, chat); handled {
+		return err
+	}
+
+	// Администратор вводит причину отклонения или правки вопроса
+	if chat.IsModerating() && h.IsAdminChat(message.Chat.ID) {
+		return h.moderationCallback.HandleModerationMessage(message)
+	}
+
+	// Пользователь проходит шаги предложения вопроса
+	if chat.IsSuggesting() {
+		return h.suggestHandler.HandleSuggestionMessage(message)
+	}
+
+	// Вопрос блица, время на который вышло, закрывается в ProcessTextResponse раньше остальных ответов
+	overdue := chat.IsBlitzOverdue()
+
+	// Ответ на вопрос повторения; обычный вопрос, если он задан, важнее
+	if chat.IsReviewing() && !chat.IsWaitingAnswer() && !overdue {
+		return h.reviewHandler.HandleAnswer(message, chat)
+	}
+
+	// Ответ на вопрос дня, если обычный вопрос не задан
+	if !chat.IsWaitingAnswer() && !overdue {
+		if handled, err := h.dailyHandler.HandleAnswer(message, chat); handled {
+			return err
+		}
+	}
+
+	// Иначе обрабатываем как обычный ответ на вопрос
 	responseText, keyboard, photoURL, err := h.ProcessTextResponse(message)
 	if err != nil {
 		fmt.Printf("Failed to process text response: %v (chat_id: %d)\n", err, message.Chat.ID)
@@ -0,0 +1,163 @@
+package handlers
+
+import (
+	"fmt"
+	tgbotapi "github.com/go-telegram-bot-api/telegram-bot-api/v5"
+	"qweasley/internal/models"
+	"qweasley/internal/repository"
+	"strings"
+	"time"
+	"unicode/utf8"
+)
+
+// Размер таблицы лидеров и минимум закрытых вопросов для таблицы по точности
+const (
+	leaderboardSize        = 10
+	leaderboardMinResolved = 5
+)
+
+// displayNameMaxLength максимальная длина имени в таблице лидеров (в символах)
+const displayNameMaxLength = 32
+
+// leaderboardPeriodTitles заголовки периодов таблицы лидеров
+var leaderboardPeriodTitles = map[models.LeaderboardPeriod]string{
+	models.LeaderboardDay:     "за сегодня",
+	models.LeaderboardWeek:    "за неделю",
+	models.LeaderboardAllTime: "за все время",
+}
+
+// topUsage подсказка по аргументам команды /top
+const topUsage = "Использование:\n" +
+	"/top \\[day\\|week\\|all\\] \\[accuracy\\] — таблица лидеров\n" +
+	"/top name Имя — имя в таблице, /top name — сбросить имя\n" +
+	"/top hide — не участвовать в таблице, /top show — вернуться"
+
+// TopHandler обработчик команды /top
+type TopHandler struct {
+	*BaseHandler
+	leaderboardRepo repository.LeaderboardStore
+}
+
+// NewTopHandler создает новый обработчик команды top
+func NewTopHandler(bot *tgbotapi.BotAPI, stores *repository.Stores) *TopHandler {
+	return &TopHandler{
+		BaseHandler:     NewBaseHandler(bot, stores),
+		leaderboardRepo: stores.Leaderboard,
+	}
+}
+
+// GetCommand возвращает название команды
+func (h *TopHandler) GetCommand() string {
+	return "top"
+}
+
+// Handle обрабатывает команду /top: таблицы лидеров и настройки участия в них
+func (h *TopHandler) Handle(message *tgbotapi.Message) error {
+	chat, err := h.GetOrCreateChat(message.Chat.ID, &message.Chat.Title)
+	if err != nil {
+		fmt.Printf("Failed to get or create chat: %v\n", err)
+		return h.SendMessage(message.Chat.ID, "Произошла ошибка при обработке команды", nil)
+	}
+
+	args := strings.Fields(message.CommandArguments())
+	if len(args) > 0 {
+		switch strings.ToLower(args[0]) {
+		case "name":
+			name := strings.TrimSpace(strings.TrimPrefix(strings.TrimSpace(message.CommandArguments()), args[0]))
+			return h.setDisplayName(message.Chat.ID, chat, name)
+		case "hide", "show":
+			return h.setHidden(message.Chat.ID, chat, strings.ToLower(args[0]) == "hide")
+		}
+	}
+
+	period, order := models.LeaderboardWeek, repository.LeaderboardByAnswers
+	for _, arg := range args {
+		switch strings.ToLower(arg) {
+		case "day", "week", "all":
+			period = models.LeaderboardPeriod(strings.ToLower(arg))
+		case "accuracy":
+			order = repository.LeaderboardByAccuracy
+		default:
+			return h.SendMessage(message.Chat.ID, topUsage, nil)
+		}
+	}
+
+	return h.sendLeaderboard(message.Chat.ID, chat, period, order)
+}
+
+// sendLeaderboard отправляет таблицу лидеров за период
+func (h *TopHandler) sendLeaderboard(telegramID int64, chat *models.Chat, period models.LeaderboardPeriod, order repository.LeaderboardOrder) error {
+	minResolved := 0
+	if order == repository.LeaderboardByAccuracy {
+		minResolved = leaderboardMinResolved
+	}
+
+	scores, err := h.leaderboardRepo.GetTop(period, period.Start(time.Now()), order, minResolved, leaderboardSize)
+	if err != nil {
+		fmt.Printf("Failed to get leaderboard: %v (chat_id: %d, period: %s)\n", err, chat.ID, period)
+		return h.SendMessage(telegramID, "Произошла ошибка при получении таблицы лидеров", nil)
+	}
+
+	title := "Больше всего правильных ответов " + leaderboardPeriodTitles[period]
+	if order == repository.LeaderboardByAccuracy {
+		title = "Самые точные ответы с учетом сложности " + leaderboardPeriodTitles[period]
+	}
+
+	if len(scores) == 0 {
+		text := fmt.Sprintf("*%s*\n\nПока никого нет\\.", h.EscapeMarkdown(title))
+		return h.SendMessage(telegramID, text, nil)
+	}
+
+	lines := make([]string, 0, len(scores))
+	for i, score := range scores {
+		value := fmt.Sprintf("%d", score.Answered)
+		if order == repository.LeaderboardByAccuracy {
+			value = fmt.Sprintf("%.0f%% из %d", score.Accuracy()*100, score.Answered+score.Revealed)
+		}
+
+		line := h.EscapeMarkdown(fmt.Sprintf("%d. %s — %s", i+1, score.Chat.LeaderboardName(), value))
+		if score.ChatID == chat.ID {
+			line = "*" + line + "*"
+		}
+		lines = append(lines, line)
+	}
+
+	text := fmt.Sprintf("*%s*\n\n%s", h.EscapeMarkdown(title), strings.Join(lines, "\n"))
+	if chat.LeaderboardHidden {
+		text += "\n\n_Ваш чат скрыт из таблицы\\. Вернуться: /top show_"
+	}
+	return h.SendMessage(telegramID, text, nil)
+}
+
+// setDisplayName задает имя чата в таблице лидеров; пустое имя возвращает название чата
+func (h *TopHandler) setDisplayName(telegramID int64, chat *models.Chat, name string) error {
+	var displayName *string
+	if name != "" {
+		if strings.ContainsAny(name, "\n\r") || utf8.RuneCountInString(name) > displayNameMaxLength {
+			return h.SendMessage(telegramID, fmt.Sprintf("Имя должно быть одной строкой не длиннее %d символов\\.", displayNameMaxLength), nil)
+		}
+		displayName = &name
+	}
+
+	if err := h.chatRepo.SetDisplayName(chat.ID, displayName); err != nil {
+		fmt.Printf("Failed to set display name: %v (chat_id: %d)\n", err, chat.ID)
+		return h.SendMessage(telegramID, "Произошла ошибка при обработке команды", nil)
+	}
+
+	chat.DisplayName = displayName
+	text := fmt.Sprintf("Теперь в таблице лидеров вы — *%s*\\.", h.EscapeMarkdown(chat.LeaderboardName()))
+	return h.SendMessage(telegramID, text, nil)
+}
+
+// setHidden скрывает чат из таблицы лидеров или возвращает его
+func (h *TopHandler) setHidden(telegramID int64, chat *models.Chat, hidden bool) error {
+	if err := h.chatRepo.SetLeaderboardHidden(chat.ID, hidden); err != nil {
+		fmt.Printf("Failed to set leaderboard visibility: %v (chat_id: %d)\n", err, chat.ID)
+		return h.SendMessage(telegramID, "Произошла ошибка при обработке команды", nil)
+	}
+
+	if hidden {
+		return h.SendMessage(telegramID, "Ваш чат больше не показывается в таблице лидеров\\. Вернуться: /top show", nil)
+	}
+	return h.SendMessage(telegramID, "Ваш чат снова участвует в таблице лидеров\\.", nil)
+}
@@ -0,0 +1,167 @@
+package handlers
+
+import (
+	"fmt"
+	tgbotapi "github.com/go-telegram-bot-api/telegram-bot-api/v5"
+	"qweasley/internal/models"
+	"qweasley/internal/repository"
+	"strconv"
+	"strings"
+)
+
+// topicsPerRow сколько тем помещается в одну строку клавиатуры
+const topicsPerRow = 2
+
+// TopicsHandler обработчик команды /topics: выбор тем, из которых задаются вопросы
+type TopicsHandler struct {
+	*BaseHandler
+}
+
+// NewTopicsHandler создает новый обработчик команды topics
+func NewTopicsHandler(bot *tgbotapi.BotAPI, stores *repository.Stores) *TopicsHandler {
+	return &TopicsHandler{
+		BaseHandler: NewBaseHandler(bot, stores),
+	}
+}
+
+// GetCommand возвращает название команды
+func (h *TopicsHandler) GetCommand() string {
+	return "topics"
+}
+
+// Handle обрабатывает команду /topics
+func (h *TopicsHandler) Handle(message *tgbotapi.Message) error {
+	chat, err := h.GetOrCreateChat(message.Chat.ID, &message.Chat.Title)
+	if err != nil {
+		fmt.Printf("Failed to get or create chat: %v\n", err)
+		return h.SendMessage(message.Chat.ID, "Произошла ошибка при обработке команды", nil)
+	}
+
+	text, keyboard, err := h.topicsMessage(chat)
+	if err != nil {
+		fmt.Printf("Failed to get topics: %v (chat_id: %d)\n", err, chat.ID)
+		return h.SendMessage(message.Chat.ID, "Произошла ошибка при получении тем", nil)
+	}
+	return h.SendMessage(message.Chat.ID, text, keyboard)
+}
+
+// topicsMessage собирает список тем с отметками выбранных и клавиатуру для переключения
+func (h *TopicsHandler) topicsMessage(chat *models.Chat) (string, *tgbotapi.InlineKeyboardMarkup, error) {
+	tags, err := h.tagRepo.GetAll()
+	if err != nil {
+		return "", nil, err
+	}
+
+	selectedIDs, err := h.tagRepo.GetChatTopics(chat.ID)
+	if err != nil {
+		return "", nil, err
+	}
+	selected := make(map[uint]bool, len(selectedIDs))
+	for _, tagID := range selectedIDs {
+		selected[tagID] = true
+	}
+
+	var rows [][]tgbotapi.InlineKeyboardButton
+	var row []tgbotapi.InlineKeyboardButton
+	var names []string
+	for _, tag := range tags {
+		// Пустые темы не показываем, если только чат их уже не выбрал
+		if tag.Questions == 0 && !selected[tag.ID] {
+			continue
+		}
+
+		label := fmt.Sprintf("%s (%d)", tag.Name, tag.Questions)
+		if selected[tag.ID] {
+			label = "✅ " + label
+			names = append(names, tag.Name)
+		}
+
+		row = append(row, tgbotapi.NewInlineKeyboardButtonData(label, fmt.Sprintf("topic:%d", tag.ID)))
+		if len(row) == topicsPerRow {
+			rows = append(rows, row)
+			row = nil
+		}
+	}
+	if len(row) > 0 {
+		rows = append(rows, row)
+	}
+
+	if len(rows) == 0 {
+		return "Темы вопросов пока не заданы\\. Вопросы выбираются из всех опубликованных\\.", nil, nil
+	}
+
+	rows = append(rows, []tgbotapi.InlineKeyboardButton{
+		tgbotapi.NewInlineKeyboardButtonData("Все темы", "topic:all"),
+	})
+
+	current := "все темы"
+	if len(names) > 0 {
+		current = strings.Join(names, ", ")
+	}
+	text := fmt.Sprintf("*Темы вопросов*\n\nНажмите на тему, чтобы выбрать ее или снять выбор\\. "+
+		"Когда вопросы выбранных тем закончатся, вопросы пойдут из остальных\\.\n\nСейчас: %s", h.EscapeMarkdown(current))
+	return text, &tgbotapi.InlineKeyboardMarkup{InlineKeyboard: rows}, nil
+}
+
+// TopicCallback обработчик нажатий на темы в сообщении /topics
+type TopicCallback struct {
+	*BaseHandler
+	topicsHandler *TopicsHandler
+}
+
+// NewTopicCallback создает новый обработчик выбора темы
+func NewTopicCallback(topicsHandler *TopicsHandler, bot *tgbotapi.BotAPI, stores *repository.Stores) *TopicCallback {
+	return &TopicCallback{
+		BaseHandler:   NewBaseHandler(bot, stores),
+		topicsHandler: topicsHandler,
+	}
+}
+
+// GetCallbackData возвращает данные callback'а
+func (h *TopicCallback) GetCallbackData() string {
+	return "topic"
+}
+
+// Handle обрабатывает callback вида "topic:<id темы>" или "topic:all" и обновляет сообщение со списком тем
+func (h *TopicCallback) Handle(callback *tgbotapi.CallbackQuery) error {
+	if err := h.AnswerCallbackQuery(callback.ID); err != nil {
+		fmt.Printf("Failed to answer callback query: %v\n", err)
+	}
+
+	chatID := callback.Message.Chat.ID
+	chat, err := h.GetOrCreateChat(chatID, &callback.Message.Chat.Title)
+	if err != nil {
+		fmt.Printf("Failed to get or create chat: %v\n", err)
+		return h.SendMessage(chatID, "Произошла ошибка при обработке команды", nil)
+	}
+
+	_, arg, _ := strings.Cut(callback.Data, ":")
+	if arg == "all" {
+		topics, err := h.tagRepo.GetChatTopics(chat.ID)
+		if err == nil && len(topics) == 0 {
+			// Уже выбраны все темы: сообщение не изменится, а такие правки Telegram отклоняет
+			return nil
+		}
+		err = h.tagRepo.ClearChatTopics(chat.ID)
+		if err != nil {
+			fmt.Printf("Failed to clear chat topics: %v (chat_id: %d)\n", err, chat.ID)
+			return h.SendMessage(chatID, "Произошла ошибка при выборе темы", nil)
+		}
+	} else {
+		tagID, err := strconv.ParseUint(arg, 10, 64)
+		if err != nil {
+			return fmt.Errorf("некорректный ID темы: %s", arg)
+		}
+		if _, err := h.tagRepo.ToggleChatTopic(chat.ID, uint(tagID)); err != nil {
+			fmt.Printf("Failed to toggle chat topic: %v (chat_id: %d, tag_id: %d)\n", err, chat.ID, tagID)
+			return h.SendMessage(chatID, "Произошла ошибка при выборе темы", nil)
+		}
+	}
+
+	text, keyboard, err := h.topicsHandler.topicsMessage(chat)
+	if err != nil {
+		fmt.Printf("Failed to get topics: %v (chat_id: %d)\n", err, chat.ID)
+		return h.SendMessage(chatID, "Произошла ошибка при получении тем", nil)
+	}
+	return h.EditMessageText(chatID, callback.Message.MessageID, text, keyboard)
+}
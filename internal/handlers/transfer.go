@@ -0,0 +1,182 @@
+package handlers
+
+import (
+	"crypto/rand"
+	"encoding/base32"
+	"errors"
+	"fmt"
+	tgbotapi "github.com/go-telegram-bot-api/telegram-bot-api/v5"
+	"qweasley/internal/models"
+	"qweasley/internal/repository"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// transferCodePrefix префикс параметра /start для кодов перевода
+const transferCodePrefix = "tr_"
+
+// transferTTL время действия кода перевода
+const transferTTL = 24 * time.Hour
+
+// TransferHandler обработчик команды /transfer
+type TransferHandler struct {
+	*BaseHandler
+	transferRepo repository.TransferStore
+}
+
+// NewTransferHandler создает новый обработчик команды transfer
+func NewTransferHandler(bot *tgbotapi.BotAPI, stores *repository.Stores) *TransferHandler {
+	return &TransferHandler{
+		BaseHandler:  NewBaseHandler(bot, stores),
+		transferRepo: stores.Transfers,
+	}
+}
+
+// GetCommand возвращает название команды
+func (h *TransferHandler) GetCommand() string {
+	return "transfer"
+}
+
+// Handle обрабатывает команду /transfer <количество>
+func (h *TransferHandler) Handle(message *tgbotapi.Message) error {
+	chat, err := h.GetOrCreateChat(message.Chat.ID, &message.Chat.Title)
+	if err != nil {
+		fmt.Printf("Failed to get or create chat: %v\n", err)
+		return h.SendMessage(message.Chat.ID, "Произошла ошибка при обработке команды", nil)
+	}
+
+	amount, err := strconv.Atoi(strings.TrimSpace(message.CommandArguments()))
+	if err != nil || amount <= 0 {
+		return h.SendMessage(message.Chat.ID, "Укажите количество монет для перевода, например: /transfer 5", nil)
+	}
+
+	if amount > chat.Balance {
+		return h.SendMessage(message.Chat.ID, fmt.Sprintf("На вашем счете только %d монет\\.", chat.Balance), nil)
+	}
+
+	code, err := generateTransferCode()
+	if err != nil {
+		fmt.Printf("Failed to generate transfer code: %v\n", err)
+		return h.SendMessage(message.Chat.ID, "Произошла ошибка при создании перевода", nil)
+	}
+
+	transfer := &models.CoinTransfer{
+		Code:       code,
+		Amount:     amount,
+		FromChatID: chat.ID,
+		ExpiresAt:  time.Now().UTC().Add(transferTTL),
+	}
+	err = h.transferRepo.Create(transfer)
+	if errors.Is(err, repository.ErrInsufficientBalance) {
+		// Баланс мог уменьшиться между проверкой и резервированием, например из-за другого кода
+		return h.SendMessage(message.Chat.ID, "На вашем счете недостаточно монет для этого перевода\\.", nil)
+	}
+	if err != nil {
+		fmt.Printf("Failed to create transfer: %v (chat_id: %d)\n", err, chat.ID)
+		return h.SendMessage(message.Chat.ID, "Произошла ошибка при создании перевода", nil)
+	}
+
+	link := fmt.Sprintf("https://t.me/%s?start=%s", h.bot.Self.UserName, code)
+	text := fmt.Sprintf("*Перевод на %d монет создан\\.*\n\nПерешлите получателю ссылку:\n%s\n\nили попросите отправить боту команду:\n`/start %s`\n\nМонеты уже списаны с вашего счета и ждут получателя\\. Ссылка одноразовая и действует 24 часа: если перевод не получат, монеты вернутся\\.",
+		amount, h.EscapeMarkdown(link), code)
+	return h.SendMessage(message.Chat.ID, text, nil)
+}
+
+// IsTransferCode проверяет, является ли параметр /start кодом перевода
+func IsTransferCode(payload string) bool {
+	return strings.HasPrefix(payload, transferCodePrefix)
+}
+
+// Redeem погашает код перевода в пользу чата, отправившего /start <код>
+func (h *TransferHandler) Redeem(message *tgbotapi.Message, code string) error {
+	chat, err := h.GetOrCreateChat(message.Chat.ID, &message.Chat.Title)
+	if err != nil {
+		fmt.Printf("Failed to get or create chat: %v\n", err)
+		return h.SendMessage(message.Chat.ID, "Произошла ошибка при обработке перевода", nil)
+	}
+
+	transfer, err := h.transferRepo.Redeem(code, chat.ID)
+	if err != nil {
+		switch {
+		case errors.Is(err, repository.ErrTransferNotFound):
+			return h.SendMessage(message.Chat.ID, "Перевод не найден\\. Проверьте ссылку\\.", nil)
+		case errors.Is(err, repository.ErrTransferRedeemed):
+			return h.SendMessage(message.Chat.ID, "Этот перевод уже получен\\.", nil)
+		case errors.Is(err, repository.ErrTransferExpired):
+			return h.SendMessage(message.Chat.ID, "Срок действия перевода истек\\.", nil)
+		case errors.Is(err, repository.ErrSelfTransfer):
+			return h.SendMessage(message.Chat.ID, "Нельзя перевести монеты самому себе\\.", nil)
+		default:
+			fmt.Printf("Failed to redeem transfer: %v (chat_id: %d)\n", err, chat.ID)
+			return h.SendMessage(message.Chat.ID, "Произошла ошибка при обработке перевода", nil)
+		}
+	}
+
+	senderText := fmt.Sprintf("Ваш перевод на %d монет получен\\.", transfer.Amount)
+	if name := describeChat(message.Chat); name != "" {
+		senderText = fmt.Sprintf("Ваш перевод на %d монет получен чатом %s\\.", transfer.Amount, h.EscapeMarkdown(name))
+	}
+	if err := h.SendMessage(transfer.FromChat.TelegramID, senderText, nil); err != nil {
+		fmt.Printf("Failed to notify transfer sender: %v (transfer_id: %d)\n", err, transfer.ID)
+	}
+
+	return h.SendMessage(message.Chat.ID, fmt.Sprintf("*Вам переведено %d монет\\!* Отправьте /start, чтобы получить вопрос\\.", transfer.Amount), nil)
+}
+
+// TransferExpireJob обработчик задачи "transfer_expire": возвращает отправителю монеты по истекшим кодам
+type TransferExpireJob struct {
+	*BaseHandler
+	transferRepo repository.TransferStore
+}
+
+// NewTransferExpireJob создает новый обработчик задачи transfer_expire
+func NewTransferExpireJob(bot *tgbotapi.BotAPI, stores *repository.Stores) *TransferExpireJob {
+	return &TransferExpireJob{
+		BaseHandler:  NewBaseHandler(bot, stores),
+		transferRepo: stores.Transfers,
+	}
+}
+
+// GetJobKind возвращает тип задачи
+func (h *TransferExpireJob) GetJobKind() models.JobKind {
+	return models.JobTransferExpire
+}
+
+// Handle обрабатывает задачу "transfer_expire"; полученные и уже возвращенные коды пропускаются
+func (h *TransferExpireJob) Handle(job *models.ScheduledJob) error {
+	if job.ChatID == nil {
+		return nil
+	}
+
+	transfers, err := h.transferRepo.RefundExpired(*job.ChatID, time.Now().UTC())
+	if err != nil {
+		return fmt.Errorf("failed to refund expired transfers: %w", err)
+	}
+	if len(transfers) == 0 {
+		return nil
+	}
+
+	chat, err := h.chatRepo.GetByID(*job.ChatID)
+	if err != nil {
+		return fmt.Errorf("failed to get chat: %w", err)
+	}
+
+	for _, transfer := range transfers {
+		text := fmt.Sprintf("Перевод на %d монет не получили за 24 часа\\. Монеты вернулись на ваш счет\\.", transfer.Amount)
+		if err := h.SendMessage(chat.TelegramID, text, nil); err != nil {
+			fmt.Printf("Failed to notify transfer refund: %v (transfer_id: %d)\n", err, transfer.ID)
+		}
+	}
+	return nil
+}
+
+// generateTransferCode генерирует случайный код перевода
+func generateTransferCode() (string, error) {
+	buf := make([]byte, 10)
+	if _, err := rand.Read(buf); err != nil {
+		return "", err
+	}
+	encoded := base32.StdEncoding.WithPadding(base32.NoPadding).EncodeToString(buf)
+	return transferCodePrefix + strings.ToLower(encoded), nil
+}
@@ -1,6 +1,10 @@
 package models
 
 import (
+	"database/sql/driver"
+	"encoding/json"
+	"fmt"
+	"math"
 	"time"
 
 	"gorm.io/gorm"
@@ -18,9 +22,42 @@ type Chat struct {
 	// Поля для отслеживания ожидания ответа
 	LastQuestionID *uint      `gorm:"column:last_question_id" json:"last_question_id"`
 	ExpiresAt      *time.Time `gorm:"column:expires_at" json:"expires_at"`
+	// BlitzMode чат играет в блиц: короткое время на вопрос и отсчет в сообщении
+	BlitzMode bool `gorm:"column:blitz_mode;default:false;not null" json:"blitz_mode"`
+	// LastMessageID сообщение с текущим вопросом, клавиатуру которого убираем после ответа
+	LastMessageID *int `gorm:"column:last_message_id" json:"last_message_id"`
 
 	// Поле для состояния обратной связи
 	FeedbackExpiresAt *time.Time `gorm:"column:feedback_expires_at" json:"feedback_expires_at"`
+
+	// Поля для пошагового предложения вопроса: вопрос создается только на последнем шаге, до этого он хранится в черновике
+	SuggestionDraft     *SuggestionDraft `gorm:"column:suggestion_draft;type:jsonb" json:"suggestion_draft"`
+	SuggestionStep      *string          `gorm:"column:suggestion_step" json:"suggestion_step"`
+	SuggestionExpiresAt *time.Time       `gorm:"column:suggestion_expires_at" json:"suggestion_expires_at"`
+
+	// Поля для модерации вопросов (используются в чате администратора)
+	ModerationQuestionID *uint      `gorm:"column:moderation_question_id" json:"moderation_question_id"`
+	ModerationAction     *string    `gorm:"column:moderation_action" json:"moderation_action"`
+	ModerationExpiresAt  *time.Time `gorm:"column:moderation_expires_at" json:"moderation_expires_at"`
+
+	// Поля для ответа администратора на обратную связь
+	ReplyFeedbackID        *uint      `gorm:"column:reply_feedback_id" json:"reply_feedback_id"`
+	ReplyFeedbackExpiresAt *time.Time `gorm:"column:reply_feedback_expires_at" json:"reply_feedback_expires_at"`
+
+	// Поля для таблицы лидеров: имя для показа и отказ от участия
+	DisplayName       *string `gorm:"column:display_name" json:"display_name"`
+	LeaderboardHidden bool    `gorm:"column:leaderboard_hidden;default:false;not null" json:"leaderboard_hidden"`
+
+	// Поля для повторения: вопрос, ответа на который ждем в /review
+	ReviewQuestionID *uint      `gorm:"column:review_question_id" json:"review_question_id"`
+	ReviewExpiresAt  *time.Time `gorm:"column:review_expires_at" json:"review_expires_at"`
+
+	// Поля для подбора вопросов по сложности: уровень чата по шкале Эло и выбранный режим
+	Skill          float64        `gorm:"column:skill;default:1500;not null" json:"skill"`
+	DifficultyMode DifficultyMode `gorm:"column:difficulty_mode;type:varchar(16);default:'random';not null" json:"difficulty_mode"`
+
+	// DailyEnabled чат подписан на вопрос дня
+	DailyEnabled bool `gorm:"column:daily_enabled;default:false;not null" json:"daily_enabled"`
 }
 
 // TableName возвращает имя таблицы для Chat
@@ -33,6 +70,12 @@ func (c *Chat) BeforeCreate(tx *gorm.DB) error {
 	if c.Balance == 0 {
 		c.Balance = 30 // Начальный баланс
 	}
+	if c.Skill == 0 {
+		c.Skill = InitialElo
+	}
+	if c.DifficultyMode == "" {
+		c.DifficultyMode = DifficultyRandom
+	}
 	return nil
 }
 
@@ -44,6 +87,14 @@ func (c *Chat) IsWaitingAnswer() bool {
 	return time.Now().UTC().Before(*c.ExpiresAt)
 }
 
+// IsBlitzOverdue проверяет, что время на вопрос блица вышло, а задача blitz_expire еще не закрыла вопрос
+func (c *Chat) IsBlitzOverdue() bool {
+	if !c.BlitzMode || c.LastQuestionID == nil || c.ExpiresAt == nil {
+		return false
+	}
+	return !time.Now().UTC().Before(*c.ExpiresAt)
+}
+
 // IsWaitingFeedback проверяет, ждет ли чат обратной связи
 func (c *Chat) IsWaitingFeedback() bool {
 	if c.FeedbackExpiresAt == nil {
@@ -52,6 +103,95 @@ func (c *Chat) IsWaitingFeedback() bool {
 	return time.Now().UTC().Before(*c.FeedbackExpiresAt)
 }
 
+// IsSuggesting проверяет, предлагает ли чат вопрос
+func (c *Chat) IsSuggesting() bool {
+	if c.SuggestionStep == nil || c.SuggestionExpiresAt == nil {
+		return false
+	}
+	return time.Now().UTC().Before(*c.SuggestionExpiresAt)
+}
+
+// SuggestionDraft черновик предлагаемого вопроса, заполняемый по шагам /suggest
+type SuggestionDraft struct {
+	Text          string   `json:"text"`
+	Answer        string   `json:"answer,omitempty"`
+	Comment       *string  `json:"comment,omitempty"`
+	Hints         []string `json:"hints,omitempty"`
+	PictureFileID *string  `json:"picture_file_id,omitempty"`
+}
+
+// Value сохраняет черновик в колонку jsonb
+func (d SuggestionDraft) Value() (driver.Value, error) {
+	data, err := json.Marshal(d)
+	if err != nil {
+		return nil, err
+	}
+	return string(data), nil
+}
+
+// Scan читает черновик из колонки jsonb
+func (d *SuggestionDraft) Scan(value interface{}) error {
+	switch data := value.(type) {
+	case []byte:
+		return json.Unmarshal(data, d)
+	case string:
+		return json.Unmarshal([]byte(data), d)
+	}
+	return fmt.Errorf("unsupported suggestion draft value: %T", value)
+}
+
+// Question собирает из черновика вопрос автора вместе с подсказками и картинкой
+func (d SuggestionDraft) Question(authorID uint) *Question {
+	question := &Question{
+		Text:     d.Text,
+		Answer:   d.Answer,
+		Comment:  d.Comment,
+		AuthorID: &authorID,
+	}
+	for i, text := range d.Hints {
+		question.Hints = append(question.Hints, QuestionHint{Position: i + 1, Text: text})
+	}
+	if d.PictureFileID != nil {
+		question.QuestionPicture = &Picture{TelegramFileID: d.PictureFileID}
+	}
+	return question
+}
+
+// IsModerating проверяет, ждет ли чат администратора ввода по модерации
+func (c *Chat) IsModerating() bool {
+	if c.ModerationQuestionID == nil || c.ModerationAction == nil || c.ModerationExpiresAt == nil {
+		return false
+	}
+	return time.Now().UTC().Before(*c.ModerationExpiresAt)
+}
+
+// IsReviewing проверяет, ждет ли чат ответа на вопрос повторения
+func (c *Chat) IsReviewing() bool {
+	if c.ReviewQuestionID == nil || c.ReviewExpiresAt == nil {
+		return false
+	}
+	return time.Now().UTC().Before(*c.ReviewExpiresAt)
+}
+
+// IsReplyingFeedback проверяет, пишет ли администратор ответ на обратную связь
+func (c *Chat) IsReplyingFeedback() bool {
+	if c.ReplyFeedbackID == nil || c.ReplyFeedbackExpiresAt == nil {
+		return false
+	}
+	return time.Now().UTC().Before(*c.ReplyFeedbackExpiresAt)
+}
+
+// LeaderboardName возвращает имя чата для таблицы лидеров: выбранное имя, название чата или заглушку
+func (c *Chat) LeaderboardName() string {
+	if c.DisplayName != nil && *c.DisplayName != "" {
+		return *c.DisplayName
+	}
+	if c.Title != nil && *c.Title != "" {
+		return *c.Title
+	}
+	return "Без имени"
+}
+
 // Question представляет вопрос в квизе
 type Question struct {
 	ID                uint       `gorm:"primaryKey;column:id;default:nextval('questions_id_seq')" json:"id"`
@@ -66,7 +206,28 @@ type Question struct {
 	AnswerPicture     *Picture   `gorm:"foreignKey:AnswerPictureID;constraint:OnDelete:SET NULL" json:"answer_picture"`
 	AnswerPictureID   *uint      `gorm:"column:answer_picture_id" json:"answer_picture_id"`
 	ApprovedAt        *time.Time `gorm:"column:approved_at" json:"approved_at"`
-	Rating            *int       `gorm:"column:rating;default:0" json:"rating"`
+	RejectedAt        *time.Time `gorm:"column:rejected_at" json:"rejected_at"`
+	Rating            *int       `gorm:"column:rating;default:50" json:"rating"`
+	// Счетчики закрытий вопроса, по которым считается рейтинг: правильные ответы,
+	// все ответы вместе с показами ответа и пропуски (в рейтинг не входят)
+	CorrectCount int `gorm:"column:correct_count;default:0;not null" json:"correct_count"`
+	AnswerCount  int `gorm:"column:answer_count;default:0;not null" json:"answer_count"`
+	SkipCount    int `gorm:"column:skip_count;default:0;not null" json:"skip_count"`
+	// Difficulty сложность по шкале Эло; меняется при каждом ответе или показе ответа
+	Difficulty float64 `gorm:"column:difficulty;default:1500;not null" json:"difficulty"`
+
+	// Лимит неверных ответов и штраф за каждый из них; nil — общие настройки
+	MaxAttempts    *int `gorm:"column:max_attempts" json:"max_attempts"`
+	AttemptPenalty *int `gorm:"column:attempt_penalty" json:"attempt_penalty"`
+
+	// DailyQueuedAt когда администратор добавил вопрос в очередь вопросов дня
+	DailyQueuedAt *time.Time `gorm:"column:daily_queued_at" json:"daily_queued_at"`
+
+	// Дополнительные принимаемые варианты ответа
+	Variants []AnswerVariant `gorm:"foreignKey:QuestionID" json:"variants"`
+
+	// Подсказки автора по порядку; если они есть, автоматические подсказки не используются
+	Hints []QuestionHint `gorm:"foreignKey:QuestionID" json:"hints"`
 }
 
 // TableName возвращает имя таблицы для Question
@@ -77,17 +238,64 @@ func (Question) TableName() string {
 // BeforeCreate хук перед созданием записи
 func (q *Question) BeforeCreate(tx *gorm.DB) error {
 	if q.Rating == nil {
-		zero := 0
-		q.Rating = &zero
+		rating := QuestionRating(0, 0)
+		q.Rating = &rating
+	}
+	if q.Difficulty == 0 {
+		q.Difficulty = InitialElo
 	}
 	return nil
 }
 
+// DisplayRating возвращает рейтинг для показа игрокам; false, пока у вопроса меньше RatingMinAnswers ответов
+func (q *Question) DisplayRating() (int, bool) {
+	if q.Rating == nil || q.AnswerCount < RatingMinAnswers {
+		return 0, false
+	}
+	return *q.Rating, true
+}
+
+// AcceptedAnswers возвращает основной ответ и все допустимые варианты
+func (q *Question) AcceptedAnswers() []string {
+	answers := []string{q.Answer}
+	for _, variant := range q.Variants {
+		answers = append(answers, variant.Text)
+	}
+	return answers
+}
+
+// AnswerVariant представляет альтернативный вариант ответа на вопрос
+type AnswerVariant struct {
+	ID         uint   `gorm:"primaryKey;column:id;default:nextval('answer_variants_id_seq')" json:"id"`
+	QuestionID uint   `gorm:"column:question_id;not null;index" json:"question_id"`
+	Text       string `gorm:"column:text;type:text;not null" json:"text"`
+}
+
+// TableName возвращает имя таблицы для AnswerVariant
+func (AnswerVariant) TableName() string {
+	return "answer_variants"
+}
+
+// QuestionHint подсказка автора к вопросу
+type QuestionHint struct {
+	ID         uint   `gorm:"primaryKey;column:id;default:nextval('question_hints_id_seq')" json:"id"`
+	QuestionID uint   `gorm:"column:question_id;not null;index" json:"question_id"`
+	Position   int    `gorm:"column:position;not null" json:"position"`
+	Text       string `gorm:"column:text;type:text;not null" json:"text"`
+}
+
+// TableName возвращает имя таблицы для QuestionHint
+func (QuestionHint) TableName() string {
+	return "question_hints"
+}
+
 // Picture представляет изображение
 type Picture struct {
 	ID        uint      `gorm:"primaryKey;column:id;default:nextval('pictures_id_seq')" json:"id"`
 	CreatedAt time.Time `gorm:"column:created_at;autoCreateTime" json:"created_at"`
 	Path      *string   `gorm:"column:path" json:"path"`
+	// Идентификатор файла в Telegram для картинок, присланных пользователями
+	TelegramFileID *string `gorm:"column:telegram_file_id" json:"telegram_file_id"`
 }
 
 // TableName возвращает имя таблицы для Picture
@@ -103,6 +311,10 @@ type Feedback struct {
 	Response  *string   `gorm:"column:response;type:text" json:"response"`
 	ChatID    uint      `gorm:"column:chat_id;not null" json:"chat_id"`
 	Chat      Chat      `gorm:"foreignKey:ChatID;constraint:OnDelete:CASCADE" json:"chat"`
+
+	// Сообщение с обратной связью в чате администратора
+	AdminMessageID *int       `gorm:"column:admin_message_id;index" json:"admin_message_id"`
+	RespondedAt    *time.Time `gorm:"column:responded_at" json:"responded_at"`
 }
 
 // TableName возвращает имя таблицы для Feedback
@@ -121,6 +333,17 @@ type Reaction struct {
 	Chat        Chat       `gorm:"foreignKey:ChatID;constraint:OnDelete:CASCADE" json:"chat"`
 	QuestionID  uint       `gorm:"column:question_id;not null" json:"question_id"`
 	Question    Question   `gorm:"foreignKey:QuestionID;constraint:OnDelete:CASCADE" json:"question"`
+
+	// Взятые подсказки и их суммарная стоимость в монетах (доли монеты копятся)
+	HintsUsed int     `gorm:"column:hints_used;default:0;not null" json:"hints_used"`
+	HintCost  float64 `gorm:"column:hint_cost;default:0;not null" json:"hint_cost"`
+
+	// Attempts число неверных ответов на вопрос
+	Attempts int `gorm:"column:attempts;default:0;not null" json:"attempts"`
+
+	// Пользователь Telegram, приславший правильный ответ, и его имя на момент ответа
+	ResponderID   *int64  `gorm:"column:responder_id" json:"responder_id"`
+	ResponderName *string `gorm:"column:responder_name" json:"responder_name"`
 }
 
 // TableName возвращает имя таблицы для Reaction
@@ -137,3 +360,410 @@ func (r *Reaction) BeforeCreate(tx *gorm.DB) error {
 	})
 	return nil
 }
+
+// IsResolved проверяет, закрыт ли вопрос: ответ, пропуск или показ ответа.
+// Реакция без этих отметок появляется, когда взята подсказка, а вопрос еще не закрыт
+func (r *Reaction) IsResolved() bool {
+	return r.ResponsedAt != nil || r.SkippedAt != nil || r.FailedAt != nil
+}
+
+// TransactionReason причина изменения баланса
+type TransactionReason string
+
+const (
+	// ReasonOpeningBalance остаток, перенесенный при запуске журнала
+	ReasonOpeningBalance TransactionReason = "opening_balance"
+	// ReasonSignupBonus начальный бонус при первом контакте с ботом
+	ReasonSignupBonus TransactionReason = "signup_bonus"
+	// ReasonAnswer списание за правильный ответ
+	ReasonAnswer TransactionReason = "answer"
+	// ReasonSkip списание за пропуск вопроса
+	ReasonSkip TransactionReason = "skip"
+	// ReasonReveal списание за показ ответа
+	ReasonReveal TransactionReason = "reveal"
+	// ReasonQuestionApproved награда за одобренный вопрос
+	ReasonQuestionApproved TransactionReason = "question_approved"
+	// ReasonPurchase покупка монет
+	ReasonPurchase TransactionReason = "purchase"
+	// ReasonAdminGrant начисление или списание администратором
+	ReasonAdminGrant TransactionReason = "admin_grant"
+	// ReasonTransferIn получение монет от другого чата
+	ReasonTransferIn TransactionReason = "transfer_in"
+	// ReasonTransferOut передача монет другому чату
+	ReasonTransferOut TransactionReason = "transfer_out"
+	// ReasonRefund возврат монет
+	ReasonRefund TransactionReason = "refund"
+	// ReasonHint списание за подсказку
+	ReasonHint TransactionReason = "hint"
+	// ReasonWrongAnswer штраф за неверный ответ
+	ReasonWrongAnswer TransactionReason = "wrong_answer"
+	// ReasonSpeedBonus возврат монет за быстрый ответ в блице
+	ReasonSpeedBonus TransactionReason = "speed_bonus"
+)
+
+// CoinTransaction представляет запись в журнале изменений баланса
+type CoinTransaction struct {
+	ID           uint              `gorm:"primaryKey;column:id;default:nextval('coin_transactions_id_seq')" json:"id"`
+	CreatedAt    time.Time         `gorm:"column:created_at;autoCreateTime" json:"created_at"`
+	ChatID       uint              `gorm:"column:chat_id;not null;index" json:"chat_id"`
+	Chat         Chat              `gorm:"foreignKey:ChatID;constraint:OnDelete:CASCADE" json:"-"`
+	Delta        int               `gorm:"column:delta;not null" json:"delta"`
+	BalanceAfter int               `gorm:"column:balance_after;not null" json:"balance_after"`
+	Reason       TransactionReason `gorm:"column:reason;type:varchar(32);not null" json:"reason"`
+
+	// Ссылки на связанные сущности
+	QuestionID    *uint   `gorm:"column:question_id" json:"question_id"`
+	RelatedChatID *uint   `gorm:"column:related_chat_id" json:"related_chat_id"`
+	ExternalID    *string `gorm:"column:external_id" json:"external_id"`
+}
+
+// TableName возвращает имя таблицы для CoinTransaction
+func (CoinTransaction) TableName() string {
+	return "coin_transactions"
+}
+
+// CoinTransfer представляет одноразовый код перевода монет другому чату
+type CoinTransfer struct {
+	ID         uint       `gorm:"primaryKey;column:id;default:nextval('coin_transfers_id_seq')" json:"id"`
+	CreatedAt  time.Time  `gorm:"column:created_at;autoCreateTime" json:"created_at"`
+	Code       string     `gorm:"column:code;uniqueIndex;not null" json:"code"`
+	Amount     int        `gorm:"column:amount;not null" json:"amount"`
+	FromChatID uint       `gorm:"column:from_chat_id;not null" json:"from_chat_id"`
+	FromChat   Chat       `gorm:"foreignKey:FromChatID;constraint:OnDelete:CASCADE" json:"from_chat"`
+	ToChatID   *uint      `gorm:"column:to_chat_id" json:"to_chat_id"`
+	ToChat     *Chat      `gorm:"foreignKey:ToChatID;constraint:OnDelete:SET NULL" json:"to_chat"`
+	ExpiresAt  time.Time  `gorm:"column:expires_at;not null" json:"expires_at"`
+	RedeemedAt *time.Time `gorm:"column:redeemed_at" json:"redeemed_at"`
+	// RefundedAt когда зарезервированные монеты вернулись отправителю после истечения кода
+	RefundedAt *time.Time `gorm:"column:refunded_at" json:"refunded_at"`
+}
+
+// TableName возвращает имя таблицы для CoinTransfer
+func (CoinTransfer) TableName() string {
+	return "coin_transfers"
+}
+
+// Purchase представляет оплаченную покупку монет
+type Purchase struct {
+	ID               uint      `gorm:"primaryKey;column:id;default:nextval('purchases_id_seq')" json:"id"`
+	CreatedAt        time.Time `gorm:"column:created_at;autoCreateTime" json:"created_at"`
+	ChatID           uint      `gorm:"column:chat_id;not null;index" json:"chat_id"`
+	Chat             Chat      `gorm:"foreignKey:ChatID;constraint:OnDelete:CASCADE" json:"-"`
+	Coins            int       `gorm:"column:coins;not null" json:"coins"`
+	Currency         string    `gorm:"column:currency;type:varchar(8);not null" json:"currency"`
+	TotalAmount      int       `gorm:"column:total_amount;not null" json:"total_amount"`
+	Payload          string    `gorm:"column:payload;not null" json:"payload"`
+	TelegramChargeID string    `gorm:"column:telegram_charge_id;uniqueIndex;not null" json:"telegram_charge_id"`
+	ProviderChargeID *string   `gorm:"column:provider_charge_id" json:"provider_charge_id"`
+}
+
+// TableName возвращает имя таблицы для Purchase
+func (Purchase) TableName() string {
+	return "purchases"
+}
+
+// ProcessedUpdate представляет обновление Telegram, которое обрабатывается или уже обработано.
+// Пока ProcessedAt пустой, обновление занято до LockedUntil; после этого его можно обработать заново
+type ProcessedUpdate struct {
+	UpdateID    int        `gorm:"primaryKey;column:update_id;autoIncrement:false" json:"update_id"`
+	ReceivedAt  time.Time  `gorm:"column:received_at;autoCreateTime" json:"received_at"`
+	LockedUntil *time.Time `gorm:"column:locked_until" json:"locked_until"`
+	ProcessedAt *time.Time `gorm:"column:processed_at" json:"processed_at"`
+}
+
+// TableName возвращает имя таблицы для ProcessedUpdate
+func (ProcessedUpdate) TableName() string {
+	return "processed_updates"
+}
+
+// LeaderboardPeriod период таблицы лидеров
+type LeaderboardPeriod string
+
+const (
+	// LeaderboardDay текущие сутки по UTC
+	LeaderboardDay LeaderboardPeriod = "day"
+	// LeaderboardWeek текущая неделя по UTC, с понедельника
+	LeaderboardWeek LeaderboardPeriod = "week"
+	// LeaderboardAllTime все время
+	LeaderboardAllTime LeaderboardPeriod = "all"
+)
+
+// LeaderboardPeriods периоды, по которым ведутся итоги
+var LeaderboardPeriods = []LeaderboardPeriod{LeaderboardDay, LeaderboardWeek, LeaderboardAllTime}
+
+// Start возвращает начало периода, в который попадает момент t
+func (p LeaderboardPeriod) Start(t time.Time) time.Time {
+	t = t.UTC()
+	day := time.Date(t.Year(), t.Month(), t.Day(), 0, 0, 0, 0, time.UTC)
+	switch p {
+	case LeaderboardDay:
+		return day
+	case LeaderboardWeek:
+		// Неделя начинается в понедельник, как date_trunc('week') в PostgreSQL
+		return day.AddDate(0, 0, -(int(day.Weekday())+6)%7)
+	default:
+		return time.Date(1970, 1, 1, 0, 0, 0, 0, time.UTC)
+	}
+}
+
+// LeaderboardScore итоги чата за период; обновляются при закрытии вопроса
+type LeaderboardScore struct {
+	ChatID      uint              `gorm:"primaryKey;column:chat_id;autoIncrement:false" json:"chat_id"`
+	Chat        Chat              `gorm:"foreignKey:ChatID;constraint:OnDelete:CASCADE" json:"-"`
+	Period      LeaderboardPeriod `gorm:"primaryKey;column:period;type:varchar(8)" json:"period"`
+	PeriodStart time.Time         `gorm:"primaryKey;column:period_start;type:date" json:"period_start"`
+	Answered    int               `gorm:"column:answered;default:0;not null" json:"answered"`
+	Revealed    int               `gorm:"column:revealed;default:0;not null" json:"revealed"`
+
+	// Суммарный вес закрытых вопросов и вес правильно отвеченных; вес растет со сложностью вопроса
+	TotalWeight   float64 `gorm:"column:total_weight;default:0;not null" json:"total_weight"`
+	CorrectWeight float64 `gorm:"column:correct_weight;default:0;not null" json:"correct_weight"`
+}
+
+// TableName возвращает имя таблицы для LeaderboardScore
+func (LeaderboardScore) TableName() string {
+	return "leaderboard_scores"
+}
+
+// Accuracy точность с учетом сложности: доля веса правильно отвеченных вопросов
+func (s *LeaderboardScore) Accuracy() float64 {
+	if s.TotalWeight == 0 {
+		return 0
+	}
+	return s.CorrectWeight / s.TotalWeight
+}
+
+// Сглаживание рейтинга (среднее апостериорного бета-распределения): к ответам на вопрос добавляются
+// RatingPriorAnswers воображаемых, из них RatingPriorCorrect правильных, поэтому один правильный ответ дает 60%, а не 100%
+const (
+	RatingPriorCorrect = 2
+	RatingPriorAnswers = 4
+)
+
+// RatingMinAnswers после скольких ответов и показов ответа рейтинг вопроса показывается игрокам
+const RatingMinAnswers = 5
+
+// QuestionRating рейтинг вопроса — сглаженный процент правильных ответов среди ответов и показов ответа
+func QuestionRating(correct, answers int) int {
+	return int(math.Round(100 * float64(correct+RatingPriorCorrect) / float64(answers+RatingPriorAnswers)))
+}
+
+// DifficultyWeight вес вопроса в точности: от 1 для вопроса, на который отвечают все,
+// до 2 для вопроса, на который не отвечает никто (рейтинг — процент правильных ответов)
+func DifficultyWeight(rating *int) float64 {
+	if rating == nil {
+		return 2
+	}
+	return 1 + float64(100-*rating)/100
+}
+
+// JobKind тип отложенной задачи
+type JobKind string
+
+const (
+	// JobBlitzTick обновление обратного отсчета в сообщении с вопросом
+	JobBlitzTick JobKind = "blitz_tick"
+	// JobBlitzExpire истечение времени на вопрос в блице
+	JobBlitzExpire JobKind = "blitz_expire"
+	// JobDailyStart выбор вопроса дня; в очереди всегда одна такая задача
+	JobDailyStart JobKind = "daily_start"
+	// JobDailySend отправка вопроса дня следующей пачке подписанных чатов
+	JobDailySend JobKind = "daily_send"
+	// JobDailySummary отправка итогов вопроса дня следующей пачке чатов, получивших вопрос
+	JobDailySummary JobKind = "daily_summary"
+	// JobTransferExpire возврат отправителю монет по истекшим кодам перевода
+	JobTransferExpire JobKind = "transfer_expire"
+)
+
+// ScheduledJob отложенная задача; выполняется планировщиком, когда наступает RunAt.
+// У задач, не относящихся к одному чату (вопрос дня), ChatID пустой
+type ScheduledJob struct {
+	ID         uint      `gorm:"primaryKey;column:id;default:nextval('scheduled_jobs_id_seq')" json:"id"`
+	CreatedAt  time.Time `gorm:"column:created_at;autoCreateTime" json:"created_at"`
+	RunAt      time.Time `gorm:"column:run_at;not null;index" json:"run_at"`
+	Kind       JobKind   `gorm:"column:kind;type:varchar(32);not null" json:"kind"`
+	ChatID     *uint     `gorm:"column:chat_id" json:"chat_id"`
+	Chat       Chat      `gorm:"foreignKey:ChatID;constraint:OnDelete:CASCADE" json:"-"`
+	QuestionID *uint     `gorm:"column:question_id" json:"question_id"`
+	// LockedUntil срок, на который задачу забрал планировщик; задача, не завершенная к этому сроку, забирается снова
+	LockedUntil *time.Time `gorm:"column:locked_until" json:"locked_until"`
+	// Attempts сколько раз задачу забирали на выполнение
+	Attempts int `gorm:"column:attempts;default:0;not null" json:"attempts"`
+}
+
+// TableName возвращает имя таблицы для ScheduledJob
+func (ScheduledJob) TableName() string {
+	return "scheduled_jobs"
+}
+
+// Tag тема вопросов
+type Tag struct {
+	ID        uint      `gorm:"primaryKey;column:id;default:nextval('tags_id_seq')" json:"id"`
+	CreatedAt time.Time `gorm:"column:created_at;autoCreateTime" json:"created_at"`
+	Name      string    `gorm:"column:name;type:varchar(32);not null" json:"name"`
+	// Questions число опубликованных вопросов темы; заполняется только при чтении списка тем
+	Questions int `gorm:"column:questions;->" json:"questions"`
+}
+
+// TableName возвращает имя таблицы для Tag
+func (Tag) TableName() string {
+	return "tags"
+}
+
+// QuestionTag связь вопроса с темой
+type QuestionTag struct {
+	QuestionID uint `gorm:"primaryKey;column:question_id" json:"question_id"`
+	TagID      uint `gorm:"primaryKey;column:tag_id" json:"tag_id"`
+}
+
+// TableName возвращает имя таблицы для QuestionTag
+func (QuestionTag) TableName() string {
+	return "question_tags"
+}
+
+// ChatTopic тема, выбранная чатом
+type ChatTopic struct {
+	ChatID uint `gorm:"primaryKey;column:chat_id" json:"chat_id"`
+	TagID  uint `gorm:"primaryKey;column:tag_id" json:"tag_id"`
+}
+
+// TableName возвращает имя таблицы для ChatTopic
+func (ChatTopic) TableName() string {
+	return "chat_topics"
+}
+
+// DifficultyMode предпочтение чата по сложности вопросов
+type DifficultyMode string
+
+const (
+	// DifficultyRandom случайный вопрос без учета сложности
+	DifficultyRandom DifficultyMode = "random"
+	// DifficultyEasy вопросы легче среднего
+	DifficultyEasy DifficultyMode = "easy"
+	// DifficultyNormal вопросы средней сложности
+	DifficultyNormal DifficultyMode = "normal"
+	// DifficultyHard вопросы сложнее среднего
+	DifficultyHard DifficultyMode = "hard"
+	// DifficultyAdaptive вопросы около уровня чата
+	DifficultyAdaptive DifficultyMode = "adaptive"
+)
+
+// InitialElo начальный уровень чата и сложность нового вопроса по шкале Эло
+const InitialElo = 1500
+
+// Коэффициенты изменения по шкале Эло: уровень чата меняется быстрее сложности вопроса,
+// на который отвечает гораздо больше чатов
+const (
+	ChatEloK     = 32
+	QuestionEloK = 16
+)
+
+// EloExpected вероятность того, что чат с уровнем skill правильно ответит на вопрос сложности difficulty
+func EloExpected(skill, difficulty float64) float64 {
+	return 1 / (1 + math.Pow(10, (difficulty-skill)/400))
+}
+
+// EloUpdate возвращает новые уровень чата и сложность вопроса после правильного ответа или показа ответа
+func EloUpdate(skill, difficulty float64, correct bool) (float64, float64) {
+	score := 0.0
+	if correct {
+		score = 1
+	}
+	delta := score - EloExpected(skill, difficulty)
+	return skill + ChatEloK*delta, difficulty - QuestionEloK*delta
+}
+
+// Параметры SM-2: начальный коэффициент легкости карточки и его нижняя граница
+const (
+	ReviewInitialEase = 2.5
+	ReviewMinEase     = 1.3
+)
+
+// Оценки повторения по шкале SM-2 (от 0 до 5): правильный ответ и показ ответа
+const (
+	ReviewQualityCorrect  = 4
+	ReviewQualityRevealed = 1
+)
+
+// ReviewCard карточка интервального повторения вопроса, который чат пропустил или не смог ответить
+type ReviewCard struct {
+	ChatID       uint       `gorm:"primaryKey;column:chat_id" json:"chat_id"`
+	QuestionID   uint       `gorm:"primaryKey;column:question_id" json:"question_id"`
+	CreatedAt    time.Time  `gorm:"column:created_at;autoCreateTime" json:"created_at"`
+	DueAt        time.Time  `gorm:"column:due_at;not null" json:"due_at"`
+	Repetitions  int        `gorm:"column:repetitions;default:0;not null" json:"repetitions"`
+	IntervalDays int        `gorm:"column:interval_days;default:0;not null" json:"interval_days"`
+	Ease         float64    `gorm:"column:ease;default:2.5;not null" json:"ease"`
+	ReviewedAt   *time.Time `gorm:"column:reviewed_at" json:"reviewed_at"`
+}
+
+// TableName возвращает имя таблицы для ReviewCard
+func (ReviewCard) TableName() string {
+	return "review_cards"
+}
+
+// Grade пересчитывает карточку по алгоритму SM-2 после повторения с оценкой quality (от 0 до 5)
+// и назначает следующее повторение: после ошибки — через день, после удачи — через все больший интервал
+func (c *ReviewCard) Grade(quality int, now time.Time) {
+	if quality >= 3 {
+		switch c.Repetitions {
+		case 0:
+			c.IntervalDays = 1
+		case 1:
+			c.IntervalDays = 6
+		default:
+			c.IntervalDays = int(math.Round(float64(c.IntervalDays) * c.Ease))
+		}
+		c.Repetitions++
+	} else {
+		c.Repetitions = 0
+		c.IntervalDays = 1
+	}
+
+	miss := float64(5 - quality)
+	c.Ease = max(ReviewMinEase, c.Ease+0.1-miss*(0.08+miss*0.02))
+	c.ReviewedAt = &now
+	c.DueAt = now.AddDate(0, 0, c.IntervalDays)
+}
+
+// DailyQuestion вопрос дня: один на день, каждый вопрос бывает вопросом дня только раз
+type DailyQuestion struct {
+	ID         uint      `gorm:"primaryKey;column:id;default:nextval('daily_questions_id_seq')" json:"id"`
+	CreatedAt  time.Time `gorm:"column:created_at;autoCreateTime" json:"created_at"`
+	Day        time.Time `gorm:"column:day;type:date;not null;uniqueIndex" json:"day"`
+	QuestionID uint      `gorm:"column:question_id;not null;uniqueIndex" json:"question_id"`
+	// ExpiresAt до какого времени принимаются ответы
+	ExpiresAt time.Time `gorm:"column:expires_at;not null" json:"expires_at"`
+}
+
+// TableName возвращает имя таблицы для DailyQuestion
+func (DailyQuestion) TableName() string {
+	return "daily_questions"
+}
+
+// DailyDelivery отправка вопроса дня одному чату: ждет отправки, отправлена или не доставлена, ответил ли чат
+// и отправлены ли ему итоги
+type DailyDelivery struct {
+	DailyQuestionID uint       `gorm:"primaryKey;column:daily_question_id" json:"daily_question_id"`
+	ChatID          uint       `gorm:"primaryKey;column:chat_id" json:"chat_id"`
+	Chat            Chat       `gorm:"foreignKey:ChatID;constraint:OnDelete:CASCADE" json:"-"`
+	CreatedAt       time.Time  `gorm:"column:created_at;autoCreateTime" json:"created_at"`
+	SentAt          *time.Time `gorm:"column:sent_at" json:"sent_at"`
+	FailedAt        *time.Time `gorm:"column:failed_at" json:"failed_at"`
+	AnsweredAt      *time.Time `gorm:"column:answered_at" json:"answered_at"`
+	// SummarySentAt когда чату отправлены итоги вопроса дня или отправка итогов не удалась
+	SummarySentAt *time.Time `gorm:"column:summary_sent_at" json:"summary_sent_at"`
+}
+
+// TableName возвращает имя таблицы для DailyDelivery
+func (DailyDelivery) TableName() string {
+	return "daily_deliveries"
+}
+
+// AnsweredPercent доля чатов, ответивших на вопрос дня, среди тех, кому он доставлен
+func AnsweredPercent(answered, sent int) int {
+	if sent == 0 {
+		return 0
+	}
+	return int(math.Round(float64(answered) * 100 / float64(sent)))
+}
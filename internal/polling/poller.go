@@ -0,0 +1,167 @@
+// Package polling получает обновления через getUpdates для запуска бота без публичного вебхука
+package polling
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	tgbotapi "github.com/go-telegram-bot-api/telegram-bot-api/v5"
+	"sync"
+	"time"
+)
+
+// retryDelay пауза перед повторным запросом после ошибки getUpdates
+const retryDelay = 3 * time.Second
+
+// HandleFunc обрабатывает одно обновление
+type HandleFunc func(update *tgbotapi.Update)
+
+// Poller получает обновления long polling'ом и раздает их воркерам.
+// Обновления одного чата всегда попадают к одному воркеру и обрабатываются по порядку
+type Poller struct {
+	bot    *tgbotapi.BotAPI
+	handle HandleFunc
+
+	// Workers количество одновременно обрабатываемых чатов
+	Workers int
+	// Timeout время ожидания новых обновлений в одном запросе getUpdates, в секундах
+	Timeout int
+
+	offset int
+}
+
+// NewPoller создает новый получатель обновлений
+func NewPoller(bot *tgbotapi.BotAPI, handle HandleFunc, workers int) *Poller {
+	if workers <= 0 {
+		workers = 1
+	}
+	return &Poller{
+		bot:     bot,
+		handle:  handle,
+		Workers: workers,
+		Timeout: 30,
+	}
+}
+
+// Run получает и обрабатывает обновления до отмены контекста.
+// После отмены новые обновления не запрашиваются, уже полученные дорабатываются,
+// а их смещение подтверждается в Telegram, чтобы после перезапуска они не пришли повторно
+func (p *Poller) Run(ctx context.Context) error {
+	// getUpdates не работает, пока у бота установлен вебхук
+	if _, err := p.bot.Request(tgbotapi.DeleteWebhookConfig{}); err != nil {
+		return fmt.Errorf("failed to delete webhook: %w", err)
+	}
+
+	queues := make([]chan tgbotapi.Update, p.Workers)
+	var wg sync.WaitGroup
+	for i := range queues {
+		queues[i] = make(chan tgbotapi.Update, 100)
+		wg.Add(1)
+		go func(queue <-chan tgbotapi.Update) {
+			defer wg.Done()
+			for update := range queue {
+				p.process(update)
+			}
+		}(queues[i])
+	}
+
+	for {
+		updates, err := p.getUpdates(ctx)
+		if ctx.Err() != nil {
+			break
+		}
+		if err != nil {
+			fmt.Printf("Failed to get updates: %v (offset: %d)\n", err, p.offset)
+			select {
+			case <-ctx.Done():
+			case <-time.After(retryDelay):
+			}
+			continue
+		}
+
+		for _, update := range updates {
+			queues[shard(&update, len(queues))] <- update
+			p.offset = update.UpdateID + 1
+		}
+	}
+
+	// Дожидаемся обработки уже полученных обновлений
+	for _, queue := range queues {
+		close(queue)
+	}
+	wg.Wait()
+
+	return p.confirm()
+}
+
+// getUpdates запрашивает очередную порцию обновлений; при отмене контекста не ждет ответа
+func (p *Poller) getUpdates(ctx context.Context) ([]tgbotapi.Update, error) {
+	type result struct {
+		updates []tgbotapi.Update
+		err     error
+	}
+
+	done := make(chan result, 1)
+	go func() {
+		updates, err := p.bot.GetUpdates(tgbotapi.UpdateConfig{
+			Offset:  p.offset,
+			Timeout: p.Timeout,
+		})
+		done <- result{updates, err}
+	}()
+
+	select {
+	case <-ctx.Done():
+		// Ответ отброшен: смещение не сдвинулось, эти обновления придут после перезапуска
+		return nil, ctx.Err()
+	case r := <-done:
+		return r.updates, r.err
+	}
+}
+
+// confirm сообщает Telegram смещение последнего обработанного обновления
+func (p *Poller) confirm() error {
+	if p.offset == 0 {
+		return nil
+	}
+
+	params := tgbotapi.Params{}
+	params.AddNonZero("offset", p.offset)
+	params.AddNonZero("limit", 1)
+	if _, err := p.bot.MakeRequest("getUpdates", params); err != nil {
+		return fmt.Errorf("failed to confirm offset %d: %w", p.offset, err)
+	}
+	return nil
+}
+
+// process обрабатывает обновление, не давая панике в обработчике остановить воркер
+func (p *Poller) process(update tgbotapi.Update) {
+	defer func() {
+		if r := recover(); r != nil {
+			data, _ := json.Marshal(update)
+			fmt.Printf("Panic while handling update %d: %v (update: %s)\n", update.UpdateID, r, data)
+		}
+	}()
+
+	p.handle(&update)
+}
+
+// shard выбирает воркера по чату обновления, чтобы сообщения одного чата обрабатывались последовательно
+func shard(update *tgbotapi.Update, workers int) int {
+	var key int64
+	switch {
+	case update.Message != nil:
+		key = update.Message.Chat.ID
+	case update.CallbackQuery != nil && update.CallbackQuery.Message != nil:
+		key = update.CallbackQuery.Message.Chat.ID
+	case update.SentFrom() != nil:
+		// Запросы без чата (например, pre_checkout_query) привязываем к пользователю:
+		// в личном чате его ID совпадает с ID чата
+		key = update.SentFrom().ID
+	}
+
+	if key < 0 {
+		key = -key
+	}
+	return int(key % int64(workers))
+}
@@ -0,0 +1,164 @@
+package polling
+
+import (
+	"context"
+	"fmt"
+	tgbotapi "github.com/go-telegram-bot-api/telegram-bot-api/v5"
+	"qweasley/internal/telegramtest"
+	"reflect"
+	"sync"
+	"testing"
+	"time"
+)
+
+// handled записывает тексты обработанных сообщений по чатам
+type handled struct {
+	mu    sync.Mutex
+	texts map[int64][]string
+}
+
+// handle записывает обновление как обработанное
+func (h *handled) handle(update *tgbotapi.Update) {
+	// Разная длительность обработки перемешивает чаты между воркерами
+	time.Sleep(time.Duration(update.UpdateID%3) * time.Millisecond)
+
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	h.texts[update.Message.Chat.ID] = append(h.texts[update.Message.Chat.ID], update.Message.Text)
+}
+
+// get возвращает тексты, обработанные в чате, по порядку
+func (h *handled) get(chatID int64) []string {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	return append([]string(nil), h.texts[chatID]...)
+}
+
+// count возвращает число обработанных обновлений во всех чатах
+func (h *handled) count() int {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+
+	total := 0
+	for _, texts := range h.texts {
+		total += len(texts)
+	}
+	return total
+}
+
+// start запускает получатель обновлений и возвращает функцию, которая останавливает его и возвращает результат Run
+func start(t *testing.T, server *telegramtest.Server, h *handled) func() error {
+	t.Helper()
+
+	bot, err := server.NewBot()
+	if err != nil {
+		t.Fatalf("failed to create bot: %v", err)
+	}
+
+	poller := NewPoller(bot, h.handle, 4)
+	// Брошенный при отмене запрос getUpdates держит соединение до таймаута, а server.Close его ждет
+	poller.Timeout = 1
+
+	ctx, cancel := context.WithCancel(context.Background())
+	done := make(chan error, 1)
+	go func() {
+		done <- poller.Run(ctx)
+	}()
+
+	return func() error {
+		cancel()
+		select {
+		case err := <-done:
+			return err
+		case <-time.After(5 * time.Second):
+			t.Fatal("poller did not stop after cancel")
+			return nil
+		}
+	}
+}
+
+// waitHandled ждет, пока обработается нужное число обновлений
+func waitHandled(t *testing.T, h *handled, want int) {
+	t.Helper()
+
+	deadline := time.Now().Add(5 * time.Second)
+	for h.count() < want {
+		if time.Now().After(deadline) {
+			t.Fatalf("handled %d updates, want %d", h.count(), want)
+		}
+		time.Sleep(5 * time.Millisecond)
+	}
+}
+
+func TestPollerKeepsChatOrder(t *testing.T) {
+	const first, second = 1001, 1002
+
+	server := telegramtest.NewServer()
+	t.Cleanup(server.Close)
+
+	var want []string
+	for i := 1; i <= 10; i++ {
+		text := fmt.Sprint(i)
+		server.Push(telegramtest.TextUpdate(first, text))
+		server.Push(telegramtest.TextUpdate(second, text))
+		want = append(want, text)
+	}
+
+	h := &handled{texts: make(map[int64][]string)}
+	stop := start(t, server, h)
+	waitHandled(t, h, 20)
+	if err := stop(); err != nil {
+		t.Fatalf("poller failed: %v", err)
+	}
+
+	for _, chatID := range []int64{first, second} {
+		if got := h.get(chatID); !reflect.DeepEqual(got, want) {
+			t.Fatalf("chat %d handled %v, want %v", chatID, got, want)
+		}
+	}
+
+	// После остановки смещение подтверждено: повторно эти обновления не придут
+	if pending := server.Pending(); len(pending) != 0 {
+		t.Fatalf("pending updates after stop: %d", len(pending))
+	}
+}
+
+func TestPollerCancelKeepsUnreceivedUpdates(t *testing.T) {
+	const chatID = 1001
+
+	server := telegramtest.NewServer()
+	t.Cleanup(server.Close)
+	h := &handled{texts: make(map[int64][]string)}
+
+	server.Push(telegramtest.TextUpdate(chatID, "before"))
+	stop := start(t, server, h)
+	waitHandled(t, h, 1)
+
+	// Отмена приходит, пока getUpdates ждет новых обновлений
+	if err := stop(); err != nil {
+		t.Fatalf("poller failed: %v", err)
+	}
+
+	// Ответ брошенного запроса не подтвержден смещением: обновление ждет следующего запуска
+	server.Push(telegramtest.TextUpdate(chatID, "after"))
+	time.Sleep(50 * time.Millisecond)
+	if pending := server.Pending(); len(pending) != 1 {
+		t.Fatalf("pending updates after cancel: %d, want 1", len(pending))
+	}
+	if got := h.get(chatID); !reflect.DeepEqual(got, []string{"before"}) {
+		t.Fatalf("handled %v after cancel, want only the first update", got)
+	}
+
+	stop = start(t, server, h)
+	waitHandled(t, h, 2)
+	if err := stop(); err != nil {
+		t.Fatalf("poller failed: %v", err)
+	}
+
+	if got := h.get(chatID); !reflect.DeepEqual(got, []string{"before", "after"}) {
+		t.Fatalf("handled %v, want both updates once", got)
+	}
+	if pending := server.Pending(); len(pending) != 0 {
+		t.Fatalf("pending updates after restart: %d", len(pending))
+	}
+}
@@ -34,7 +34,18 @@ func (r *ChatRepository) GetOrCreate(telegramID int64, title *string) (*models.C
 		Balance:    30, // Начальный баланс
 	}
 
-	err = r.db.Create(&chat).Error
+	// Создаем чат вместе с записью о начальном бонусе в журнале
+	err = r.db.Transaction(func(tx *gorm.DB) error {
+		if err := tx.Create(&chat).Error; err != nil {
+			return err
+		}
+		return tx.Create(&models.CoinTransaction{
+			ChatID:       chat.ID,
+			Delta:        chat.Balance,
+			BalanceAfter: chat.Balance,
+			Reason:       models.ReasonSignupBonus,
+		}).Error
+	})
 	if err != nil {
 		return nil, err
 	}
@@ -42,41 +53,32 @@ func (r *ChatRepository) GetOrCreate(telegramID int64, title *string) (*models.C
 	return &chat, nil
 }
 
-// SetWaitingAnswer устанавливает ожидание ответа на вопрос
+// SetWaitingAnswer устанавливает ожидание ответа на вопрос.
+// Сообщение предыдущего вопроса забывается до отправки нового, а начатое повторение прерывается
 func (r *ChatRepository) SetWaitingAnswer(chatID uint, questionID uint, expiresIn time.Duration) error {
-	chat, err := r.GetByID(chatID)
-	if err != nil {
-		return err
-	}
-
-	chat.LastQuestionID = &questionID
 	expiresAt := time.Now().UTC().Add(expiresIn)
-	chat.ExpiresAt = &expiresAt
+	return r.updateChat(chatID, map[string]interface{}{
+		"last_question_id":   questionID,
+		"expires_at":         expiresAt,
+		"last_message_id":    nil,
+		"review_question_id": nil,
+		"review_expires_at":  nil,
+	})
+}
 
-	return r.db.Save(chat).Error
+// SetLastMessage запоминает сообщение, в котором отправлен текущий вопрос
+func (r *ChatRepository) SetLastMessage(chatID uint, messageID int) error {
+	return r.updateChat(chatID, map[string]interface{}{
+		"last_message_id": messageID,
+	})
 }
 
 // ClearWaitingAnswer очищает ожидание ответа
 func (r *ChatRepository) ClearWaitingAnswer(chatID uint) error {
-	chat, err := r.GetByID(chatID)
-	if err != nil {
-		return err
-	}
-
-	chat.LastQuestionID = nil
-	chat.ExpiresAt = nil
-
-	return r.db.Save(chat).Error
-}
-
-// UpdateBalance обновляет баланс чата
-func (r *ChatRepository) UpdateBalance(chatID uint, balance int) error {
-	return r.db.Model(&models.Chat{}).Where("id = ?", chatID).Update("balance", balance).Error
-}
-
-// DecreaseBalance уменьшает баланс на 1
-func (r *ChatRepository) DecreaseBalance(chatID uint) error {
-	return r.db.Model(&models.Chat{}).Where("id = ?", chatID).Update("balance", gorm.Expr("balance - 1")).Error
+	return r.updateChat(chatID, map[string]interface{}{
+		"last_question_id": nil,
+		"expires_at":       nil,
+	})
 }
 
 // GetByID получает чат по ID
@@ -91,25 +93,128 @@ func (r *ChatRepository) GetByID(chatID uint) (*models.Chat, error) {
 
 // SetWaitingFeedback устанавливает ожидание обратной связи
 func (r *ChatRepository) SetWaitingFeedback(chatID uint, expiresIn time.Duration) error {
-	chat, err := r.GetByID(chatID)
-	if err != nil {
-		return err
-	}
-
 	expiresAt := time.Now().UTC().Add(expiresIn)
-	chat.FeedbackExpiresAt = &expiresAt
-
-	return r.db.Save(chat).Error
+	return r.updateChat(chatID, map[string]interface{}{
+		"feedback_expires_at": expiresAt,
+	})
 }
 
 // ClearWaitingFeedback очищает ожидание обратной связи
 func (r *ChatRepository) ClearWaitingFeedback(chatID uint) error {
-	chat, err := r.GetByID(chatID)
-	if err != nil {
-		return err
-	}
+	return r.updateChat(chatID, map[string]interface{}{
+		"feedback_expires_at": nil,
+	})
+}
+
+// SetSuggestionStep устанавливает шаг предложения вопроса
+func (r *ChatRepository) SetSuggestionStep(chatID uint, draft *models.SuggestionDraft, step string, expiresIn time.Duration) error {
+	expiresAt := time.Now().UTC().Add(expiresIn)
+	return r.updateChat(chatID, map[string]interface{}{
+		"suggestion_draft":      draft,
+		"suggestion_step":       step,
+		"suggestion_expires_at": expiresAt,
+	})
+}
+
+// ClearSuggestion очищает состояние предложения вопроса
+func (r *ChatRepository) ClearSuggestion(chatID uint) error {
+	return r.updateChat(chatID, map[string]interface{}{
+		"suggestion_draft":      nil,
+		"suggestion_step":       nil,
+		"suggestion_expires_at": nil,
+	})
+}
 
-	chat.FeedbackExpiresAt = nil
+// SetModeration устанавливает ожидание ввода администратора по вопросу
+func (r *ChatRepository) SetModeration(chatID uint, questionID uint, action string, expiresIn time.Duration) error {
+	expiresAt := time.Now().UTC().Add(expiresIn)
+	return r.updateChat(chatID, map[string]interface{}{
+		"moderation_question_id": questionID,
+		"moderation_action":      action,
+		"moderation_expires_at":  expiresAt,
+	})
+}
+
+// ClearModeration очищает ожидание ввода администратора
+func (r *ChatRepository) ClearModeration(chatID uint) error {
+	return r.updateChat(chatID, map[string]interface{}{
+		"moderation_question_id": nil,
+		"moderation_action":      nil,
+		"moderation_expires_at":  nil,
+	})
+}
+
+// SetReplyFeedback устанавливает ожидание ответа администратора на обратную связь
+func (r *ChatRepository) SetReplyFeedback(chatID uint, feedbackID uint, expiresIn time.Duration) error {
+	expiresAt := time.Now().UTC().Add(expiresIn)
+	return r.updateChat(chatID, map[string]interface{}{
+		"reply_feedback_id":         feedbackID,
+		"reply_feedback_expires_at": expiresAt,
+	})
+}
+
+// ClearReplyFeedback очищает ожидание ответа на обратную связь
+func (r *ChatRepository) ClearReplyFeedback(chatID uint) error {
+	return r.updateChat(chatID, map[string]interface{}{
+		"reply_feedback_id":         nil,
+		"reply_feedback_expires_at": nil,
+	})
+}
+
+// updateChat обновляет только указанные колонки чата, не перезаписывая остальные
+func (r *ChatRepository) updateChat(chatID uint, columns map[string]interface{}) error {
+	return r.db.Model(&models.Chat{}).Where("id = ?", chatID).Updates(columns).Error
+}
+
+// SetDisplayName задает имя чата в таблице лидеров
+func (r *ChatRepository) SetDisplayName(chatID uint, name *string) error {
+	return r.updateChat(chatID, map[string]interface{}{
+		"display_name": name,
+	})
+}
+
+// SetLeaderboardHidden скрывает чат из таблицы лидеров или возвращает его
+func (r *ChatRepository) SetLeaderboardHidden(chatID uint, hidden bool) error {
+	return r.updateChat(chatID, map[string]interface{}{
+		"leaderboard_hidden": hidden,
+	})
+}
+
+// SetBlitzMode включает или выключает блиц
+func (r *ChatRepository) SetBlitzMode(chatID uint, enabled bool) error {
+	return r.updateChat(chatID, map[string]interface{}{
+		"blitz_mode": enabled,
+	})
+}
+
+// SetDifficultyMode задает режим подбора вопросов по сложности
+func (r *ChatRepository) SetDifficultyMode(chatID uint, mode models.DifficultyMode) error {
+	return r.updateChat(chatID, map[string]interface{}{
+		"difficulty_mode": mode,
+	})
+}
+
+// SetReview устанавливает ожидание ответа на вопрос повторения; сообщение вопроса запоминается в SetLastMessage
+func (r *ChatRepository) SetReview(chatID uint, questionID uint, expiresIn time.Duration) error {
+	expiresAt := time.Now().UTC().Add(expiresIn)
+	return r.updateChat(chatID, map[string]interface{}{
+		"review_question_id": questionID,
+		"review_expires_at":  expiresAt,
+		"last_message_id":    nil,
+	})
+}
+
+// ClearReview очищает ожидание ответа на вопрос повторения
+func (r *ChatRepository) ClearReview(chatID uint) error {
+	return r.updateChat(chatID, map[string]interface{}{
+		"review_question_id": nil,
+		"review_expires_at":  nil,
+	})
+}
 
-	return r.db.Save(chat).Error
+// SetDailyEnabled подписывает чат на вопрос дня или отписывает
+func (r *ChatRepository) SetDailyEnabled(chatID uint, enabled bool) error {
+	return r.updateChat(chatID, map[string]interface{}{
+		"daily_enabled": enabled,
+	})
 }
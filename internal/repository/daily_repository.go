@@ -0,0 +1,196 @@
+package repository
+
+import (
+	"errors"
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+	"qweasley/internal/database"
+	"qweasley/internal/models"
+	"time"
+)
+
+// DailyRepository репозиторий для работы с вопросами дня
+type DailyRepository struct {
+	db *gorm.DB
+}
+
+// NewDailyRepository создает новый репозиторий вопросов дня
+func NewDailyRepository() *DailyRepository {
+	return &DailyRepository{
+		db: database.GetDB(),
+	}
+}
+
+// Start в одной транзакции выбирает вопрос дня и создает отправки подписанным чатам.
+// Параллельный запуск на тот же день отсекает уникальный индекс по дню
+func (r *DailyRepository) Start(day time.Time, expiresAt time.Time) (*models.DailyQuestion, error) {
+	var daily *models.DailyQuestion
+	err := r.db.Transaction(func(tx *gorm.DB) error {
+		var started int64
+		if err := tx.Model(&models.DailyQuestion{}).Where("day = ?", day).Count(&started).Error; err != nil {
+			return err
+		}
+		if started > 0 {
+			return nil
+		}
+
+		// Без подписчиков вопрос из очереди не расходуем
+		var subscribed int64
+		if err := tx.Model(&models.Chat{}).Where("daily_enabled").Count(&subscribed).Error; err != nil {
+			return err
+		}
+		if subscribed == 0 {
+			return nil
+		}
+
+		var questionIDs []uint
+		err := tx.Raw(`
+			SELECT q.id FROM questions q
+			WHERE q.is_published
+			  AND (q.daily_queued_at IS NOT NULL OR q.answer_count >= ?)
+			  AND NOT EXISTS (SELECT 1 FROM daily_questions d WHERE d.question_id = q.id)
+			ORDER BY q.daily_queued_at NULLS LAST, q.answer_count DESC, q.id
+			LIMIT 1
+		`, models.RatingMinAnswers).Scan(&questionIDs).Error
+		if err != nil {
+			return err
+		}
+		if len(questionIDs) == 0 {
+			return nil
+		}
+
+		candidate := models.DailyQuestion{Day: day, QuestionID: questionIDs[0], ExpiresAt: expiresAt}
+		result := tx.Clauses(clause.OnConflict{DoNothing: true}).Create(&candidate)
+		if result.Error != nil {
+			return result.Error
+		}
+		if result.RowsAffected == 0 {
+			return nil
+		}
+
+		err = tx.Exec(`
+			INSERT INTO daily_deliveries (daily_question_id, chat_id)
+			SELECT ?, id FROM chats WHERE daily_enabled
+		`, candidate.ID).Error
+		if err != nil {
+			return err
+		}
+
+		daily = &candidate
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	return daily, nil
+}
+
+// GetByQuestionID возвращает вопрос дня по вопросу
+func (r *DailyRepository) GetByQuestionID(questionID uint) (*models.DailyQuestion, error) {
+	var daily models.DailyQuestion
+	err := r.db.Where("question_id = ?", questionID).First(&daily).Error
+	if err != nil {
+		return nil, err
+	}
+	return &daily, nil
+}
+
+// GetPending возвращает неотправленные отправки подписанным чатам по порядку чатов
+func (r *DailyRepository) GetPending(dailyID uint, limit int) ([]models.DailyDelivery, error) {
+	var deliveries []models.DailyDelivery
+	err := r.db.Preload("Chat").
+		Where("daily_question_id = ? AND sent_at IS NULL AND failed_at IS NULL", dailyID).
+		Where("chat_id IN (SELECT id FROM chats WHERE daily_enabled)").
+		Order("chat_id").
+		Limit(limit).
+		Find(&deliveries).Error
+	return deliveries, err
+}
+
+// MarkSent отмечает отправку доставленной
+func (r *DailyRepository) MarkSent(dailyID, chatID uint) error {
+	return r.updateDelivery(dailyID, chatID, "sent_at")
+}
+
+// MarkFailed отмечает отправку недоставленной; повторно она не отправляется
+func (r *DailyRepository) MarkFailed(dailyID, chatID uint) error {
+	return r.updateDelivery(dailyID, chatID, "failed_at")
+}
+
+// GetUnsummarized возвращает доставленные отправки подписанным чатам без итогов по порядку чатов
+func (r *DailyRepository) GetUnsummarized(dailyID uint, limit int) ([]models.DailyDelivery, error) {
+	var deliveries []models.DailyDelivery
+	err := r.db.Preload("Chat").
+		Where("daily_question_id = ? AND sent_at IS NOT NULL AND summary_sent_at IS NULL", dailyID).
+		Where("chat_id IN (SELECT id FROM chats WHERE daily_enabled)").
+		Order("chat_id").
+		Limit(limit).
+		Find(&deliveries).Error
+	return deliveries, err
+}
+
+// MarkSummarySent отмечает итоги отправленными
+func (r *DailyRepository) MarkSummarySent(dailyID, chatID uint) error {
+	return r.updateDelivery(dailyID, chatID, "summary_sent_at")
+}
+
+// GetOpen возвращает самый свежий доставленный чату вопрос дня, на который еще можно ответить, или nil
+func (r *DailyRepository) GetOpen(chatID uint, now time.Time) (*models.DailyQuestion, error) {
+	var daily models.DailyQuestion
+	err := r.db.Joins("JOIN daily_deliveries ON daily_deliveries.daily_question_id = daily_questions.id").
+		Where("daily_deliveries.chat_id = ? AND daily_deliveries.sent_at IS NOT NULL AND daily_deliveries.answered_at IS NULL", chatID).
+		Where("daily_questions.expires_at > ?", now).
+		Order("daily_questions.day DESC").
+		First(&daily).Error
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	return &daily, nil
+}
+
+// MarkAnswered отмечает ответ только один раз, даже если ответы пришли одновременно
+func (r *DailyRepository) MarkAnswered(dailyID, chatID uint) (bool, error) {
+	result := r.db.Model(&models.DailyDelivery{}).
+		Where("daily_question_id = ? AND chat_id = ? AND answered_at IS NULL", dailyID, chatID).
+		Update("answered_at", time.Now().UTC())
+	if result.Error != nil {
+		return false, result.Error
+	}
+	return result.RowsAffected > 0, nil
+}
+
+// Summary считает доставленные отправки и ответы
+func (r *DailyRepository) Summary(dailyID uint) (int, int, error) {
+	var summary struct {
+		Sent     int
+		Answered int
+	}
+	err := r.db.Model(&models.DailyDelivery{}).
+		Select("COUNT(sent_at) AS sent, COUNT(answered_at) AS answered").
+		Where("daily_question_id = ?", dailyID).
+		Scan(&summary).Error
+	return summary.Sent, summary.Answered, err
+}
+
+// Queue ставит вопросы в очередь вопросов дня
+func (r *DailyRepository) Queue(questionIDs []uint) (int, error) {
+	if len(questionIDs) == 0 {
+		return 0, nil
+	}
+
+	result := r.db.Model(&models.Question{}).
+		Where("id IN ? AND is_published AND daily_queued_at IS NULL", questionIDs).
+		Where("NOT EXISTS (SELECT 1 FROM daily_questions WHERE daily_questions.question_id = questions.id)").
+		Update("daily_queued_at", time.Now().UTC())
+	return int(result.RowsAffected), result.Error
+}
+
+// updateDelivery отмечает в отправке время события в колонке column
+func (r *DailyRepository) updateDelivery(dailyID, chatID uint, column string) error {
+	return r.db.Model(&models.DailyDelivery{}).
+		Where("daily_question_id = ? AND chat_id = ?", dailyID, chatID).
+		Update(column, time.Now().UTC()).Error
+}
@@ -4,6 +4,7 @@ import (
 	"gorm.io/gorm"
 	"qweasley/internal/database"
 	"qweasley/internal/models"
+	"time"
 )
 
 // FeedbackRepository репозиторий для работы с обратной связью
@@ -22,3 +23,54 @@ func NewFeedbackRepository() *FeedbackRepository {
 func (r *FeedbackRepository) Create(feedback *models.Feedback) error {
 	return r.db.Create(feedback).Error
 }
+
+// GetByID получает обратную связь по ID вместе с чатом
+func (r *FeedbackRepository) GetByID(id uint) (*models.Feedback, error) {
+	var feedback models.Feedback
+	err := r.db.Preload("Chat").Where("id = ?", id).First(&feedback).Error
+	if err != nil {
+		return nil, err
+	}
+	return &feedback, nil
+}
+
+// GetByAdminMessageID получает обратную связь по ID сообщения в чате администратора
+func (r *FeedbackRepository) GetByAdminMessageID(messageID int) (*models.Feedback, error) {
+	var feedback models.Feedback
+	err := r.db.Preload("Chat").Where("admin_message_id = ?", messageID).First(&feedback).Error
+	if err != nil {
+		return nil, err
+	}
+	return &feedback, nil
+}
+
+// SetAdminMessageID сохраняет ID сообщения с обратной связью в чате администратора
+func (r *FeedbackRepository) SetAdminMessageID(id uint, messageID int) error {
+	return r.db.Model(&models.Feedback{}).Where("id = ?", id).Update("admin_message_id", messageID).Error
+}
+
+// SaveResponse сохраняет ответ администратора
+func (r *FeedbackRepository) SaveResponse(id uint, response string) error {
+	return r.db.Model(&models.Feedback{}).Where("id = ?", id).Updates(map[string]interface{}{
+		"response":     response,
+		"responded_at": time.Now().UTC(),
+	}).Error
+}
+
+// GetUnanswered получает самые старые сообщения обратной связи без ответа
+func (r *FeedbackRepository) GetUnanswered(limit int) ([]models.Feedback, error) {
+	var feedbacks []models.Feedback
+	err := r.db.Preload("Chat").
+		Where("response IS NULL").
+		Order("created_at ASC").
+		Limit(limit).
+		Find(&feedbacks).Error
+	return feedbacks, err
+}
+
+// CountUnanswered считает сообщения обратной связи без ответа
+func (r *FeedbackRepository) CountUnanswered() (int64, error) {
+	var count int64
+	err := r.db.Model(&models.Feedback{}).Where("response IS NULL").Count(&count).Error
+	return count, err
+}
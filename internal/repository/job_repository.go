@@ -0,0 +1,108 @@
+package repository
+
+import (
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+	"qweasley/internal/database"
+	"qweasley/internal/models"
+	"sort"
+	"time"
+)
+
+// JobRepository репозиторий для работы с отложенными задачами
+type JobRepository struct {
+	db *gorm.DB
+}
+
+// NewJobRepository создает новый репозиторий отложенных задач
+func NewJobRepository() *JobRepository {
+	return &JobRepository{
+		db: database.GetDB(),
+	}
+}
+
+// Schedule добавляет задачи в очередь
+func (r *JobRepository) Schedule(jobs ...*models.ScheduledJob) error {
+	if len(jobs) == 0 {
+		return nil
+	}
+	return r.db.Create(jobs).Error
+}
+
+// ClaimDue одним запросом забирает наступившие задачи на время lease; строки, уже забранные
+// другим планировщиком, пропускаются
+func (r *JobRepository) ClaimDue(now time.Time, limit int, lease time.Duration) ([]models.ScheduledJob, error) {
+	return r.claim("TRUE", nil, now, limit, lease)
+}
+
+// ClaimDueForChat забирает наступившие задачи чата с указанным Telegram ID
+func (r *JobRepository) ClaimDueForChat(telegramID int64, now time.Time, limit int, lease time.Duration) ([]models.ScheduledJob, error) {
+	return r.claim("chat_id IN (SELECT id FROM chats WHERE telegram_id = ?)", []interface{}{telegramID}, now, limit, lease)
+}
+
+// claim забирает до limit наступивших задач, подходящих под условие scope с аргументами scopeArgs
+func (r *JobRepository) claim(scope string, scopeArgs []interface{}, now time.Time, limit int, lease time.Duration) ([]models.ScheduledJob, error) {
+	args := append([]interface{}{now.Add(lease), now, now}, scopeArgs...)
+	args = append(args, limit)
+
+	var jobs []models.ScheduledJob
+	err := r.db.Raw(`
+		UPDATE scheduled_jobs
+		SET locked_until = ?, attempts = attempts + 1
+		WHERE id IN (
+			SELECT id FROM scheduled_jobs
+			WHERE run_at <= ? AND (locked_until IS NULL OR locked_until <= ?) AND `+scope+`
+			ORDER BY run_at
+			LIMIT ?
+			FOR UPDATE SKIP LOCKED
+		)
+		RETURNING *
+	`, args...).Scan(&jobs).Error
+	if err != nil {
+		return nil, err
+	}
+
+	sortJobs(jobs)
+	return jobs, nil
+}
+
+// Complete удаляет выполненную задачу; задачу, которую уже перенесли или забрал другой планировщик, не трогает
+func (r *JobRepository) Complete(job *models.ScheduledJob) error {
+	return r.db.Where("id = ? AND locked_until = ?", job.ID, job.LockedUntil).Delete(&models.ScheduledJob{}).Error
+}
+
+// Retry возвращает забранную задачу в очередь с новым сроком
+func (r *JobRepository) Retry(job *models.ScheduledJob, runAt time.Time) error {
+	return r.release(job, map[string]interface{}{"run_at": runAt, "locked_until": nil})
+}
+
+// Reschedule переносит забранную задачу на новый срок и сбрасывает число попыток
+func (r *JobRepository) Reschedule(job *models.ScheduledJob, runAt time.Time) error {
+	return r.release(job, map[string]interface{}{"run_at": runAt, "locked_until": nil, "attempts": 0})
+}
+
+// release обновляет задачу, если она все еще забрана этим планировщиком
+func (r *JobRepository) release(job *models.ScheduledJob, changes map[string]interface{}) error {
+	return r.db.Model(&models.ScheduledJob{}).
+		Where("id = ? AND locked_until = ?", job.ID, job.LockedUntil).
+		Updates(changes).Error
+}
+
+// ScheduleOnce добавляет задачу; если задача того же типа уже в очереди, уникальный индекс не дает добавить вторую
+func (r *JobRepository) ScheduleOnce(job *models.ScheduledJob) (bool, error) {
+	result := r.db.Clauses(clause.OnConflict{DoNothing: true}).Create(job)
+	if result.Error != nil {
+		return false, result.Error
+	}
+	return result.RowsAffected > 0, nil
+}
+
+// sortJobs упорядочивает задачи по времени запуска (RETURNING не сохраняет порядок подзапроса)
+func sortJobs(jobs []models.ScheduledJob) {
+	sort.Slice(jobs, func(i, j int) bool {
+		if !jobs[i].RunAt.Equal(jobs[j].RunAt) {
+			return jobs[i].RunAt.Before(jobs[j].RunAt)
+		}
+		return jobs[i].ID < jobs[j].ID
+	})
+}
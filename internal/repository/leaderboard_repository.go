@@ -0,0 +1,63 @@
+package repository
+
+import (
+	"gorm.io/gorm"
+	"qweasley/internal/database"
+	"qweasley/internal/models"
+	"time"
+)
+
+// LeaderboardRepository репозиторий для работы с таблицами лидеров
+type LeaderboardRepository struct {
+	db *gorm.DB
+}
+
+// NewLeaderboardRepository создает новый репозиторий таблиц лидеров
+func NewLeaderboardRepository() *LeaderboardRepository {
+	return &LeaderboardRepository{
+		db: database.GetDB(),
+	}
+}
+
+// GetTop возвращает лучшие чаты за период по готовым итогам, без обращения к реакциям
+func (r *LeaderboardRepository) GetTop(period models.LeaderboardPeriod, start time.Time, order LeaderboardOrder, minResolved int, limit int) ([]models.LeaderboardScore, error) {
+	query := r.db.Joins("Chat").
+		Where("leaderboard_scores.period = ? AND leaderboard_scores.period_start = ?", period, start).
+		Where(`"Chat".leaderboard_hidden = false`).
+		Where("leaderboard_scores.answered + leaderboard_scores.revealed >= ?", minResolved)
+
+	if order == LeaderboardByAccuracy {
+		query = query.Order("leaderboard_scores.correct_weight / NULLIF(leaderboard_scores.total_weight, 0) DESC NULLS LAST")
+	}
+
+	var scores []models.LeaderboardScore
+	err := query.Order("leaderboard_scores.answered DESC").
+		Order("leaderboard_scores.chat_id").
+		Limit(limit).
+		Find(&scores).Error
+	return scores, err
+}
+
+// addScore одним запросом добавляет закрытый вопрос в итоги чата за день, неделю и все время
+func addScore(db *gorm.DB, chatID, questionID uint, correct bool, now time.Time) error {
+	answered, revealed := 0, 1
+	if correct {
+		answered, revealed = 1, 0
+	}
+
+	return db.Exec(`
+		INSERT INTO leaderboard_scores (chat_id, period, period_start, answered, revealed, total_weight, correct_weight)
+		SELECT ?, p.period, p.period_start, ?, ?, q.weight, q.weight * ?
+		FROM (SELECT 1 + (100 - COALESCE(rating, 0)) / 100.0 AS weight FROM questions WHERE id = ?) q
+		CROSS JOIN (VALUES (?, ?::date), (?, ?::date), (?, ?::date)) AS p (period, period_start)
+		ON CONFLICT (chat_id, period, period_start) DO UPDATE
+		SET answered       = leaderboard_scores.answered + EXCLUDED.answered,
+		    revealed       = leaderboard_scores.revealed + EXCLUDED.revealed,
+		    total_weight   = leaderboard_scores.total_weight + EXCLUDED.total_weight,
+		    correct_weight = leaderboard_scores.correct_weight + EXCLUDED.correct_weight
+	`, chatID, answered, revealed, answered, questionID,
+		models.LeaderboardDay, models.LeaderboardDay.Start(now),
+		models.LeaderboardWeek, models.LeaderboardWeek.Start(now),
+		models.LeaderboardAllTime, models.LeaderboardAllTime.Start(now),
+	).Error
+}
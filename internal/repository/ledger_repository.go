@@ -0,0 +1,85 @@
+package repository
+
+import (
+	"gorm.io/gorm"
+	"qweasley/internal/database"
+	"qweasley/internal/models"
+)
+
+// LedgerRepository репозиторий для работы с журналом монет
+type LedgerRepository struct {
+	db *gorm.DB
+}
+
+// NewLedgerRepository создает новый репозиторий журнала монет
+func NewLedgerRepository() *LedgerRepository {
+	return &LedgerRepository{
+		db: database.GetDB(),
+	}
+}
+
+// Apply изменяет баланс чата и записывает операцию в журнал в одной транзакции
+func (r *LedgerRepository) Apply(entry *models.CoinTransaction) error {
+	return r.db.Transaction(func(tx *gorm.DB) error {
+		return applyTransaction(tx, entry)
+	})
+}
+
+// applyTransaction изменяет баланс и пишет журнал внутри уже открытой транзакции
+func applyTransaction(tx *gorm.DB, entry *models.CoinTransaction) error {
+	var balance int
+	err := tx.Raw("UPDATE chats SET balance = balance + ? WHERE id = ? RETURNING balance", entry.Delta, entry.ChatID).
+		Scan(&balance).Error
+	if err != nil {
+		return err
+	}
+
+	entry.BalanceAfter = balance
+	return tx.Create(entry).Error
+}
+
+// GetHistory получает последние операции чата
+func (r *LedgerRepository) GetHistory(chatID uint, limit int) ([]models.CoinTransaction, error) {
+	var transactions []models.CoinTransaction
+	err := r.db.Where("chat_id = ?", chatID).
+		Order("id DESC").
+		Limit(limit).
+		Find(&transactions).Error
+	return transactions, err
+}
+
+// Sum считает баланс чата по журналу
+func (r *LedgerRepository) Sum(chatID uint) (int, error) {
+	var sum int
+	err := r.db.Model(&models.CoinTransaction{}).
+		Where("chat_id = ?", chatID).
+		Select("COALESCE(SUM(delta), 0)").
+		Scan(&sum).Error
+	return sum, err
+}
+
+// Reconciliation результат сверки баланса с журналом
+type Reconciliation struct {
+	Balance   int
+	LedgerSum int
+}
+
+// Consistent возвращает true, если баланс совпадает с суммой по журналу
+func (r Reconciliation) Consistent() bool {
+	return r.Balance == r.LedgerSum
+}
+
+// Reconcile сверяет баланс чата с суммой операций в журнале
+func (r *LedgerRepository) Reconcile(chatID uint) (*Reconciliation, error) {
+	var chat models.Chat
+	if err := r.db.Select("balance").First(&chat, chatID).Error; err != nil {
+		return nil, err
+	}
+
+	sum, err := r.Sum(chatID)
+	if err != nil {
+		return nil, err
+	}
+
+	return &Reconciliation{Balance: chat.Balance, LedgerSum: sum}, nil
+}
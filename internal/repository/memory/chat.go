@@ -0,0 +1,222 @@
+package memory
+
+import (
+	"gorm.io/gorm"
+	"qweasley/internal/models"
+	"time"
+)
+
+// chatStore хранилище чатов в памяти
+type chatStore struct {
+	*db
+}
+
+// GetOrCreate получает существующий чат или создает новый с начальным бонусом
+func (s *chatStore) GetOrCreate(telegramID int64, title *string) (*models.Chat, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	for _, chat := range s.chats {
+		if chat.TelegramID == telegramID {
+			result := *chat
+			return &result, nil
+		}
+	}
+
+	chat := &models.Chat{
+		ID:             s.nextID(),
+		CreatedAt:      time.Now().UTC(),
+		TelegramID:     telegramID,
+		Title:          title,
+		Skill:          models.InitialElo,
+		DifficultyMode: models.DifficultyRandom,
+	}
+	s.chats[chat.ID] = chat
+
+	if err := s.apply(&models.CoinTransaction{ChatID: chat.ID, Delta: 30, Reason: models.ReasonSignupBonus}); err != nil {
+		return nil, err
+	}
+
+	result := *chat
+	return &result, nil
+}
+
+// GetByID получает чат по ID
+func (s *chatStore) GetByID(chatID uint) (*models.Chat, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	chat, ok := s.copyChat(chatID)
+	if !ok {
+		return nil, gorm.ErrRecordNotFound
+	}
+	return &chat, nil
+}
+
+// update применяет изменение к чату под блокировкой
+func (s *chatStore) update(chatID uint, change func(chat *models.Chat)) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	chat, ok := s.chats[chatID]
+	if !ok {
+		return gorm.ErrRecordNotFound
+	}
+	change(chat)
+	return nil
+}
+
+// SetWaitingAnswer устанавливает ожидание ответа на вопрос и прерывает начатое повторение
+func (s *chatStore) SetWaitingAnswer(chatID uint, questionID uint, expiresIn time.Duration) error {
+	return s.update(chatID, func(chat *models.Chat) {
+		expiresAt := time.Now().UTC().Add(expiresIn)
+		chat.LastQuestionID = &questionID
+		chat.ExpiresAt = &expiresAt
+		chat.LastMessageID = nil
+		chat.ReviewQuestionID = nil
+		chat.ReviewExpiresAt = nil
+	})
+}
+
+// SetLastMessage запоминает сообщение, в котором отправлен текущий вопрос
+func (s *chatStore) SetLastMessage(chatID uint, messageID int) error {
+	return s.update(chatID, func(chat *models.Chat) {
+		chat.LastMessageID = &messageID
+	})
+}
+
+// ClearWaitingAnswer очищает ожидание ответа
+func (s *chatStore) ClearWaitingAnswer(chatID uint) error {
+	return s.update(chatID, func(chat *models.Chat) {
+		chat.LastQuestionID = nil
+		chat.ExpiresAt = nil
+	})
+}
+
+// SetWaitingFeedback устанавливает ожидание обратной связи
+func (s *chatStore) SetWaitingFeedback(chatID uint, expiresIn time.Duration) error {
+	return s.update(chatID, func(chat *models.Chat) {
+		expiresAt := time.Now().UTC().Add(expiresIn)
+		chat.FeedbackExpiresAt = &expiresAt
+	})
+}
+
+// ClearWaitingFeedback очищает ожидание обратной связи
+func (s *chatStore) ClearWaitingFeedback(chatID uint) error {
+	return s.update(chatID, func(chat *models.Chat) {
+		chat.FeedbackExpiresAt = nil
+	})
+}
+
+// SetSuggestionStep устанавливает шаг предложения вопроса
+func (s *chatStore) SetSuggestionStep(chatID uint, draft *models.SuggestionDraft, step string, expiresIn time.Duration) error {
+	return s.update(chatID, func(chat *models.Chat) {
+		expiresAt := time.Now().UTC().Add(expiresIn)
+		chat.SuggestionDraft = nil
+		if draft != nil {
+			stored := *draft
+			stored.Hints = append([]string(nil), draft.Hints...)
+			chat.SuggestionDraft = &stored
+		}
+		chat.SuggestionStep = &step
+		chat.SuggestionExpiresAt = &expiresAt
+	})
+}
+
+// ClearSuggestion очищает состояние предложения вопроса
+func (s *chatStore) ClearSuggestion(chatID uint) error {
+	return s.update(chatID, func(chat *models.Chat) {
+		chat.SuggestionDraft = nil
+		chat.SuggestionStep = nil
+		chat.SuggestionExpiresAt = nil
+	})
+}
+
+// SetModeration устанавливает ожидание ввода администратора по вопросу
+func (s *chatStore) SetModeration(chatID uint, questionID uint, action string, expiresIn time.Duration) error {
+	return s.update(chatID, func(chat *models.Chat) {
+		expiresAt := time.Now().UTC().Add(expiresIn)
+		chat.ModerationQuestionID = &questionID
+		chat.ModerationAction = &action
+		chat.ModerationExpiresAt = &expiresAt
+	})
+}
+
+// ClearModeration очищает ожидание ввода администратора
+func (s *chatStore) ClearModeration(chatID uint) error {
+	return s.update(chatID, func(chat *models.Chat) {
+		chat.ModerationQuestionID = nil
+		chat.ModerationAction = nil
+		chat.ModerationExpiresAt = nil
+	})
+}
+
+// SetReplyFeedback устанавливает ожидание ответа администратора на обратную связь
+func (s *chatStore) SetReplyFeedback(chatID uint, feedbackID uint, expiresIn time.Duration) error {
+	return s.update(chatID, func(chat *models.Chat) {
+		expiresAt := time.Now().UTC().Add(expiresIn)
+		chat.ReplyFeedbackID = &feedbackID
+		chat.ReplyFeedbackExpiresAt = &expiresAt
+	})
+}
+
+// ClearReplyFeedback очищает ожидание ответа на обратную связь
+func (s *chatStore) ClearReplyFeedback(chatID uint) error {
+	return s.update(chatID, func(chat *models.Chat) {
+		chat.ReplyFeedbackID = nil
+		chat.ReplyFeedbackExpiresAt = nil
+	})
+}
+
+// SetDisplayName задает имя чата в таблице лидеров
+func (s *chatStore) SetDisplayName(chatID uint, name *string) error {
+	return s.update(chatID, func(chat *models.Chat) {
+		chat.DisplayName = name
+	})
+}
+
+// SetLeaderboardHidden скрывает чат из таблицы лидеров или возвращает его
+func (s *chatStore) SetLeaderboardHidden(chatID uint, hidden bool) error {
+	return s.update(chatID, func(chat *models.Chat) {
+		chat.LeaderboardHidden = hidden
+	})
+}
+
+// SetBlitzMode включает или выключает блиц
+func (s *chatStore) SetBlitzMode(chatID uint, enabled bool) error {
+	return s.update(chatID, func(chat *models.Chat) {
+		chat.BlitzMode = enabled
+	})
+}
+
+// SetDifficultyMode задает режим подбора вопросов по сложности
+func (s *chatStore) SetDifficultyMode(chatID uint, mode models.DifficultyMode) error {
+	return s.update(chatID, func(chat *models.Chat) {
+		chat.DifficultyMode = mode
+	})
+}
+
+// SetReview устанавливает ожидание ответа на вопрос повторения; сообщение вопроса запоминается в SetLastMessage
+func (s *chatStore) SetReview(chatID uint, questionID uint, expiresIn time.Duration) error {
+	return s.update(chatID, func(chat *models.Chat) {
+		expiresAt := time.Now().UTC().Add(expiresIn)
+		chat.ReviewQuestionID = &questionID
+		chat.ReviewExpiresAt = &expiresAt
+		chat.LastMessageID = nil
+	})
+}
+
+// ClearReview очищает ожидание ответа на вопрос повторения
+func (s *chatStore) ClearReview(chatID uint) error {
+	return s.update(chatID, func(chat *models.Chat) {
+		chat.ReviewQuestionID = nil
+		chat.ReviewExpiresAt = nil
+	})
+}
+
+// SetDailyEnabled подписывает чат на вопрос дня или отписывает
+func (s *chatStore) SetDailyEnabled(chatID uint, enabled bool) error {
+	return s.update(chatID, func(chat *models.Chat) {
+		chat.DailyEnabled = enabled
+	})
+}
@@ -0,0 +1,266 @@
+package memory
+
+import (
+	"gorm.io/gorm"
+	"qweasley/internal/models"
+	"sort"
+	"time"
+)
+
+// dailyDeliveryKey отправка одного вопроса дня одному чату
+type dailyDeliveryKey struct {
+	dailyID uint
+	chatID  uint
+}
+
+// dailyStore хранилище вопросов дня в памяти
+type dailyStore struct {
+	*db
+}
+
+// Start выбирает вопрос дня и создает отправки подписанным чатам
+func (s *dailyStore) Start(day time.Time, expiresAt time.Time) (*models.DailyQuestion, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	used := make(map[uint]bool)
+	for _, daily := range s.dailyQuestions {
+		if daily.Day.Equal(day) {
+			return nil, nil
+		}
+		used[daily.QuestionID] = true
+	}
+
+	// Без подписчиков вопрос из очереди не расходуем
+	subscribed := false
+	for _, chat := range s.chats {
+		subscribed = subscribed || chat.DailyEnabled
+	}
+	if !subscribed {
+		return nil, nil
+	}
+
+	// Сначала самый ранний вопрос из очереди, затем самый отвечаемый
+	var picked *models.Question
+	for _, question := range s.questions {
+		if !question.IsPublished || used[question.ID] {
+			continue
+		}
+		if question.DailyQueuedAt == nil && question.AnswerCount < models.RatingMinAnswers {
+			continue
+		}
+		if picked == nil || dailyBefore(question, picked) {
+			picked = question
+		}
+	}
+	if picked == nil {
+		return nil, nil
+	}
+
+	daily := &models.DailyQuestion{
+		ID:         s.nextID(),
+		CreatedAt:  time.Now().UTC(),
+		Day:        day,
+		QuestionID: picked.ID,
+		ExpiresAt:  expiresAt,
+	}
+	s.dailyQuestions[daily.ID] = daily
+
+	for _, chat := range s.chats {
+		if chat.DailyEnabled {
+			key := dailyDeliveryKey{dailyID: daily.ID, chatID: chat.ID}
+			s.dailyDeliveries[key] = &models.DailyDelivery{
+				DailyQuestionID: daily.ID,
+				ChatID:          chat.ID,
+				CreatedAt:       time.Now().UTC(),
+			}
+		}
+	}
+
+	result := *daily
+	return &result, nil
+}
+
+// GetByQuestionID возвращает вопрос дня по вопросу
+func (s *dailyStore) GetByQuestionID(questionID uint) (*models.DailyQuestion, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	for _, daily := range s.dailyQuestions {
+		if daily.QuestionID == questionID {
+			result := *daily
+			return &result, nil
+		}
+	}
+	return nil, gorm.ErrRecordNotFound
+}
+
+// GetPending возвращает неотправленные отправки подписанным чатам по порядку чатов
+func (s *dailyStore) GetPending(dailyID uint, limit int) ([]models.DailyDelivery, error) {
+	return s.deliveries(dailyID, limit, func(delivery *models.DailyDelivery) bool {
+		return delivery.SentAt == nil && delivery.FailedAt == nil
+	}), nil
+}
+
+// GetUnsummarized возвращает доставленные отправки подписанным чатам без итогов по порядку чатов
+func (s *dailyStore) GetUnsummarized(dailyID uint, limit int) ([]models.DailyDelivery, error) {
+	return s.deliveries(dailyID, limit, func(delivery *models.DailyDelivery) bool {
+		return delivery.SentAt != nil && delivery.SummarySentAt == nil
+	}), nil
+}
+
+// MarkSent отмечает отправку доставленной
+func (s *dailyStore) MarkSent(dailyID, chatID uint) error {
+	return s.updateDelivery(dailyID, chatID, func(delivery *models.DailyDelivery, now time.Time) {
+		delivery.SentAt = &now
+	})
+}
+
+// MarkFailed отмечает отправку недоставленной
+func (s *dailyStore) MarkFailed(dailyID, chatID uint) error {
+	return s.updateDelivery(dailyID, chatID, func(delivery *models.DailyDelivery, now time.Time) {
+		delivery.FailedAt = &now
+	})
+}
+
+// MarkSummarySent отмечает итоги отправленными
+func (s *dailyStore) MarkSummarySent(dailyID, chatID uint) error {
+	return s.updateDelivery(dailyID, chatID, func(delivery *models.DailyDelivery, now time.Time) {
+		delivery.SummarySentAt = &now
+	})
+}
+
+// GetOpen возвращает самый свежий доставленный чату вопрос дня, на который еще можно ответить, или nil
+func (s *dailyStore) GetOpen(chatID uint, now time.Time) (*models.DailyQuestion, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	var open *models.DailyQuestion
+	for key, delivery := range s.dailyDeliveries {
+		daily := s.dailyQuestions[key.dailyID]
+		if key.chatID != chatID || delivery.SentAt == nil || delivery.AnsweredAt != nil || !daily.ExpiresAt.After(now) {
+			continue
+		}
+		if open == nil || daily.Day.After(open.Day) {
+			open = daily
+		}
+	}
+	if open == nil {
+		return nil, nil
+	}
+
+	result := *open
+	return &result, nil
+}
+
+// MarkAnswered отмечает ответ чата только один раз
+func (s *dailyStore) MarkAnswered(dailyID, chatID uint) (bool, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	delivery, ok := s.dailyDeliveries[dailyDeliveryKey{dailyID: dailyID, chatID: chatID}]
+	if !ok || delivery.AnsweredAt != nil {
+		return false, nil
+	}
+
+	now := time.Now().UTC()
+	delivery.AnsweredAt = &now
+	return true, nil
+}
+
+// Summary считает доставленные отправки и ответы
+func (s *dailyStore) Summary(dailyID uint) (int, int, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	sent, answered := 0, 0
+	for key, delivery := range s.dailyDeliveries {
+		if key.dailyID != dailyID {
+			continue
+		}
+		if delivery.SentAt != nil {
+			sent++
+		}
+		if delivery.AnsweredAt != nil {
+			answered++
+		}
+	}
+	return sent, answered, nil
+}
+
+// Queue ставит вопросы в очередь вопросов дня
+func (s *dailyStore) Queue(questionIDs []uint) (int, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	used := make(map[uint]bool)
+	for _, daily := range s.dailyQuestions {
+		used[daily.QuestionID] = true
+	}
+
+	queued := 0
+	now := time.Now().UTC()
+	for _, questionID := range questionIDs {
+		question, ok := s.questions[questionID]
+		if !ok || !question.IsPublished || question.DailyQueuedAt != nil || used[questionID] {
+			continue
+		}
+
+		queuedAt := now
+		question.DailyQueuedAt = &queuedAt
+		queued++
+	}
+	return queued, nil
+}
+
+// deliveries возвращает до limit отправок вопроса дня подписанным чатам, подходящих под match, по порядку чатов
+func (s *dailyStore) deliveries(dailyID uint, limit int, match func(delivery *models.DailyDelivery) bool) []models.DailyDelivery {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	var deliveries []models.DailyDelivery
+	for key, delivery := range s.dailyDeliveries {
+		chat, ok := s.chats[key.chatID]
+		if key.dailyID != dailyID || !match(delivery) || !ok || !chat.DailyEnabled {
+			continue
+		}
+
+		result := *delivery
+		result.Chat = *chat
+		deliveries = append(deliveries, result)
+	}
+
+	sort.Slice(deliveries, func(i, j int) bool { return deliveries[i].ChatID < deliveries[j].ChatID })
+	if len(deliveries) > limit {
+		deliveries = deliveries[:limit]
+	}
+	return deliveries
+}
+
+// updateDelivery изменяет отправку под блокировкой
+func (s *dailyStore) updateDelivery(dailyID, chatID uint, update func(delivery *models.DailyDelivery, now time.Time)) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	delivery, ok := s.dailyDeliveries[dailyDeliveryKey{dailyID: dailyID, chatID: chatID}]
+	if !ok {
+		return gorm.ErrRecordNotFound
+	}
+	update(delivery, time.Now().UTC())
+	return nil
+}
+
+// dailyBefore сравнивает вопросы в порядке выбора вопроса дня: очередь по времени добавления,
+// затем самые отвечаемые, затем по ID
+func dailyBefore(a, b *models.Question) bool {
+	if (a.DailyQueuedAt != nil) != (b.DailyQueuedAt != nil) {
+		return a.DailyQueuedAt != nil
+	}
+	if a.DailyQueuedAt != nil && !a.DailyQueuedAt.Equal(*b.DailyQueuedAt) {
+		return a.DailyQueuedAt.Before(*b.DailyQueuedAt)
+	}
+	if a.AnswerCount != b.AnswerCount {
+		return a.AnswerCount > b.AnswerCount
+	}
+	return a.ID < b.ID
+}
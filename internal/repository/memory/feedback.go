@@ -0,0 +1,113 @@
+package memory
+
+import (
+	"gorm.io/gorm"
+	"qweasley/internal/models"
+	"sort"
+	"time"
+)
+
+// feedbackStore хранилище обратной связи в памяти
+type feedbackStore struct {
+	*db
+}
+
+// Create создает новую запись обратной связи
+func (s *feedbackStore) Create(feedback *models.Feedback) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	feedback.ID = s.nextID()
+	feedback.CreatedAt = time.Now().UTC()
+
+	stored := *feedback
+	stored.Chat = models.Chat{}
+	s.feedbacks[stored.ID] = &stored
+	return nil
+}
+
+// GetByID получает обратную связь по ID вместе с чатом
+func (s *feedbackStore) GetByID(id uint) (*models.Feedback, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	feedback, ok := s.feedbacks[id]
+	if !ok {
+		return nil, gorm.ErrRecordNotFound
+	}
+	result := s.copyFeedback(feedback)
+	return &result, nil
+}
+
+// GetByAdminMessageID получает обратную связь по ID сообщения в чате администратора
+func (s *feedbackStore) GetByAdminMessageID(messageID int) (*models.Feedback, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	for _, feedback := range s.feedbacks {
+		if feedback.AdminMessageID != nil && *feedback.AdminMessageID == messageID {
+			result := s.copyFeedback(feedback)
+			return &result, nil
+		}
+	}
+	return nil, gorm.ErrRecordNotFound
+}
+
+// SetAdminMessageID сохраняет ID сообщения с обратной связью в чате администратора
+func (s *feedbackStore) SetAdminMessageID(id uint, messageID int) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if feedback, ok := s.feedbacks[id]; ok {
+		feedback.AdminMessageID = &messageID
+	}
+	return nil
+}
+
+// SaveResponse сохраняет ответ администратора
+func (s *feedbackStore) SaveResponse(id uint, response string) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if feedback, ok := s.feedbacks[id]; ok {
+		now := time.Now().UTC()
+		feedback.Response = &response
+		feedback.RespondedAt = &now
+	}
+	return nil
+}
+
+// unanswered возвращает сообщения без ответа от старых к новым; вызывается под блокировкой
+func (s *feedbackStore) unanswered() []*models.Feedback {
+	var feedbacks []*models.Feedback
+	for _, feedback := range s.feedbacks {
+		if feedback.Response == nil {
+			feedbacks = append(feedbacks, feedback)
+		}
+	}
+	sort.Slice(feedbacks, func(i, j int) bool { return feedbacks[i].ID < feedbacks[j].ID })
+	return feedbacks
+}
+
+// GetUnanswered получает самые старые сообщения обратной связи без ответа
+func (s *feedbackStore) GetUnanswered(limit int) ([]models.Feedback, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	var result []models.Feedback
+	for _, feedback := range s.unanswered() {
+		if len(result) == limit {
+			break
+		}
+		result = append(result, s.copyFeedback(feedback))
+	}
+	return result, nil
+}
+
+// CountUnanswered считает сообщения обратной связи без ответа
+func (s *feedbackStore) CountUnanswered() (int64, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	return int64(len(s.unanswered())), nil
+}
@@ -0,0 +1,136 @@
+package memory
+
+import (
+	"qweasley/internal/models"
+	"sort"
+	"time"
+)
+
+// jobStore очередь отложенных задач в памяти
+type jobStore struct {
+	*db
+}
+
+// Schedule добавляет задачи в очередь
+func (s *jobStore) Schedule(jobs ...*models.ScheduledJob) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	for _, job := range jobs {
+		s.enqueue(job)
+	}
+	return nil
+}
+
+// ScheduleOnce добавляет задачу, если задачи того же типа в очереди нет
+func (s *jobStore) ScheduleOnce(job *models.ScheduledJob) (bool, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	for _, queued := range s.jobs {
+		if queued.Kind == job.Kind {
+			return false, nil
+		}
+	}
+
+	s.enqueue(job)
+	return true, nil
+}
+
+// ClaimDue забирает наступившие задачи на время lease, самые ранние первыми
+func (s *jobStore) ClaimDue(now time.Time, limit int, lease time.Duration) ([]models.ScheduledJob, error) {
+	return s.claim(func(job *models.ScheduledJob) bool { return true }, now, limit, lease)
+}
+
+// ClaimDueForChat забирает наступившие задачи чата с указанным Telegram ID
+func (s *jobStore) ClaimDueForChat(telegramID int64, now time.Time, limit int, lease time.Duration) ([]models.ScheduledJob, error) {
+	return s.claim(func(job *models.ScheduledJob) bool {
+		if job.ChatID == nil {
+			return false
+		}
+		chat, ok := s.chats[*job.ChatID]
+		return ok && chat.TelegramID == telegramID
+	}, now, limit, lease)
+}
+
+// claim забирает до limit наступивших и не забранных задач, подходящих под match
+func (s *jobStore) claim(match func(job *models.ScheduledJob) bool, now time.Time, limit int, lease time.Duration) ([]models.ScheduledJob, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	sort.SliceStable(s.jobs, func(i, j int) bool { return s.jobs[i].RunAt.Before(s.jobs[j].RunAt) })
+
+	lockedUntil := now.Add(lease)
+	var due []models.ScheduledJob
+	for _, job := range s.jobs {
+		if len(due) == limit {
+			break
+		}
+		if job.RunAt.After(now) || (job.LockedUntil != nil && job.LockedUntil.After(now)) || !match(job) {
+			continue
+		}
+
+		locked := lockedUntil
+		job.LockedUntil = &locked
+		job.Attempts++
+		due = append(due, *job)
+	}
+	return due, nil
+}
+
+// Complete удаляет выполненную задачу, если она все еще забрана этим планировщиком
+func (s *jobStore) Complete(job *models.ScheduledJob) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	for i, queued := range s.jobs {
+		if queued.ID == job.ID && sameLock(queued, job) {
+			s.jobs = append(s.jobs[:i], s.jobs[i+1:]...)
+			break
+		}
+	}
+	return nil
+}
+
+// Retry возвращает забранную задачу в очередь с новым сроком
+func (s *jobStore) Retry(job *models.ScheduledJob, runAt time.Time) error {
+	return s.release(job, runAt, false)
+}
+
+// Reschedule переносит забранную задачу на новый срок и сбрасывает число попыток
+func (s *jobStore) Reschedule(job *models.ScheduledJob, runAt time.Time) error {
+	return s.release(job, runAt, true)
+}
+
+// release возвращает задачу в очередь, если она все еще забрана этим планировщиком
+func (s *jobStore) release(job *models.ScheduledJob, runAt time.Time, resetAttempts bool) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	for _, queued := range s.jobs {
+		if queued.ID == job.ID && sameLock(queued, job) {
+			queued.RunAt = runAt
+			queued.LockedUntil = nil
+			if resetAttempts {
+				queued.Attempts = 0
+			}
+			break
+		}
+	}
+	return nil
+}
+
+// sameLock проверяет, что задача в очереди забрана тем же вызовом, что и job
+func sameLock(queued, job *models.ScheduledJob) bool {
+	return queued.LockedUntil != nil && job.LockedUntil != nil && queued.LockedUntil.Equal(*job.LockedUntil)
+}
+
+// enqueue выдает задаче ID и кладет ее копию в очередь; вызывается под блокировкой
+func (d *db) enqueue(job *models.ScheduledJob) {
+	job.ID = d.nextID()
+	job.CreatedAt = time.Now().UTC()
+
+	stored := *job
+	stored.Chat = models.Chat{}
+	d.jobs = append(d.jobs, &stored)
+}
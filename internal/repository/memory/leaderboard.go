@@ -0,0 +1,94 @@
+package memory
+
+import (
+	"qweasley/internal/models"
+	"qweasley/internal/repository"
+	"sort"
+	"time"
+)
+
+// leaderboardKey итоги чата за один период
+type leaderboardKey struct {
+	chatID uint
+	period models.LeaderboardPeriod
+	start  time.Time
+}
+
+// leaderboardStore хранилище таблиц лидеров в памяти
+type leaderboardStore struct {
+	*db
+}
+
+// GetTop возвращает лучшие чаты за период по готовым итогам
+func (s *leaderboardStore) GetTop(period models.LeaderboardPeriod, start time.Time, order repository.LeaderboardOrder, minResolved int, limit int) ([]models.LeaderboardScore, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	var scores []models.LeaderboardScore
+	for key, score := range s.scores {
+		if key.period != period || !key.start.Equal(start) || score.Answered+score.Revealed < minResolved {
+			continue
+		}
+		chat, ok := s.copyChat(key.chatID)
+		if !ok || chat.LeaderboardHidden {
+			continue
+		}
+
+		result := *score
+		result.Chat = chat
+		scores = append(scores, result)
+	}
+
+	sort.Slice(scores, func(i, j int) bool {
+		a, b := &scores[i], &scores[j]
+		if order == repository.LeaderboardByAccuracy && a.Accuracy() != b.Accuracy() {
+			return a.Accuracy() > b.Accuracy()
+		}
+		if a.Answered != b.Answered {
+			return a.Answered > b.Answered
+		}
+		return a.ChatID < b.ChatID
+	})
+
+	if len(scores) > limit {
+		scores = scores[:limit]
+	}
+	return scores, nil
+}
+
+// addScore добавляет закрытый вопрос в итоги чата за все периоды и возвращает функцию отката; вызывается под блокировкой
+func (d *db) addScore(chatID, questionID uint, correct bool, now time.Time) func() {
+	var rating *int
+	if question, ok := d.questions[questionID]; ok {
+		rating = question.Rating
+	}
+	weight := models.DifficultyWeight(rating)
+
+	var undo []func()
+	for _, period := range models.LeaderboardPeriods {
+		key := leaderboardKey{chatID: chatID, period: period, start: period.Start(now)}
+		score, ok := d.scores[key]
+		if !ok {
+			score = &models.LeaderboardScore{ChatID: chatID, Period: period, PeriodStart: key.start}
+			d.scores[key] = score
+			undo = append(undo, func() { delete(d.scores, key) })
+		} else {
+			previous := *score
+			undo = append(undo, func() { *score = previous })
+		}
+
+		score.TotalWeight += weight
+		if correct {
+			score.Answered++
+			score.CorrectWeight += weight
+		} else {
+			score.Revealed++
+		}
+	}
+
+	return func() {
+		for i := len(undo) - 1; i >= 0; i-- {
+			undo[i]()
+		}
+	}
+}
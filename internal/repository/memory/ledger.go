@@ -0,0 +1,65 @@
+package memory
+
+import (
+	"gorm.io/gorm"
+	"qweasley/internal/models"
+	"qweasley/internal/repository"
+)
+
+// ledgerStore журнал монет в памяти
+type ledgerStore struct {
+	*db
+}
+
+// Apply изменяет баланс чата и записывает операцию в журнал
+func (s *ledgerStore) Apply(entry *models.CoinTransaction) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	return s.apply(entry)
+}
+
+// GetHistory получает последние операции чата
+func (s *ledgerStore) GetHistory(chatID uint, limit int) ([]models.CoinTransaction, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	var history []models.CoinTransaction
+	for i := len(s.transactions) - 1; i >= 0 && len(history) < limit; i-- {
+		if s.transactions[i].ChatID == chatID {
+			history = append(history, *s.transactions[i])
+		}
+	}
+	return history, nil
+}
+
+// sum считает баланс чата по журналу; вызывается под блокировкой
+func (s *ledgerStore) sum(chatID uint) int {
+	total := 0
+	for _, entry := range s.transactions {
+		if entry.ChatID == chatID {
+			total += entry.Delta
+		}
+	}
+	return total
+}
+
+// Sum считает баланс чата по журналу
+func (s *ledgerStore) Sum(chatID uint) (int, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	return s.sum(chatID), nil
+}
+
+// Reconcile сверяет баланс чата с суммой операций в журнале
+func (s *ledgerStore) Reconcile(chatID uint) (*repository.Reconciliation, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	chat, ok := s.chats[chatID]
+	if !ok {
+		return nil, gorm.ErrRecordNotFound
+	}
+	return &repository.Reconciliation{Balance: chat.Balance, LedgerSum: s.sum(chatID)}, nil
+}
@@ -0,0 +1,145 @@
+// Package memory содержит хранилища в памяти процесса с той же семантикой, что и PostgreSQL.
+// Используется для локального запуска и проверки обработчиков без базы данных
+package memory
+
+import (
+	"gorm.io/gorm"
+	"qweasley/internal/models"
+	"qweasley/internal/repository"
+	"sync"
+	"time"
+)
+
+// db общее состояние всех хранилищ; все операции выполняются под одной блокировкой,
+// поэтому составные операции (журнал, переводы, покупки) атомарны так же, как транзакции в базе
+type db struct {
+	mu sync.Mutex
+
+	chats        map[uint]*models.Chat
+	questions    map[uint]*models.Question
+	pictures     map[uint]*models.Picture
+	reactions    map[uint]*models.Reaction
+	feedbacks    map[uint]*models.Feedback
+	transactions []*models.CoinTransaction
+	transfers    map[uint]*models.CoinTransfer
+	purchases    map[uint]*models.Purchase
+	updates      map[int]*models.ProcessedUpdate
+	scores       map[leaderboardKey]*models.LeaderboardScore
+	jobs         []*models.ScheduledJob
+	tags         map[uint]*models.Tag
+	questionTags map[uint]map[uint]bool
+	chatTopics   map[uint]map[uint]bool
+	reviewCards  map[reviewKey]*models.ReviewCard
+
+	dailyQuestions  map[uint]*models.DailyQuestion
+	dailyDeliveries map[dailyDeliveryKey]*models.DailyDelivery
+
+	lastID uint
+}
+
+// NewStores создает набор хранилищ в памяти
+func NewStores() *repository.Stores {
+	d := &db{
+		chats:     make(map[uint]*models.Chat),
+		questions: make(map[uint]*models.Question),
+		pictures:  make(map[uint]*models.Picture),
+		reactions: make(map[uint]*models.Reaction),
+		feedbacks: make(map[uint]*models.Feedback),
+		transfers: make(map[uint]*models.CoinTransfer),
+		purchases: make(map[uint]*models.Purchase),
+		updates:   make(map[int]*models.ProcessedUpdate),
+		scores:    make(map[leaderboardKey]*models.LeaderboardScore),
+
+		tags:         make(map[uint]*models.Tag),
+		questionTags: make(map[uint]map[uint]bool),
+		chatTopics:   make(map[uint]map[uint]bool),
+		reviewCards:  make(map[reviewKey]*models.ReviewCard),
+
+		dailyQuestions:  make(map[uint]*models.DailyQuestion),
+		dailyDeliveries: make(map[dailyDeliveryKey]*models.DailyDelivery),
+	}
+
+	return &repository.Stores{
+		Chats:     &chatStore{d},
+		Questions: &questionStore{d},
+		Reactions: &reactionStore{d},
+		Feedbacks: &feedbackStore{d},
+		Ledger:    &ledgerStore{d},
+		Transfers: &transferStore{d},
+		Purchases: &purchaseStore{d},
+		Updates:   &updateStore{d},
+		Tags:      &tagStore{d},
+		Reviews:   &reviewStore{d},
+		Daily:     &dailyStore{d},
+
+		Leaderboard: &leaderboardStore{d},
+		Jobs:        &jobStore{d},
+		UnitOfWork:  &unitOfWork{d},
+	}
+}
+
+// nextID выдает следующий идентификатор записи
+func (d *db) nextID() uint {
+	d.lastID++
+	return d.lastID
+}
+
+// apply изменяет баланс чата и пишет журнал; вызывается под блокировкой
+func (d *db) apply(entry *models.CoinTransaction) error {
+	chat, ok := d.chats[entry.ChatID]
+	if !ok {
+		return gorm.ErrRecordNotFound
+	}
+
+	chat.Balance += entry.Delta
+	entry.ID = d.nextID()
+	entry.CreatedAt = time.Now().UTC()
+	entry.BalanceAfter = chat.Balance
+
+	stored := *entry
+	d.transactions = append(d.transactions, &stored)
+	return nil
+}
+
+// copyChat возвращает копию чата, чтобы вызывающий код не менял состояние хранилища напрямую
+func (d *db) copyChat(chatID uint) (models.Chat, bool) {
+	chat, ok := d.chats[chatID]
+	if !ok {
+		return models.Chat{}, false
+	}
+	return *chat, true
+}
+
+// copyQuestion возвращает копию вопроса с подгруженными связями
+func (d *db) copyQuestion(question *models.Question) *models.Question {
+	result := *question
+	result.Variants = append([]models.AnswerVariant(nil), question.Variants...)
+	result.Hints = append([]models.QuestionHint(nil), question.Hints...)
+
+	if question.AuthorID != nil {
+		if author, ok := d.copyChat(*question.AuthorID); ok {
+			result.Author = &author
+		}
+	}
+	if question.QuestionPictureID != nil {
+		if picture, ok := d.pictures[*question.QuestionPictureID]; ok {
+			copied := *picture
+			result.QuestionPicture = &copied
+		}
+	}
+	if question.AnswerPictureID != nil {
+		if picture, ok := d.pictures[*question.AnswerPictureID]; ok {
+			copied := *picture
+			result.AnswerPicture = &copied
+		}
+	}
+
+	return &result
+}
+
+// copyFeedback возвращает копию обратной связи вместе с чатом
+func (d *db) copyFeedback(feedback *models.Feedback) models.Feedback {
+	result := *feedback
+	result.Chat, _ = d.copyChat(feedback.ChatID)
+	return result
+}
@@ -0,0 +1,128 @@
+package memory
+
+import (
+	"fmt"
+	"qweasley/internal/models"
+	"qweasley/internal/repository"
+	"testing"
+)
+
+// firstSelector выбирает первый из подходящих вопросов, чтобы выбор был предсказуемым
+type firstSelector struct{}
+
+func (firstSelector) Select(chat *models.Chat, candidates []repository.QuestionCandidate) uint {
+	return candidates[0].ID
+}
+
+// newTestStores создает хранилища с чатом и опубликованными вопросами
+func newTestStores(t *testing.T, questions int) (*repository.Stores, *models.Chat, []uint) {
+	t.Helper()
+
+	stores := NewStores()
+	chat, err := stores.Chats.GetOrCreate(1001, nil)
+	if err != nil {
+		t.Fatalf("failed to create chat: %v", err)
+	}
+
+	var ids []uint
+	for i := 1; i <= questions; i++ {
+		question := &models.Question{Text: fmt.Sprintf("Q%d?", i), Answer: fmt.Sprintf("A%d", i), IsPublished: true}
+		if err := stores.Questions.Create(question); err != nil {
+			t.Fatalf("failed to create question: %v", err)
+		}
+		ids = append(ids, question.ID)
+	}
+	return stores, chat, ids
+}
+
+func TestCreateOrUpdateReactionKeepsOneReaction(t *testing.T) {
+	tests := []struct {
+		name      string
+		reactions []string
+		skipped   bool
+		responsed bool
+		failed    bool
+	}{
+		{name: "single answer", reactions: []string{"response"}, responsed: true},
+		{name: "skip then answer", reactions: []string{"skip", "response"}, skipped: true, responsed: true},
+		{name: "skip twice then fail", reactions: []string{"skip", "skip", "fail"}, skipped: true, failed: true},
+	}
+
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			stores, chat, ids := newTestStores(t, 1)
+
+			if err := stores.Reactions.MarkShown(chat.ID, ids[0]); err != nil {
+				t.Fatalf("failed to mark question shown: %v", err)
+			}
+			for _, reaction := range tc.reactions {
+				if err := stores.Reactions.CreateOrUpdateReaction(chat.ID, ids[0], reaction); err != nil {
+					t.Fatalf("failed to save %s reaction: %v", reaction, err)
+				}
+			}
+
+			reacted, err := stores.Reactions.GetReactedQuestionIDs(chat.ID)
+			if err != nil {
+				t.Fatalf("failed to get reacted questions: %v", err)
+			}
+			if len(reacted) != 1 {
+				t.Fatalf("reacted questions = %v, want one", reacted)
+			}
+
+			reaction, err := stores.Reactions.GetReaction(chat.ID, ids[0])
+			if err != nil {
+				t.Fatalf("failed to get reaction: %v", err)
+			}
+			if (reaction.SkippedAt != nil) != tc.skipped || (reaction.ResponsedAt != nil) != tc.responsed || (reaction.FailedAt != nil) != tc.failed {
+				t.Fatalf("unexpected reaction: %+v", reaction)
+			}
+		})
+	}
+
+	t.Run("unknown reaction", func(t *testing.T) {
+		stores, chat, ids := newTestStores(t, 1)
+		if err := stores.Reactions.CreateOrUpdateReaction(chat.ID, ids[0], "like"); err == nil {
+			t.Fatal("unknown reaction type saved")
+		}
+	})
+}
+
+func TestGetQuestionRecyclesSkipped(t *testing.T) {
+	tests := []struct {
+		name string
+		// reactions реакции на вопросы по их номеру (с нуля)
+		reactions map[int]string
+		// want номер ожидаемого вопроса или -1, если вопросов не осталось
+		want int
+	}{
+		{name: "new questions first", reactions: map[int]string{0: "skip"}, want: 1},
+		{name: "skipped when no new left", reactions: map[int]string{0: "skip", 1: "response", 2: "fail"}, want: 0},
+		{name: "answered are never asked again", reactions: map[int]string{0: "response", 1: "fail", 2: "response"}, want: -1},
+		{name: "earliest skipped of several", reactions: map[int]string{0: "skip", 1: "response", 2: "skip"}, want: 0},
+	}
+
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			stores, chat, ids := newTestStores(t, 3)
+			for i, reaction := range tc.reactions {
+				if err := stores.Reactions.CreateOrUpdateReaction(chat.ID, ids[i], reaction); err != nil {
+					t.Fatalf("failed to save reaction: %v", err)
+				}
+			}
+
+			question, err := stores.Questions.GetQuestion(chat, stores.Reactions, nil, firstSelector{})
+			if err != nil {
+				t.Fatalf("failed to get question: %v", err)
+			}
+
+			switch {
+			case tc.want < 0 && question != nil:
+				t.Fatalf("got question %d, want none", question.ID)
+			case tc.want >= 0 && question == nil:
+				t.Fatalf("got no question, want %d", ids[tc.want])
+			case tc.want >= 0 && question.ID != ids[tc.want]:
+				t.Fatalf("got question %d, want %d", question.ID, ids[tc.want])
+			}
+		})
+	}
+}
@@ -0,0 +1,42 @@
+package memory
+
+import (
+	"qweasley/internal/models"
+	"time"
+)
+
+// purchaseStore хранилище покупок монет в памяти
+type purchaseStore struct {
+	*db
+}
+
+// Credit сохраняет покупку и начисляет монеты.
+// Повторный платеж с тем же идентификатором Telegram игнорируется, в этом случае возвращается false
+func (s *purchaseStore) Credit(purchase *models.Purchase) (bool, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	for _, existing := range s.purchases {
+		if existing.TelegramChargeID == purchase.TelegramChargeID {
+			return false, nil
+		}
+	}
+
+	err := s.apply(&models.CoinTransaction{
+		ChatID:     purchase.ChatID,
+		Delta:      purchase.Coins,
+		Reason:     models.ReasonPurchase,
+		ExternalID: &purchase.TelegramChargeID,
+	})
+	if err != nil {
+		return false, err
+	}
+
+	purchase.ID = s.nextID()
+	purchase.CreatedAt = time.Now().UTC()
+
+	stored := *purchase
+	stored.Chat = models.Chat{}
+	s.purchases[stored.ID] = &stored
+	return true, nil
+}
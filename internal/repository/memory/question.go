@@ -0,0 +1,277 @@
+package memory
+
+import (
+	"gorm.io/gorm"
+	"math/rand"
+	"qweasley/internal/models"
+	"qweasley/internal/repository"
+	"sort"
+	"time"
+)
+
+// questionStore хранилище вопросов в памяти
+type questionStore struct {
+	*db
+}
+
+// GetRandomPublished получает случайный опубликованный вопрос
+func (s *questionStore) GetRandomPublished() (*models.Question, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	var published []*models.Question
+	for _, question := range s.questions {
+		if question.IsPublished {
+			published = append(published, question)
+		}
+	}
+	if len(published) == 0 {
+		return nil, gorm.ErrRecordNotFound
+	}
+
+	return s.copyQuestion(published[rand.Intn(len(published))]), nil
+}
+
+// GetByID получает вопрос по ID
+func (s *questionStore) GetByID(id uint) (*models.Question, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	question, ok := s.questions[id]
+	if !ok {
+		return nil, gorm.ErrRecordNotFound
+	}
+	return s.copyQuestion(question), nil
+}
+
+// GetQuestion получает вопрос для пользователя, исключая его собственные вопросы и уже отвеченные.
+// Если заданы темы, сначала ищет среди вопросов этих тем, а когда они закончились — среди всех
+func (s *questionStore) GetQuestion(chat *models.Chat, reactions repository.ReactionStore, topics []uint, selector repository.QuestionSelector) (*models.Question, error) {
+	reactedIDs, err := reactions.GetReactedQuestionIDs(chat.ID)
+	if err != nil {
+		return nil, err
+	}
+
+	notSkippedIDs, err := reactions.GetNotSkippedQuestionIDs(chat.ID)
+	if err != nil {
+		return nil, err
+	}
+
+	scopes := [][]uint{topics}
+	if len(topics) > 0 {
+		scopes = append(scopes, nil)
+	}
+
+	var candidates []repository.QuestionCandidate
+	for _, scope := range scopes {
+		candidates = s.candidates(chat.ID, reactedIDs, scope)
+
+		// Если нет новых вопросов, пробуем найти пропущенные
+		if len(candidates) == 0 {
+			candidates = s.candidates(chat.ID, notSkippedIDs, scope)
+		}
+		if len(candidates) > 0 {
+			break
+		}
+	}
+
+	if len(candidates) == 0 {
+		return nil, nil
+	}
+
+	return s.GetByID(selector.Select(chat, candidates))
+}
+
+// candidates возвращает опубликованные вопросы не от этого чата, кроме исключенных;
+// если заданы темы, только вопросы этих тем
+func (s *questionStore) candidates(chatID uint, excluded []uint, topics []uint) []repository.QuestionCandidate {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	skip := make(map[uint]bool, len(excluded))
+	for _, id := range excluded {
+		skip[id] = true
+	}
+
+	var candidates []repository.QuestionCandidate
+	for _, question := range s.questions {
+		if !question.IsPublished || skip[question.ID] {
+			continue
+		}
+		if question.AuthorID != nil && *question.AuthorID == chatID {
+			continue
+		}
+		if len(topics) > 0 && !s.hasAnyTag(question.ID, topics) {
+			continue
+		}
+		candidates = append(candidates, repository.QuestionCandidate{ID: question.ID, Difficulty: question.Difficulty})
+	}
+
+	// Порядок обхода map случаен, сортируем для воспроизводимости при фиксированном seed
+	sort.Slice(candidates, func(i, j int) bool { return candidates[i].ID < candidates[j].ID })
+	return candidates
+}
+
+// UpdateQuestionRating обновляет рейтинг конкретного вопроса
+func (s *questionStore) UpdateQuestionRating(questionID uint) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	s.updateRating(questionID)
+	return nil
+}
+
+// RecomputeRatings пересчитывает рейтинг всех опубликованных вопросов
+func (s *questionStore) RecomputeRatings() (int64, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	var updated int64
+	for _, question := range s.questions {
+		if question.IsPublished {
+			s.updateRating(question.ID)
+			updated++
+		}
+	}
+	return updated, nil
+}
+
+// updateRating пересчитывает счетчики и рейтинг вопроса и возвращает функцию отката; вызывается под блокировкой
+func (d *db) updateRating(questionID uint) func() {
+	question, ok := d.questions[questionID]
+	if !ok || !question.IsPublished {
+		return func() {}
+	}
+
+	// Реакция учитывается по итогу: ответ, иначе показ ответа, иначе пропуск
+	correct, revealed, skipped := 0, 0, 0
+	for _, reaction := range d.reactions {
+		if reaction.QuestionID != questionID {
+			continue
+		}
+		switch {
+		case reaction.ResponsedAt != nil:
+			correct++
+		case reaction.FailedAt != nil:
+			revealed++
+		case reaction.SkippedAt != nil:
+			skipped++
+		}
+	}
+
+	previous := *question
+	rating := models.QuestionRating(correct, correct+revealed)
+	question.Rating = &rating
+	question.CorrectCount = correct
+	question.AnswerCount = correct + revealed
+	question.SkipCount = skipped
+	return func() {
+		question.Rating = previous.Rating
+		question.CorrectCount = previous.CorrectCount
+		question.AnswerCount = previous.AnswerCount
+		question.SkipCount = previous.SkipCount
+	}
+}
+
+// Create создает новый вопрос вместе с вариантами ответа, подсказками и картинкой вопроса
+func (s *questionStore) Create(question *models.Question) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	question.ID = s.nextID()
+	if question.Rating == nil {
+		rating := models.QuestionRating(0, 0)
+		question.Rating = &rating
+	}
+	if question.Difficulty == 0 {
+		question.Difficulty = models.InitialElo
+	}
+	for i := range question.Variants {
+		question.Variants[i].ID = s.nextID()
+		question.Variants[i].QuestionID = question.ID
+	}
+	for i := range question.Hints {
+		question.Hints[i].ID = s.nextID()
+		question.Hints[i].QuestionID = question.ID
+	}
+	if question.QuestionPicture != nil {
+		picture := *question.QuestionPicture
+		picture.ID = s.nextID()
+		picture.CreatedAt = time.Now().UTC()
+		s.pictures[picture.ID] = &picture
+		question.QuestionPicture.ID = picture.ID
+		question.QuestionPictureID = &picture.ID
+	}
+
+	stored := *question
+	stored.Author = nil
+	stored.QuestionPicture = nil
+	stored.AnswerPicture = nil
+	stored.Variants = append([]models.AnswerVariant(nil), question.Variants...)
+	stored.Hints = append([]models.QuestionHint(nil), question.Hints...)
+	s.questions[stored.ID] = &stored
+	return nil
+}
+
+// UpdateContent обновляет текст, ответ и комментарий вопроса, если он еще не прошел модерацию
+func (s *questionStore) UpdateContent(question *models.Question) (bool, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	stored, ok := s.questions[question.ID]
+	if !ok || !pending(stored) {
+		return false, nil
+	}
+	stored.Text = question.Text
+	stored.Answer = question.Answer
+	stored.Comment = question.Comment
+	return true, nil
+}
+
+// pending проверяет, что вопрос еще не прошел модерацию
+func pending(question *models.Question) bool {
+	return !question.IsPublished && question.ApprovedAt == nil && question.RejectedAt == nil
+}
+
+// Approve публикует вопрос, если он еще не прошел модерацию, и начисляет автору награду
+func (s *questionStore) Approve(questionID uint, reward int) (bool, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	question, ok := s.questions[questionID]
+	if !ok || !pending(question) {
+		return false, nil
+	}
+
+	if question.AuthorID != nil && reward != 0 {
+		err := s.apply(&models.CoinTransaction{
+			ChatID:     *question.AuthorID,
+			Delta:      reward,
+			Reason:     models.ReasonQuestionApproved,
+			QuestionID: &question.ID,
+		})
+		if err != nil {
+			return false, err
+		}
+	}
+
+	now := time.Now().UTC()
+	question.IsPublished = true
+	question.ApprovedAt = &now
+	return true, nil
+}
+
+// Reject отклоняет вопрос, если он еще не прошел модерацию
+func (s *questionStore) Reject(questionID uint) (bool, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	question, ok := s.questions[questionID]
+	if !ok || !pending(question) {
+		return false, nil
+	}
+
+	now := time.Now().UTC()
+	question.RejectedAt = &now
+	return true, nil
+}
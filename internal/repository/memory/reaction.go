@@ -0,0 +1,246 @@
+package memory
+
+import (
+	"fmt"
+	"gorm.io/gorm"
+	"qweasley/internal/models"
+	"qweasley/internal/repository"
+	"sort"
+	"time"
+)
+
+// reactionStore хранилище реакций в памяти
+type reactionStore struct {
+	*db
+}
+
+// questionIDs возвращает ID вопросов из реакций чата, подходящих под условие
+func (s *reactionStore) questionIDs(chatID uint, match func(reaction *models.Reaction) bool) []uint {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	var ids []uint
+	for _, reaction := range s.reactions {
+		if reaction.ChatID == chatID && match(reaction) {
+			ids = append(ids, reaction.QuestionID)
+		}
+	}
+	sort.Slice(ids, func(i, j int) bool { return ids[i] < ids[j] })
+	return ids
+}
+
+// GetReactedQuestionIDs получает ID вопросов, на которые пользователь уже реагировал (ответ, пропуск или показ ответа)
+func (s *reactionStore) GetReactedQuestionIDs(chatID uint) ([]uint, error) {
+	return s.questionIDs(chatID, (*models.Reaction).IsResolved), nil
+}
+
+// GetNotSkippedQuestionIDs получает ID вопросов, которые пользователь не пропускал
+func (s *reactionStore) GetNotSkippedQuestionIDs(chatID uint) ([]uint, error) {
+	return s.questionIDs(chatID, func(reaction *models.Reaction) bool {
+		return reaction.ResponsedAt != nil || reaction.FailedAt != nil
+	}), nil
+}
+
+// findReaction ищет реакцию чата на вопрос; вызывается под блокировкой
+func (d *db) findReaction(chatID, questionID uint) *models.Reaction {
+	for _, reaction := range d.reactions {
+		if reaction.ChatID == chatID && reaction.QuestionID == questionID {
+			return reaction
+		}
+	}
+	return nil
+}
+
+// CreateOrUpdateReaction создает или обновляет реакцию пользователя на вопрос
+func (s *reactionStore) CreateOrUpdateReaction(chatID, questionID uint, reactionType string) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	_, err := s.saveReaction(chatID, questionID, reactionType)
+	return err
+}
+
+// saveReaction создает или обновляет реакцию и возвращает функцию отката; вызывается под блокировкой
+func (d *db) saveReaction(chatID, questionID uint, reactionType string) (func(), error) {
+	now := time.Now()
+	var field **time.Time
+
+	reaction := d.findReaction(chatID, questionID)
+	created := reaction == nil
+	if created {
+		reaction = &models.Reaction{
+			ID:         d.nextID(),
+			CreatedAt:  time.Now().UTC(),
+			ChatID:     chatID,
+			QuestionID: questionID,
+		}
+	}
+
+	switch reactionType {
+	case "skip":
+		field = &reaction.SkippedAt
+	case "response":
+		field = &reaction.ResponsedAt
+	case "fail":
+		field = &reaction.FailedAt
+	default:
+		return nil, fmt.Errorf("unknown reaction type: %s", reactionType)
+	}
+
+	if created {
+		d.reactions[reaction.ID] = reaction
+	}
+	previous := *field
+	*field = &now
+
+	return func() {
+		if created {
+			delete(d.reactions, reaction.ID)
+			return
+		}
+		*field = previous
+	}, nil
+}
+
+// GetReaction получает реакцию пользователя на конкретный вопрос
+func (s *reactionStore) GetReaction(chatID, questionID uint) (*models.Reaction, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	reaction := s.findReaction(chatID, questionID)
+	if reaction == nil {
+		return nil, gorm.ErrRecordNotFound
+	}
+	result := *reaction
+	return &result, nil
+}
+
+// MarkShown создает пустую реакцию при показе вопроса; существующую реакцию не меняет
+func (s *reactionStore) MarkShown(chatID, questionID uint) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if s.findReaction(chatID, questionID) != nil {
+		return nil
+	}
+	id := s.nextID()
+	s.reactions[id] = &models.Reaction{
+		ID:         id,
+		CreatedAt:  time.Now().UTC(),
+		ChatID:     chatID,
+		QuestionID: questionID,
+	}
+	return nil
+}
+
+// GetStats считает статистику чата
+func (s *reactionStore) GetStats(chatID uint) (*repository.ReactionStats, error) {
+	return s.stats(func(reaction *models.Reaction) bool { return reaction.ChatID == chatID }), nil
+}
+
+// GetGlobalStats считает статистику по всем чатам
+func (s *reactionStore) GetGlobalStats() (*repository.ReactionStats, error) {
+	return s.stats(func(*models.Reaction) bool { return true }), nil
+}
+
+// stats считает статистику по реакциям, подходящим под условие, по тем же правилам, что и PostgreSQL
+func (s *reactionStore) stats(match func(reaction *models.Reaction) bool) *repository.ReactionStats {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	stats := &repository.ReactionStats{}
+	chats := make(map[uint]bool)
+	var answerTime time.Duration
+	timed := 0
+	for _, reaction := range s.reactions {
+		if !match(reaction) || !reaction.IsResolved() {
+			continue
+		}
+		chats[reaction.ChatID] = true
+
+		switch {
+		case reaction.ResponsedAt != nil:
+			stats.Answered++
+			if elapsed := reaction.ResponsedAt.Sub(reaction.CreatedAt); reaction.SkippedAt == nil && elapsed >= time.Second {
+				answerTime += elapsed
+				timed++
+			}
+		case reaction.FailedAt != nil:
+			stats.Revealed++
+		default:
+			stats.Skipped++
+		}
+	}
+
+	stats.Chats = len(chats)
+	if timed > 0 {
+		stats.AnswerTime = answerTime / time.Duration(timed)
+	}
+	return stats
+}
+
+// GetAnswerDays возвращает дни (UTC) с правильными ответами чата по возрастанию
+func (s *reactionStore) GetAnswerDays(chatID uint) ([]time.Time, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	seen := make(map[time.Time]bool)
+	var days []time.Time
+	for _, reaction := range s.reactions {
+		if reaction.ChatID != chatID || reaction.ResponsedAt == nil {
+			continue
+		}
+		t := reaction.ResponsedAt.UTC()
+		day := time.Date(t.Year(), t.Month(), t.Day(), 0, 0, 0, 0, time.UTC)
+		if !seen[day] {
+			seen[day] = true
+			days = append(days, day)
+		}
+	}
+	sort.Slice(days, func(i, j int) bool { return days[i].Before(days[j]) })
+	return days, nil
+}
+
+// GetMemberStats возвращает участников чата по числу правильных ответов
+func (s *reactionStore) GetMemberStats(chatID uint, limit int) ([]repository.MemberStats, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	members := make(map[int64]*repository.MemberStats)
+	lastAnswered := make(map[int64]time.Time)
+	for _, reaction := range s.reactions {
+		if reaction.ChatID != chatID || reaction.ResponderID == nil || reaction.ResponsedAt == nil {
+			continue
+		}
+
+		id := *reaction.ResponderID
+		member, ok := members[id]
+		if !ok {
+			member = &repository.MemberStats{ResponderID: id}
+			members[id] = member
+		}
+		member.Answered++
+		if reaction.ResponsedAt.After(lastAnswered[id]) {
+			lastAnswered[id] = *reaction.ResponsedAt
+			if reaction.ResponderName != nil {
+				member.Name = *reaction.ResponderName
+			}
+		}
+	}
+
+	stats := make([]repository.MemberStats, 0, len(members))
+	for _, member := range members {
+		stats = append(stats, *member)
+	}
+	sort.Slice(stats, func(i, j int) bool {
+		if stats[i].Answered != stats[j].Answered {
+			return stats[i].Answered > stats[j].Answered
+		}
+		return lastAnswered[stats[i].ResponderID].Before(lastAnswered[stats[j].ResponderID])
+	})
+
+	if len(stats) > limit {
+		stats = stats[:limit]
+	}
+	return stats, nil
+}
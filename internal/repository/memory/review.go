@@ -0,0 +1,81 @@
+package memory
+
+import (
+	"qweasley/internal/models"
+	"time"
+)
+
+// reviewKey карточка повторения одного вопроса в одном чате
+type reviewKey struct {
+	chatID     uint
+	questionID uint
+}
+
+// reviewStore хранилище карточек повторения в памяти
+type reviewStore struct {
+	*db
+}
+
+// GetDue возвращает самую давнюю наступившую карточку опубликованного вопроса или nil
+func (s *reviewStore) GetDue(chatID uint, now time.Time) (*models.ReviewCard, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	var due *models.ReviewCard
+	for _, card := range s.cards(chatID) {
+		if card.DueAt.After(now) {
+			continue
+		}
+		if due == nil || card.DueAt.Before(due.DueAt) ||
+			(card.DueAt.Equal(due.DueAt) && card.QuestionID < due.QuestionID) {
+			due = card
+		}
+	}
+	if due == nil {
+		return nil, nil
+	}
+
+	result := *due
+	return &result, nil
+}
+
+// CountDue считает наступившие карточки опубликованных вопросов
+func (s *reviewStore) CountDue(chatID uint, now time.Time) (int, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	count := 0
+	for _, card := range s.cards(chatID) {
+		if !card.DueAt.After(now) {
+			count++
+		}
+	}
+	return count, nil
+}
+
+// NextDue возвращает время ближайшего повторения или nil
+func (s *reviewStore) NextDue(chatID uint) (*time.Time, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	var next *time.Time
+	for _, card := range s.cards(chatID) {
+		if next == nil || card.DueAt.Before(*next) {
+			dueAt := card.DueAt
+			next = &dueAt
+		}
+	}
+	return next, nil
+}
+
+// cards возвращает карточки чата по опубликованным вопросам; вызывается под блокировкой
+func (s *reviewStore) cards(chatID uint) []*models.ReviewCard {
+	var cards []*models.ReviewCard
+	for key, card := range s.reviewCards {
+		question, ok := s.questions[key.questionID]
+		if key.chatID == chatID && ok && question.IsPublished {
+			cards = append(cards, card)
+		}
+	}
+	return cards
+}
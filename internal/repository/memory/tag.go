@@ -0,0 +1,165 @@
+package memory
+
+import (
+	"gorm.io/gorm"
+	"qweasley/internal/models"
+	"sort"
+	"strings"
+	"time"
+)
+
+// tagStore хранилище тем в памяти
+type tagStore struct {
+	*db
+}
+
+// GetAll возвращает все темы по алфавиту вместе с числом опубликованных вопросов
+func (s *tagStore) GetAll() ([]models.Tag, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	tags := make([]models.Tag, 0, len(s.tags))
+	for _, tag := range s.tags {
+		result := *tag
+		result.Questions = 0
+		for questionID, tagIDs := range s.questionTags {
+			if question, ok := s.questions[questionID]; ok && question.IsPublished && tagIDs[tag.ID] {
+				result.Questions++
+			}
+		}
+		tags = append(tags, result)
+	}
+
+	sort.Slice(tags, func(i, j int) bool { return strings.ToLower(tags[i].Name) < strings.ToLower(tags[j].Name) })
+	return tags, nil
+}
+
+// GetByName ищет тему по имени без учета регистра
+func (s *tagStore) GetByName(name string) (*models.Tag, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	tag := s.findTag(name)
+	if tag == nil {
+		return nil, gorm.ErrRecordNotFound
+	}
+	result := *tag
+	return &result, nil
+}
+
+// GetOrCreate находит тему по имени без учета регистра или создает новую
+func (s *tagStore) GetOrCreate(name string) (*models.Tag, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	tag := s.findTag(name)
+	if tag == nil {
+		tag = &models.Tag{ID: s.nextID(), CreatedAt: time.Now().UTC(), Name: name}
+		s.tags[tag.ID] = tag
+	}
+	result := *tag
+	return &result, nil
+}
+
+// TagQuestions относит к теме существующие вопросы; уже отнесенные пропускаются
+func (s *tagStore) TagQuestions(tagID uint, questionIDs []uint) (int, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if _, ok := s.tags[tagID]; !ok {
+		return 0, gorm.ErrRecordNotFound
+	}
+
+	added := 0
+	for _, questionID := range questionIDs {
+		if _, ok := s.questions[questionID]; !ok || s.questionTags[questionID][tagID] {
+			continue
+		}
+		if s.questionTags[questionID] == nil {
+			s.questionTags[questionID] = make(map[uint]bool)
+		}
+		s.questionTags[questionID][tagID] = true
+		added++
+	}
+	return added, nil
+}
+
+// UntagQuestions убирает вопросы из темы
+func (s *tagStore) UntagQuestions(tagID uint, questionIDs []uint) (int, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	removed := 0
+	for _, questionID := range questionIDs {
+		if s.questionTags[questionID][tagID] {
+			delete(s.questionTags[questionID], tagID)
+			removed++
+		}
+	}
+	return removed, nil
+}
+
+// GetChatTopics возвращает темы, выбранные чатом
+func (s *tagStore) GetChatTopics(chatID uint) ([]uint, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	var tagIDs []uint
+	for tagID := range s.chatTopics[chatID] {
+		tagIDs = append(tagIDs, tagID)
+	}
+	sort.Slice(tagIDs, func(i, j int) bool { return tagIDs[i] < tagIDs[j] })
+	return tagIDs, nil
+}
+
+// ToggleChatTopic выбирает тему для чата или снимает выбор
+func (s *tagStore) ToggleChatTopic(chatID, tagID uint) (bool, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if s.chatTopics[chatID][tagID] {
+		delete(s.chatTopics[chatID], tagID)
+		return false, nil
+	}
+
+	if _, ok := s.chats[chatID]; !ok {
+		return false, gorm.ErrRecordNotFound
+	}
+	if _, ok := s.tags[tagID]; !ok {
+		return false, gorm.ErrRecordNotFound
+	}
+	if s.chatTopics[chatID] == nil {
+		s.chatTopics[chatID] = make(map[uint]bool)
+	}
+	s.chatTopics[chatID][tagID] = true
+	return true, nil
+}
+
+// ClearChatTopics снимает выбор всех тем
+func (s *tagStore) ClearChatTopics(chatID uint) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	delete(s.chatTopics, chatID)
+	return nil
+}
+
+// findTag ищет тему по имени без учета регистра; вызывается под блокировкой
+func (d *db) findTag(name string) *models.Tag {
+	for _, tag := range d.tags {
+		if strings.EqualFold(tag.Name, name) {
+			return tag
+		}
+	}
+	return nil
+}
+
+// hasAnyTag проверяет, относится ли вопрос хотя бы к одной из тем; вызывается под блокировкой
+func (d *db) hasAnyTag(questionID uint, tagIDs []uint) bool {
+	for _, tagID := range tagIDs {
+		if d.questionTags[questionID][tagID] {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,130 @@
+package memory
+
+import (
+	"gorm.io/gorm"
+	"qweasley/internal/models"
+	"qweasley/internal/repository"
+	"sort"
+	"time"
+)
+
+// transferStore хранилище переводов монет в памяти
+type transferStore struct {
+	*db
+}
+
+// Create резервирует сумму перевода на счете отправителя, создает код и ставит задачу возврата
+func (s *transferStore) Create(transfer *models.CoinTransfer) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	for _, existing := range s.transfers {
+		if existing.Code == transfer.Code {
+			return gorm.ErrDuplicatedKey
+		}
+	}
+
+	sender, ok := s.chats[transfer.FromChatID]
+	if !ok {
+		return gorm.ErrRecordNotFound
+	}
+	if sender.Balance < transfer.Amount {
+		return repository.ErrInsufficientBalance
+	}
+
+	transfer.ID = s.nextID()
+	transfer.CreatedAt = time.Now().UTC()
+
+	stored := *transfer
+	stored.FromChat = models.Chat{}
+	stored.ToChat = nil
+	s.transfers[stored.ID] = &stored
+
+	s.apply(&models.CoinTransaction{
+		ChatID:     transfer.FromChatID,
+		Delta:      -transfer.Amount,
+		Reason:     models.ReasonTransferOut,
+		ExternalID: &stored.Code,
+	})
+	s.enqueue(&models.ScheduledJob{
+		RunAt:  transfer.ExpiresAt,
+		Kind:   models.JobTransferExpire,
+		ChatID: &stored.FromChatID,
+	})
+	return nil
+}
+
+// Redeem погашает код перевода: начисляет получателю зарезервированные монеты
+func (s *transferStore) Redeem(code string, toChatID uint) (*models.CoinTransfer, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	var transfer *models.CoinTransfer
+	for _, existing := range s.transfers {
+		if existing.Code == code {
+			transfer = existing
+			break
+		}
+	}
+
+	switch {
+	case transfer == nil:
+		return nil, repository.ErrTransferNotFound
+	case transfer.RedeemedAt != nil:
+		return nil, repository.ErrTransferRedeemed
+	case transfer.RefundedAt != nil || time.Now().UTC().After(transfer.ExpiresAt):
+		return nil, repository.ErrTransferExpired
+	case transfer.FromChatID == toChatID:
+		return nil, repository.ErrSelfTransfer
+	}
+
+	sender, ok := s.chats[transfer.FromChatID]
+	if !ok {
+		return nil, gorm.ErrRecordNotFound
+	}
+	if _, ok := s.chats[toChatID]; !ok {
+		return nil, gorm.ErrRecordNotFound
+	}
+
+	s.apply(&models.CoinTransaction{
+		ChatID:        toChatID,
+		Delta:         transfer.Amount,
+		Reason:        models.ReasonTransferIn,
+		RelatedChatID: &transfer.FromChatID,
+		ExternalID:    &transfer.Code,
+	})
+
+	now := time.Now().UTC()
+	transfer.ToChatID = &toChatID
+	transfer.RedeemedAt = &now
+
+	result := *transfer
+	result.FromChat = *sender
+	return &result, nil
+}
+
+// RefundExpired возвращает отправителю монеты по истекшим и не полученным кодам
+func (s *transferStore) RefundExpired(fromChatID uint, now time.Time) ([]models.CoinTransfer, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	var refunded []models.CoinTransfer
+	for _, transfer := range s.transfers {
+		if transfer.FromChatID != fromChatID || transfer.ExpiresAt.After(now) || transfer.RedeemedAt != nil || transfer.RefundedAt != nil {
+			continue
+		}
+
+		s.apply(&models.CoinTransaction{
+			ChatID:     transfer.FromChatID,
+			Delta:      transfer.Amount,
+			Reason:     models.ReasonRefund,
+			ExternalID: &transfer.Code,
+		})
+		refundedAt := now
+		transfer.RefundedAt = &refundedAt
+		refunded = append(refunded, *transfer)
+	}
+
+	sort.Slice(refunded, func(i, j int) bool { return refunded[i].ID < refunded[j].ID })
+	return refunded, nil
+}
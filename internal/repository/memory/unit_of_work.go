@@ -0,0 +1,214 @@
+package memory
+
+import (
+	"gorm.io/gorm"
+	"qweasley/internal/models"
+	"qweasley/internal/repository"
+	"time"
+)
+
+// unitOfWork выполняет изменения под общей блокировкой и откатывает их при ошибке
+type unitOfWork struct {
+	*db
+}
+
+// Do выполняет fn атомарно: при ошибке все изменения, сделанные через tx, отменяются
+func (u *unitOfWork) Do(fn func(tx repository.Tx) error) error {
+	u.mu.Lock()
+	defer u.mu.Unlock()
+
+	tx := &memoryTx{db: u.db}
+	if err := fn(tx); err != nil {
+		for i := len(tx.undo) - 1; i >= 0; i-- {
+			tx.undo[i]()
+		}
+		return err
+	}
+	return nil
+}
+
+// memoryTx операции внутри unitOfWork с журналом отката
+type memoryTx struct {
+	db   *db
+	undo []func()
+}
+
+// LockChat читает чат; блокировка уже удерживается всей транзакцией
+func (t *memoryTx) LockChat(chatID uint) (*models.Chat, error) {
+	chat, ok := t.db.copyChat(chatID)
+	if !ok {
+		return nil, gorm.ErrRecordNotFound
+	}
+	return &chat, nil
+}
+
+// SaveReaction создает или обновляет реакцию чата на вопрос
+func (t *memoryTx) SaveReaction(chatID, questionID uint, reactionType string) error {
+	undo, err := t.db.saveReaction(chatID, questionID, reactionType)
+	if err != nil {
+		return err
+	}
+	t.undo = append(t.undo, undo)
+	return nil
+}
+
+// ApplyTransaction изменяет баланс и пишет журнал
+func (t *memoryTx) ApplyTransaction(entry *models.CoinTransaction) error {
+	if err := t.db.apply(entry); err != nil {
+		return err
+	}
+
+	t.undo = append(t.undo, func() {
+		t.db.chats[entry.ChatID].Balance -= entry.Delta
+		t.db.transactions = t.db.transactions[:len(t.db.transactions)-1]
+	})
+	return nil
+}
+
+// ClearWaitingAnswer снимает ожидание ответа, только если чат все еще ждет ответа на этот вопрос
+func (t *memoryTx) ClearWaitingAnswer(chatID, questionID uint) error {
+	chat, ok := t.db.chats[chatID]
+	if !ok || chat.LastQuestionID == nil || *chat.LastQuestionID != questionID {
+		return repository.ErrQuestionChanged
+	}
+
+	lastQuestionID, expiresAt := chat.LastQuestionID, chat.ExpiresAt
+	chat.LastQuestionID = nil
+	chat.ExpiresAt = nil
+
+	t.undo = append(t.undo, func() {
+		chat.LastQuestionID = lastQuestionID
+		chat.ExpiresAt = expiresAt
+	})
+	return nil
+}
+
+// UpdateQuestionRating пересчитывает рейтинг вопроса
+func (t *memoryTx) UpdateQuestionRating(questionID uint) error {
+	t.undo = append(t.undo, t.db.updateRating(questionID))
+	return nil
+}
+
+// AddHint отмечает в реакции еще одну взятую подсказку и возвращает копию реакции
+func (t *memoryTx) AddHint(chatID, questionID uint, cost float64) (*models.Reaction, error) {
+	reaction := t.reaction(chatID, questionID)
+	reaction.HintsUsed++
+	reaction.HintCost += cost
+
+	result := *reaction
+	return &result, nil
+}
+
+// AddAttempt отмечает в реакции еще один неверный ответ и возвращает копию реакции
+func (t *memoryTx) AddAttempt(chatID, questionID uint) (*models.Reaction, error) {
+	reaction := t.reaction(chatID, questionID)
+	reaction.Attempts++
+
+	result := *reaction
+	return &result, nil
+}
+
+// AddScore добавляет закрытый вопрос в итоги таблиц лидеров
+func (t *memoryTx) AddScore(chatID, questionID uint, correct bool) error {
+	t.undo = append(t.undo, t.db.addScore(chatID, questionID, correct, time.Now()))
+	return nil
+}
+
+// SetResponder запоминает в реакции пользователя, приславшего правильный ответ
+func (t *memoryTx) SetResponder(chatID, questionID uint, responderID int64, name string) error {
+	reaction := t.reaction(chatID, questionID)
+	reaction.ResponderID = &responderID
+	reaction.ResponderName = &name
+	return nil
+}
+
+// UpdateElo пересчитывает уровень чата и сложность вопроса
+func (t *memoryTx) UpdateElo(chatID, questionID uint, correct bool) error {
+	chat, ok := t.db.chats[chatID]
+	if !ok {
+		return gorm.ErrRecordNotFound
+	}
+	question, ok := t.db.questions[questionID]
+	if !ok {
+		return gorm.ErrRecordNotFound
+	}
+
+	skill, difficulty := chat.Skill, question.Difficulty
+	chat.Skill, question.Difficulty = models.EloUpdate(skill, difficulty, correct)
+	t.undo = append(t.undo, func() {
+		chat.Skill, question.Difficulty = skill, difficulty
+	})
+	return nil
+}
+
+// AddReviewCard ставит вопрос на повторение, если карточки еще нет
+func (t *memoryTx) AddReviewCard(chatID, questionID uint, dueAt time.Time) error {
+	key := reviewKey{chatID: chatID, questionID: questionID}
+	if _, ok := t.db.reviewCards[key]; ok {
+		return nil
+	}
+
+	t.db.reviewCards[key] = &models.ReviewCard{
+		ChatID:     chatID,
+		QuestionID: questionID,
+		CreatedAt:  time.Now().UTC(),
+		DueAt:      dueAt,
+		Ease:       models.ReviewInitialEase,
+	}
+	t.undo = append(t.undo, func() { delete(t.db.reviewCards, key) })
+	return nil
+}
+
+// ClearReview снимает ожидание ответа, только если чат все еще ждет ответа на этот вопрос повторения
+func (t *memoryTx) ClearReview(chatID, questionID uint) error {
+	chat, ok := t.db.chats[chatID]
+	if !ok || chat.ReviewQuestionID == nil || *chat.ReviewQuestionID != questionID {
+		return repository.ErrQuestionChanged
+	}
+
+	reviewQuestionID, reviewExpiresAt := chat.ReviewQuestionID, chat.ReviewExpiresAt
+	chat.ReviewQuestionID = nil
+	chat.ReviewExpiresAt = nil
+
+	t.undo = append(t.undo, func() {
+		chat.ReviewQuestionID = reviewQuestionID
+		chat.ReviewExpiresAt = reviewExpiresAt
+	})
+	return nil
+}
+
+// GradeReview пересчитывает карточку и возвращает ее копию
+func (t *memoryTx) GradeReview(chatID, questionID uint, quality int, now time.Time) (*models.ReviewCard, error) {
+	card, ok := t.db.reviewCards[reviewKey{chatID: chatID, questionID: questionID}]
+	if !ok {
+		return nil, gorm.ErrRecordNotFound
+	}
+
+	previous := *card
+	card.Grade(quality, now)
+	t.undo = append(t.undo, func() { *card = previous })
+
+	result := *card
+	return &result, nil
+}
+
+// reaction возвращает реакцию чата на вопрос, создавая ее при необходимости;
+// прежнее состояние реакции восстанавливается при откате
+func (t *memoryTx) reaction(chatID, questionID uint) *models.Reaction {
+	reaction := t.db.findReaction(chatID, questionID)
+	if reaction == nil {
+		reaction = &models.Reaction{
+			ID:         t.db.nextID(),
+			CreatedAt:  time.Now().UTC(),
+			ChatID:     chatID,
+			QuestionID: questionID,
+		}
+		t.db.reactions[reaction.ID] = reaction
+		t.undo = append(t.undo, func() { delete(t.db.reactions, reaction.ID) })
+		return reaction
+	}
+
+	previous := *reaction
+	t.undo = append(t.undo, func() { *reaction = previous })
+	return reaction
+}
@@ -0,0 +1,64 @@
+package memory
+
+import (
+	"qweasley/internal/models"
+	"qweasley/internal/repository"
+	"time"
+)
+
+// updateStore отметки обработанных обновлений в памяти
+type updateStore struct {
+	*db
+}
+
+// Claim занимает обновление на время lease, если оно не обработано и не занято другим вызовом
+func (s *updateStore) Claim(updateID int, lease time.Duration) (repository.UpdateClaim, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	now := time.Now().UTC()
+	lockedUntil := now.Add(lease)
+
+	update, ok := s.updates[updateID]
+	switch {
+	case !ok:
+		s.updates[updateID] = &models.ProcessedUpdate{UpdateID: updateID, ReceivedAt: now, LockedUntil: &lockedUntil}
+		return repository.UpdateClaimed, nil
+	case update.ProcessedAt != nil:
+		return repository.UpdateProcessed, nil
+	case update.LockedUntil != nil && update.LockedUntil.After(now):
+		return repository.UpdateBusy, nil
+	}
+
+	update.LockedUntil = &lockedUntil
+	return repository.UpdateClaimed, nil
+}
+
+// MarkProcessed отмечает обновление обработанным
+func (s *updateStore) MarkProcessed(updateID int) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if update, ok := s.updates[updateID]; ok {
+		now := time.Now().UTC()
+		update.ProcessedAt = &now
+		update.LockedUntil = nil
+	}
+	return nil
+}
+
+// DeleteOlderThan удаляет отметки старше указанного возраста
+func (s *updateStore) DeleteOlderThan(age time.Duration) (int64, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	threshold := time.Now().UTC().Add(-age)
+	var deleted int64
+	for updateID, update := range s.updates {
+		if update.ReceivedAt.Before(threshold) {
+			delete(s.updates, updateID)
+			deleted++
+		}
+	}
+	return deleted, nil
+}
@@ -0,0 +1,45 @@
+package repository
+
+import (
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+	"qweasley/internal/database"
+	"qweasley/internal/models"
+)
+
+// PurchaseRepository репозиторий для работы с покупками монет
+type PurchaseRepository struct {
+	db *gorm.DB
+}
+
+// NewPurchaseRepository создает новый репозиторий покупок
+func NewPurchaseRepository() *PurchaseRepository {
+	return &PurchaseRepository{
+		db: database.GetDB(),
+	}
+}
+
+// Credit сохраняет покупку и начисляет монеты в одной транзакции.
+// Повторный платеж с тем же идентификатором Telegram игнорируется, в этом случае возвращается false
+func (r *PurchaseRepository) Credit(purchase *models.Purchase) (bool, error) {
+	credited := false
+	err := r.db.Transaction(func(tx *gorm.DB) error {
+		result := tx.Clauses(clause.OnConflict{
+			Columns:   []clause.Column{{Name: "telegram_charge_id"}},
+			DoNothing: true,
+		}).Create(purchase)
+		if result.Error != nil || result.RowsAffected == 0 {
+			return result.Error
+		}
+
+		err := applyTransaction(tx, &models.CoinTransaction{
+			ChatID:     purchase.ChatID,
+			Delta:      purchase.Coins,
+			Reason:     models.ReasonPurchase,
+			ExternalID: &purchase.TelegramChargeID,
+		})
+		credited = err == nil
+		return err
+	})
+	return credited, err
+}
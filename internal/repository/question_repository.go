@@ -3,9 +3,9 @@ package repository
 import (
 	"fmt"
 	"gorm.io/gorm"
-	"math/rand"
 	"qweasley/internal/database"
 	"qweasley/internal/models"
+	"time"
 )
 
 // QuestionRepository репозиторий для работы с вопросами
@@ -39,6 +39,8 @@ func (r *QuestionRepository) GetByID(id uint) (*models.Question, error) {
 	err := r.db.Preload("Author").
 		Preload("QuestionPicture").
 		Preload("AnswerPicture").
+		Preload("Variants").
+		Preload("Hints", func(db *gorm.DB) *gorm.DB { return db.Order("position") }).
 		Where("id = ?", id).
 		First(&question).Error
 	if err != nil {
@@ -47,8 +49,9 @@ func (r *QuestionRepository) GetByID(id uint) (*models.Question, error) {
 	return &question, nil
 }
 
-// GetQuestion получает вопрос для пользователя, исключая его собственные вопросы и уже отвеченные
-func (r *QuestionRepository) GetQuestion(chat *models.Chat, reactionRepo *ReactionRepository) (*models.Question, error) {
+// GetQuestion получает вопрос для пользователя, исключая его собственные вопросы и уже отвеченные.
+// Если заданы темы, сначала ищет среди вопросов этих тем, а когда они закончились — среди всех
+func (r *QuestionRepository) GetQuestion(chat *models.Chat, reactionRepo ReactionStore, topics []uint, selector QuestionSelector) (*models.Question, error) {
 	// Получаем ID вопросов, на которые пользователь уже реагировал
 	reactedIDs, err := reactionRepo.GetReactedQuestionIDs(chat.ID)
 	if err != nil {
@@ -56,52 +59,49 @@ func (r *QuestionRepository) GetQuestion(chat *models.Chat, reactionRepo *Reacti
 		return nil, err
 	}
 
-	// Строим запрос для получения вопросов
-	query := r.db.Table("questions").Where("is_published = ?", true).
-		Where("(author_id IS NULL OR author_id != ?)", chat.ID)
-
-	// Исключаем уже отвеченные вопросы
-	if len(reactedIDs) > 0 {
-		query = query.Where("id NOT IN ?", reactedIDs)
+	var notSkippedIDs []uint
+	notSkippedLoaded := false
+	scopes := [][]uint{topics}
+	if len(topics) > 0 {
+		scopes = append(scopes, nil)
 	}
 
-	// Получаем ID доступных вопросов
-	var questionIDs []uint
-	err = query.Pluck("id", &questionIDs).Error
-
-	if err != nil {
-		fmt.Printf("Failed to get question IDs: %v (chat_id: %d, reacted_count: %d)\n", err, chat.ID, len(reactedIDs))
-		return nil, err
-	}
-
-	// Если нет новых вопросов, пробуем найти пропущенные
-	if len(questionIDs) == 0 {
-		notSkippedIDs, err := reactionRepo.GetNotSkippedQuestionIDs(chat.ID)
+	var candidates []QuestionCandidate
+	for _, scope := range scopes {
+		candidates, err = r.candidates(chat.ID, reactedIDs, scope)
 		if err != nil {
+			fmt.Printf("Failed to get question IDs: %v (chat_id: %d, reacted_count: %d)\n", err, chat.ID, len(reactedIDs))
 			return nil, err
 		}
 
-		query = r.db.Table("questions").Where("is_published = ?", true).
-			Where("(author_id IS NULL OR author_id != ?)", chat.ID)
-
-		if len(notSkippedIDs) > 0 {
-			query = query.Where("id NOT IN ?", notSkippedIDs)
+		// Если нет новых вопросов, пробуем найти пропущенные
+		if len(candidates) == 0 {
+			if !notSkippedLoaded {
+				notSkippedIDs, err = reactionRepo.GetNotSkippedQuestionIDs(chat.ID)
+				if err != nil {
+					return nil, err
+				}
+				notSkippedLoaded = true
+			}
+
+			candidates, err = r.candidates(chat.ID, notSkippedIDs, scope)
+			if err != nil {
+				return nil, err
+			}
 		}
 
-		err = query.Pluck("id", &questionIDs).Error
-		if err != nil {
-			return nil, err
+		if len(candidates) > 0 {
+			break
 		}
 	}
 
 	// Если все еще нет вопросов, возвращаем nil
-	if len(questionIDs) == 0 {
+	if len(candidates) == 0 {
 		return nil, nil
 	}
 
-	// Выбираем случайный вопрос из доступных
-	randomIndex := rand.Intn(len(questionIDs))
-	selectedID := questionIDs[randomIndex]
+	// Выбираем вопрос из доступных по стратегии чата
+	selectedID := selector.Select(chat, candidates)
 
 	// Получаем полную информацию о вопросе
 	var question models.Question
@@ -117,22 +117,122 @@ func (r *QuestionRepository) GetQuestion(chat *models.Chat, reactionRepo *Reacti
 	return &question, nil
 }
 
+// candidates возвращает опубликованные вопросы не от этого чата, кроме исключенных;
+// если заданы темы, только вопросы этих тем
+func (r *QuestionRepository) candidates(chatID uint, excluded []uint, topics []uint) ([]QuestionCandidate, error) {
+	query := r.db.Table("questions").Where("is_published = ?", true).
+		Where("(author_id IS NULL OR author_id != ?)", chatID)
+
+	if len(excluded) > 0 {
+		query = query.Where("id NOT IN ?", excluded)
+	}
+	if len(topics) > 0 {
+		query = query.Where("id IN (SELECT question_id FROM question_tags WHERE tag_id IN ?)", topics)
+	}
+
+	var candidates []QuestionCandidate
+	err := query.Select("id", "difficulty").Order("id").Scan(&candidates).Error
+	return candidates, err
+}
+
 // UpdateQuestionRating обновляет рейтинг конкретного вопроса
 func (r *QuestionRepository) UpdateQuestionRating(questionID uint) error {
-	query := `
-		UPDATE questions 
-		SET rating = (
-			SELECT COALESCE(
-				ROUND(
-					(COUNT(CASE WHEN r.responsed_at IS NOT NULL THEN 1 END) * 100.0) / 
-					NULLIF(COUNT(*), 0)
-				), 0
-			)
-			FROM reactions r 
-			WHERE r.question_id = ?
-		)
-		WHERE id = ? AND is_published = true
-	`
-
-	return r.db.Exec(query, questionID, questionID).Error
+	return updateQuestionRating(r.db, questionID)
+}
+
+// questionRatingQuery пересчитывает из реакций счетчики и сглаженный рейтинг опубликованных вопросов,
+// отобранных условием %[1]s. Реакция учитывается по итогу: ответ, иначе показ ответа, иначе пропуск;
+// реакции, где взята только подсказка, не учитываются
+const questionRatingQuery = `
+	UPDATE questions q
+	SET correct_count = s.correct,
+	    answer_count  = s.correct + s.revealed,
+	    skip_count    = s.skipped,
+	    rating        = ROUND(100.0 * (s.correct + %[2]d) / (s.correct + s.revealed + %[3]d))
+	FROM (
+		SELECT questions.id,
+		       COUNT(r.id) FILTER (WHERE r.responsed_at IS NOT NULL) AS correct,
+		       COUNT(r.id) FILTER (WHERE r.responsed_at IS NULL AND r.failed_at IS NOT NULL) AS revealed,
+		       COUNT(r.id) FILTER (WHERE r.responsed_at IS NULL AND r.failed_at IS NULL AND r.skipped_at IS NOT NULL) AS skipped
+		FROM questions
+		LEFT JOIN reactions r ON r.question_id = questions.id
+		WHERE questions.is_published AND %[1]s
+		GROUP BY questions.id
+	) s
+	WHERE q.id = s.id
+`
+
+// updateQuestionRating пересчитывает счетчики и рейтинг одного вопроса
+func updateQuestionRating(db *gorm.DB, questionID uint) error {
+	query := fmt.Sprintf(questionRatingQuery, "questions.id = ?", models.RatingPriorCorrect, models.RatingPriorAnswers)
+	return db.Exec(query, questionID).Error
+}
+
+// RecomputeRatings пересчитывает счетчики и рейтинг всех опубликованных вопросов одним запросом
+func (r *QuestionRepository) RecomputeRatings() (int64, error) {
+	query := fmt.Sprintf(questionRatingQuery, "TRUE", models.RatingPriorCorrect, models.RatingPriorAnswers)
+	result := r.db.Exec(query)
+	return result.RowsAffected, result.Error
+}
+
+// Create создает новый вопрос; варианты ответа, подсказки и картинку GORM создает в той же транзакции
+func (r *QuestionRepository) Create(question *models.Question) error {
+	return r.db.Create(question).Error
+}
+
+// UpdateContent обновляет текст, ответ и комментарий вопроса, если он еще не прошел модерацию
+func (r *QuestionRepository) UpdateContent(question *models.Question) (bool, error) {
+	result := r.db.Model(&models.Question{}).
+		Where("id = ? AND is_published = ? AND approved_at IS NULL AND rejected_at IS NULL", question.ID, false).
+		Updates(map[string]interface{}{
+			"text":    question.Text,
+			"answer":  question.Answer,
+			"comment": question.Comment,
+		})
+	return result.RowsAffected > 0, result.Error
+}
+
+// Approve публикует вопрос, если он еще не прошел модерацию, и начисляет автору награду
+func (r *QuestionRepository) Approve(questionID uint, reward int) (bool, error) {
+	approved := false
+	err := r.db.Transaction(func(tx *gorm.DB) error {
+		result := tx.Model(&models.Question{}).
+			Where("id = ? AND is_published = ? AND approved_at IS NULL AND rejected_at IS NULL", questionID, false).
+			Updates(map[string]interface{}{
+				"is_published": true,
+				"approved_at":  time.Now().UTC(),
+			})
+		if result.Error != nil || result.RowsAffected == 0 {
+			return result.Error
+		}
+
+		var question models.Question
+		if err := tx.Select("id", "author_id").First(&question, questionID).Error; err != nil {
+			return err
+		}
+
+		if question.AuthorID != nil && reward != 0 {
+			err := applyTransaction(tx, &models.CoinTransaction{
+				ChatID:     *question.AuthorID,
+				Delta:      reward,
+				Reason:     models.ReasonQuestionApproved,
+				QuestionID: &question.ID,
+			})
+			if err != nil {
+				return err
+			}
+		}
+
+		approved = true
+		return nil
+	})
+	return approved, err
+}
+
+// Reject отклоняет вопрос, если он еще не прошел модерацию
+func (r *QuestionRepository) Reject(questionID uint) (bool, error) {
+	result := r.db.Model(&models.Question{}).
+		Where("id = ? AND is_published = ? AND approved_at IS NULL AND rejected_at IS NULL", questionID, false).
+		Update("rejected_at", time.Now().UTC())
+	return result.RowsAffected > 0, result.Error
 }
@@ -19,10 +19,12 @@ func NewReactionRepository() *ReactionRepository {
 	}
 }
 
-// GetReactedQuestionIDs получает ID вопросов, на которые пользователь уже реагировал
+// GetReactedQuestionIDs получает ID вопросов, на которые пользователь уже реагировал (ответ, пропуск или показ ответа)
 func (r *ReactionRepository) GetReactedQuestionIDs(chatID uint) ([]uint, error) {
 	var reactions []models.Reaction
-	err := r.db.Table("reactions").Where("chat_id = ?", chatID).Find(&reactions).Error
+	err := r.db.Table("reactions").
+		Where("chat_id = ? AND (responsed_at IS NOT NULL OR skipped_at IS NOT NULL OR failed_at IS NOT NULL)", chatID).
+		Find(&reactions).Error
 
 	if err != nil {
 		return nil, err
@@ -52,38 +54,161 @@ func (r *ReactionRepository) GetNotSkippedQuestionIDs(chatID uint) ([]uint, erro
 
 // CreateOrUpdateReaction создает или обновляет реакцию пользователя на вопрос
 func (r *ReactionRepository) CreateOrUpdateReaction(chatID, questionID uint, reactionType string) error {
-	now := time.Now()
+	return saveReaction(r.db, chatID, questionID, reactionType)
+}
 
+// GetReaction получает реакцию пользователя на конкретный вопрос
+func (r *ReactionRepository) GetReaction(chatID, questionID uint) (*models.Reaction, error) {
 	var reaction models.Reaction
 	err := r.db.Where("chat_id = ? AND question_id = ?", chatID, questionID).First(&reaction).Error
+	if err != nil {
+		return nil, err
+	}
+	return &reaction, nil
+}
 
-	if err == gorm.ErrRecordNotFound {
-		// Создаем новую реакцию
-		reaction = models.Reaction{
-			ChatID:     chatID,
-			QuestionID: questionID,
-		}
+// MarkShown создает пустую реакцию при показе вопроса; существующую реакцию не меняет
+func (r *ReactionRepository) MarkShown(chatID, questionID uint) error {
+	return r.db.Exec(`
+		INSERT INTO reactions (chat_id, question_id, created_at)
+		VALUES (?, ?, NOW())
+		ON CONFLICT (chat_id, question_id) DO NOTHING
+	`, chatID, questionID).Error
+}
+
+// ReactionStats сводка закрытых вопросов. Каждая реакция учитывается один раз по итогу:
+// ответ важнее показа ответа, показ важнее пропуска
+type ReactionStats struct {
+	Chats    int
+	Answered int
+	Revealed int
+	Skipped  int
+	// AnswerTime среднее время от показа вопроса до правильного ответа
+	AnswerTime time.Duration
+}
+
+// Accuracy доля правильных ответов среди вопросов, на которые ответили или посмотрели ответ
+func (s ReactionStats) Accuracy() float64 {
+	if s.Answered+s.Revealed == 0 {
+		return 0
 	}
+	return float64(s.Answered) / float64(s.Answered+s.Revealed)
+}
 
-	// Обновляем соответствующие поля в зависимости от типа реакции
-	switch reactionType {
-	case "skip":
-		reaction.SkippedAt = &now
-	case "response":
-		reaction.ResponsedAt = &now
-	case "fail":
-		reaction.FailedAt = &now
+// AnsweredPerChat среднее число правильных ответов на чат
+func (s ReactionStats) AnsweredPerChat() float64 {
+	if s.Chats == 0 {
+		return 0
 	}
+	return float64(s.Answered) / float64(s.Chats)
+}
 
-	return r.db.Save(&reaction).Error
+// GetStats считает статистику чата
+func (r *ReactionRepository) GetStats(chatID uint) (*ReactionStats, error) {
+	return reactionStats(r.db.Where("chat_id = ?", chatID))
 }
 
-// GetReaction получает реакцию пользователя на конкретный вопрос
-func (r *ReactionRepository) GetReaction(chatID, questionID uint) (*models.Reaction, error) {
-	var reaction models.Reaction
-	err := r.db.Where("chat_id = ? AND question_id = ?", chatID, questionID).First(&reaction).Error
+// GetGlobalStats считает статистику по всем чатам
+func (r *ReactionRepository) GetGlobalStats() (*ReactionStats, error) {
+	return reactionStats(r.db)
+}
+
+// reactionStats считает статистику по реакциям, подходящим под условия запроса.
+// Время ответа берется только для вопросов, ответ на которые дан с первого показа (без пропуска).
+// Реакции, записанные до появления MarkShown, созданы в момент ответа, поэтому ответы быстрее секунды не учитываются
+func reactionStats(query *gorm.DB) (*ReactionStats, error) {
+	var row struct {
+		Chats         int
+		Answered      int
+		Revealed      int
+		Skipped       int
+		AnswerSeconds float64
+	}
+	err := query.Model(&models.Reaction{}).Select(`
+		COUNT(DISTINCT chat_id) FILTER (WHERE responsed_at IS NOT NULL OR failed_at IS NOT NULL OR skipped_at IS NOT NULL) AS chats,
+		COUNT(*) FILTER (WHERE responsed_at IS NOT NULL) AS answered,
+		COUNT(*) FILTER (WHERE responsed_at IS NULL AND failed_at IS NOT NULL) AS revealed,
+		COUNT(*) FILTER (WHERE responsed_at IS NULL AND failed_at IS NULL AND skipped_at IS NOT NULL) AS skipped,
+		COALESCE(AVG(EXTRACT(EPOCH FROM responsed_at - created_at))
+			FILTER (WHERE skipped_at IS NULL AND responsed_at >= created_at + INTERVAL '1 second'), 0) AS answer_seconds
+	`).Scan(&row).Error
 	if err != nil {
 		return nil, err
 	}
-	return &reaction, nil
+
+	return &ReactionStats{
+		Chats:      row.Chats,
+		Answered:   row.Answered,
+		Revealed:   row.Revealed,
+		Skipped:    row.Skipped,
+		AnswerTime: time.Duration(row.AnswerSeconds * float64(time.Second)),
+	}, nil
+}
+
+// GetAnswerDays возвращает дни (UTC) с правильными ответами чата по возрастанию
+func (r *ReactionRepository) GetAnswerDays(chatID uint) ([]time.Time, error) {
+	var days []time.Time
+	err := r.db.Raw(`
+		SELECT DISTINCT (responsed_at AT TIME ZONE 'UTC')::date AS day
+		FROM reactions
+		WHERE chat_id = ? AND responsed_at IS NOT NULL
+		ORDER BY day
+	`, chatID).Scan(&days).Error
+	return days, err
+}
+
+// Streak серия дней подряд с правильными ответами
+type Streak struct {
+	Current int
+	Best    int
+}
+
+// CountStreak считает текущую и лучшую серию по дням с ответами (по возрастанию).
+// Текущая серия не прерывается, пока не закончился следующий после последнего ответа день
+func CountStreak(days []time.Time, today time.Time) Streak {
+	var streak Streak
+	length := 0
+	for i, day := range days {
+		if i > 0 && dayOf(day).Sub(dayOf(days[i-1])) == 24*time.Hour {
+			length++
+		} else {
+			length = 1
+		}
+		streak.Best = max(streak.Best, length)
+	}
+
+	if len(days) > 0 && dayOf(today).Sub(dayOf(days[len(days)-1])) <= 24*time.Hour {
+		streak.Current = length
+	}
+	return streak
+}
+
+// dayOf возвращает начало дня по UTC
+func dayOf(t time.Time) time.Time {
+	t = t.UTC()
+	return time.Date(t.Year(), t.Month(), t.Day(), 0, 0, 0, 0, time.UTC)
+}
+
+// MemberStats правильные ответы участника группового чата
+type MemberStats struct {
+	ResponderID int64
+	// Name имя участника в последнем правильном ответе
+	Name     string
+	Answered int
+}
+
+// GetMemberStats возвращает участников чата по числу правильных ответов
+func (r *ReactionRepository) GetMemberStats(chatID uint, limit int) ([]MemberStats, error) {
+	var stats []MemberStats
+	err := r.db.Raw(`
+		SELECT responder_id,
+		       (ARRAY_AGG(responder_name ORDER BY responsed_at DESC))[1] AS name,
+		       COUNT(*) AS answered
+		FROM reactions
+		WHERE chat_id = ? AND responder_id IS NOT NULL AND responsed_at IS NOT NULL
+		GROUP BY responder_id
+		ORDER BY answered DESC, MAX(responsed_at)
+		LIMIT ?
+	`, chatID, limit).Scan(&stats).Error
+	return stats, err
 }
@@ -0,0 +1,59 @@
+package repository
+
+import (
+	"errors"
+	"gorm.io/gorm"
+	"qweasley/internal/database"
+	"qweasley/internal/models"
+	"time"
+)
+
+// ReviewRepository репозиторий для работы с карточками повторения
+type ReviewRepository struct {
+	db *gorm.DB
+}
+
+// NewReviewRepository создает новый репозиторий карточек повторения
+func NewReviewRepository() *ReviewRepository {
+	return &ReviewRepository{
+		db: database.GetDB(),
+	}
+}
+
+// GetDue возвращает самую давнюю наступившую карточку опубликованного вопроса или nil
+func (r *ReviewRepository) GetDue(chatID uint, now time.Time) (*models.ReviewCard, error) {
+	var card models.ReviewCard
+	err := r.due(chatID, now).Order("review_cards.due_at, review_cards.question_id").First(&card).Error
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	return &card, nil
+}
+
+// CountDue считает наступившие карточки опубликованных вопросов
+func (r *ReviewRepository) CountDue(chatID uint, now time.Time) (int, error) {
+	var count int64
+	err := r.due(chatID, now).Count(&count).Error
+	return int(count), err
+}
+
+// NextDue возвращает время ближайшего повторения или nil
+func (r *ReviewRepository) NextDue(chatID uint) (*time.Time, error) {
+	var next *time.Time
+	err := r.db.Model(&models.ReviewCard{}).
+		Joins("JOIN questions ON questions.id = review_cards.question_id AND questions.is_published").
+		Where("review_cards.chat_id = ?", chatID).
+		Select("MIN(review_cards.due_at)").
+		Scan(&next).Error
+	return next, err
+}
+
+// due запрос наступивших карточек чата; карточки снятых с публикации вопросов пропускаются
+func (r *ReviewRepository) due(chatID uint, now time.Time) *gorm.DB {
+	return r.db.Model(&models.ReviewCard{}).
+		Joins("JOIN questions ON questions.id = review_cards.question_id AND questions.is_published").
+		Where("review_cards.chat_id = ? AND review_cards.due_at <= ?", chatID, now)
+}
@@ -0,0 +1,306 @@
+package repository
+
+import (
+	"qweasley/internal/models"
+	"time"
+)
+
+// ChatStore хранилище чатов
+type ChatStore interface {
+	GetOrCreate(telegramID int64, title *string) (*models.Chat, error)
+	GetByID(chatID uint) (*models.Chat, error)
+	SetWaitingAnswer(chatID uint, questionID uint, expiresIn time.Duration) error
+	ClearWaitingAnswer(chatID uint) error
+	SetLastMessage(chatID uint, messageID int) error
+	SetWaitingFeedback(chatID uint, expiresIn time.Duration) error
+	ClearWaitingFeedback(chatID uint) error
+	// SetSuggestionStep переводит предложение вопроса на шаг step, сохраняя черновик, заполненный на предыдущих шагах
+	SetSuggestionStep(chatID uint, draft *models.SuggestionDraft, step string, expiresIn time.Duration) error
+	ClearSuggestion(chatID uint) error
+	SetModeration(chatID uint, questionID uint, action string, expiresIn time.Duration) error
+	ClearModeration(chatID uint) error
+	SetReplyFeedback(chatID uint, feedbackID uint, expiresIn time.Duration) error
+	ClearReplyFeedback(chatID uint) error
+	// SetDisplayName задает имя чата в таблице лидеров; nil возвращает название чата
+	SetDisplayName(chatID uint, name *string) error
+	SetLeaderboardHidden(chatID uint, hidden bool) error
+	SetBlitzMode(chatID uint, enabled bool) error
+	SetDifficultyMode(chatID uint, mode models.DifficultyMode) error
+	// SetReview запоминает вопрос повторения, ответа на который ждет чат
+	SetReview(chatID uint, questionID uint, expiresIn time.Duration) error
+	ClearReview(chatID uint) error
+	SetDailyEnabled(chatID uint, enabled bool) error
+}
+
+// QuestionStore хранилище вопросов
+type QuestionStore interface {
+	GetRandomPublished() (*models.Question, error)
+	GetByID(id uint) (*models.Question, error)
+	// GetQuestion выбирает вопрос для чата: сначала новые, затем пропущенные ранее.
+	// Непустые topics ограничивают выбор этими темами, пока в них есть вопросы;
+	// среди подходящих вопросов конкретный выбирает selector.
+	// Возвращает nil, если вопросов не осталось
+	GetQuestion(chat *models.Chat, reactions ReactionStore, topics []uint, selector QuestionSelector) (*models.Question, error)
+	UpdateQuestionRating(questionID uint) error
+	// RecomputeRatings пересчитывает рейтинг всех опубликованных вопросов и возвращает их число
+	RecomputeRatings() (int64, error)
+	// Create создает вопрос вместе с вариантами ответа, подсказками и картинкой вопроса
+	Create(question *models.Question) error
+	// UpdateContent обновляет текст, ответ и комментарий вопроса; false, если вопрос уже прошел модерацию
+	UpdateContent(question *models.Question) (bool, error)
+	Approve(questionID uint, reward int) (bool, error)
+	Reject(questionID uint) (bool, error)
+}
+
+// QuestionCandidate вопрос, который можно задать чату, и его сложность
+type QuestionCandidate struct {
+	ID         uint
+	Difficulty float64
+}
+
+// QuestionSelector стратегия выбора вопроса среди подходящих; candidates всегда не пуст
+type QuestionSelector interface {
+	Select(chat *models.Chat, candidates []QuestionCandidate) uint
+}
+
+// ReactionStore хранилище реакций; на пару (чат, вопрос) приходится не больше одной реакции
+type ReactionStore interface {
+	GetReactedQuestionIDs(chatID uint) ([]uint, error)
+	GetNotSkippedQuestionIDs(chatID uint) ([]uint, error)
+	CreateOrUpdateReaction(chatID, questionID uint, reactionType string) error
+	GetReaction(chatID, questionID uint) (*models.Reaction, error)
+	// MarkShown создает пустую реакцию при показе вопроса, если реакции еще нет: от нее считается время ответа
+	MarkShown(chatID, questionID uint) error
+	GetStats(chatID uint) (*ReactionStats, error)
+	// GetGlobalStats считает статистику по всем чатам; Chats — число чатов, закрывших хотя бы один вопрос
+	GetGlobalStats() (*ReactionStats, error)
+	// GetAnswerDays возвращает дни (UTC), в которые чат правильно ответил хотя бы на один вопрос, по возрастанию
+	GetAnswerDays(chatID uint) ([]time.Time, error)
+	// GetMemberStats возвращает участников чата, больше всех ответивших правильно
+	GetMemberStats(chatID uint, limit int) ([]MemberStats, error)
+}
+
+// TagStore хранилище тем вопросов и тем, выбранных чатами
+type TagStore interface {
+	// GetAll возвращает все темы по алфавиту вместе с числом опубликованных вопросов
+	GetAll() ([]models.Tag, error)
+	// GetByName ищет тему по имени без учета регистра
+	GetByName(name string) (*models.Tag, error)
+	// GetOrCreate находит тему по имени без учета регистра или создает новую
+	GetOrCreate(name string) (*models.Tag, error)
+	// TagQuestions относит к теме существующие вопросы из questionIDs и возвращает число новых связей
+	TagQuestions(tagID uint, questionIDs []uint) (int, error)
+	// UntagQuestions убирает вопросы из темы и возвращает число удаленных связей
+	UntagQuestions(tagID uint, questionIDs []uint) (int, error)
+	// GetChatTopics возвращает темы, выбранные чатом; пустой список означает все темы
+	GetChatTopics(chatID uint) ([]uint, error)
+	// ToggleChatTopic выбирает тему для чата или снимает выбор; возвращает, выбрана ли тема теперь
+	ToggleChatTopic(chatID, tagID uint) (bool, error)
+	ClearChatTopics(chatID uint) error
+}
+
+// ReviewStore карточки интервального повторения; создаются и пересчитываются через Tx
+type ReviewStore interface {
+	// GetDue возвращает карточку опубликованного вопроса, повторение которой наступило к now, самую давнюю первой;
+	// nil, если повторять нечего
+	GetDue(chatID uint, now time.Time) (*models.ReviewCard, error)
+	CountDue(chatID uint, now time.Time) (int, error)
+	// NextDue возвращает время ближайшего повторения; nil, если карточек нет
+	NextDue(chatID uint) (*time.Time, error)
+}
+
+// LeaderboardOrder порядок сортировки таблицы лидеров
+type LeaderboardOrder string
+
+const (
+	// LeaderboardByAnswers по числу правильных ответов
+	LeaderboardByAnswers LeaderboardOrder = "answers"
+	// LeaderboardByAccuracy по точности с учетом сложности вопросов
+	LeaderboardByAccuracy LeaderboardOrder = "accuracy"
+)
+
+// LeaderboardStore таблицы лидеров; итоги обновляются в Tx.AddScore при закрытии вопроса
+type LeaderboardStore interface {
+	// GetTop возвращает лучшие чаты за период, начавшийся в start, вместе с чатами.
+	// Скрытые чаты и чаты, закрывшие меньше minResolved вопросов, не попадают в таблицу
+	GetTop(period models.LeaderboardPeriod, start time.Time, order LeaderboardOrder, minResolved int, limit int) ([]models.LeaderboardScore, error)
+}
+
+// JobStore очередь отложенных задач
+type JobStore interface {
+	Schedule(jobs ...*models.ScheduledJob) error
+	// ClaimDue забирает до limit задач, срок которых наступил к now, самые ранние первыми, на время lease.
+	// Забранная задача остается в очереди, но другие планировщики не получат ее до истечения срока;
+	// задача, которую не завершили за это время (например, функцию прервали), забирается снова
+	ClaimDue(now time.Time, limit int, lease time.Duration) ([]models.ScheduledJob, error)
+	// ClaimDueForChat как ClaimDue, но только задачи чата с указанным Telegram ID
+	ClaimDueForChat(telegramID int64, now time.Time, limit int, lease time.Duration) ([]models.ScheduledJob, error)
+	// Complete удаляет выполненную задачу, если она все еще забрана этим планировщиком
+	Complete(job *models.ScheduledJob) error
+	// Retry возвращает забранную задачу в очередь с новым сроком, сохраняя число попыток
+	Retry(job *models.ScheduledJob, runAt time.Time) error
+	// Reschedule переносит забранную задачу на новый срок как новую: число попыток сбрасывается,
+	// а Complete после переноса задачу уже не удалит
+	Reschedule(job *models.ScheduledJob, runAt time.Time) error
+	// ScheduleOnce добавляет задачу, если в очереди нет задачи того же типа; единственность задачи
+	// обеспечивает уникальный индекс по типу (есть у daily_start). Возвращает, добавлена ли задача
+	ScheduleOnce(job *models.ScheduledJob) (bool, error)
+}
+
+// DailyStore вопросы дня и их отправка подписанным чатам
+type DailyStore interface {
+	// Start выбирает вопрос дня на day и создает отправки для всех подписанных чатов. Сначала берется самый ранний
+	// вопрос из очереди администратора, затем самый отвечаемый из вопросов, еще не бывших вопросами дня.
+	// Возвращает nil, если вопрос на этот день уже выбран, выбрать нечего или нет подписанных чатов
+	Start(day time.Time, expiresAt time.Time) (*models.DailyQuestion, error)
+	GetByQuestionID(questionID uint) (*models.DailyQuestion, error)
+	// GetPending возвращает до limit неотправленных отправок вместе с чатами;
+	// чаты, отписавшиеся после начала рассылки, пропускаются
+	GetPending(dailyID uint, limit int) ([]models.DailyDelivery, error)
+	MarkSent(dailyID, chatID uint) error
+	MarkFailed(dailyID, chatID uint) error
+	// GetUnsummarized возвращает до limit доставленных отправок, итоги по которым еще не отправлены, вместе с чатами;
+	// чаты, отписавшиеся после рассылки, пропускаются
+	GetUnsummarized(dailyID uint, limit int) ([]models.DailyDelivery, error)
+	// MarkSummarySent отмечает, что итоги чату отправлены или их не удалось доставить; повторно они не отправляются
+	MarkSummarySent(dailyID, chatID uint) error
+	// GetOpen возвращает доставленный чату вопрос дня, на который он еще не ответил и ответы на который
+	// принимаются к now; nil, если такого нет
+	GetOpen(chatID uint, now time.Time) (*models.DailyQuestion, error)
+	// MarkAnswered отмечает правильный ответ чата; false, если ответ уже отмечен
+	MarkAnswered(dailyID, chatID uint) (bool, error)
+	// Summary возвращает число чатов, которым вопрос дня доставлен, и число ответивших из них
+	Summary(dailyID uint) (int, int, error)
+	// Queue добавляет в очередь вопросов дня опубликованные вопросы, еще не бывшие вопросами дня
+	// и не стоящие в очереди, и возвращает их число
+	Queue(questionIDs []uint) (int, error)
+}
+
+// FeedbackStore хранилище обратной связи
+type FeedbackStore interface {
+	Create(feedback *models.Feedback) error
+	GetByID(id uint) (*models.Feedback, error)
+	GetByAdminMessageID(messageID int) (*models.Feedback, error)
+	SetAdminMessageID(id uint, messageID int) error
+	SaveResponse(id uint, response string) error
+	GetUnanswered(limit int) ([]models.Feedback, error)
+	CountUnanswered() (int64, error)
+}
+
+// LedgerStore журнал монет; Apply меняет баланс и пишет журнал атомарно
+type LedgerStore interface {
+	Apply(entry *models.CoinTransaction) error
+	GetHistory(chatID uint, limit int) ([]models.CoinTransaction, error)
+	Sum(chatID uint) (int, error)
+	Reconcile(chatID uint) (*Reconciliation, error)
+}
+
+// TransferStore хранилище переводов монет
+type TransferStore interface {
+	// Create резервирует сумму перевода на счете отправителя, создает код и ставит задачу возврата на момент истечения кода
+	Create(transfer *models.CoinTransfer) error
+	// Redeem начисляет зарезервированные монеты получателю
+	Redeem(code string, toChatID uint) (*models.CoinTransfer, error)
+	// RefundExpired возвращает отправителю монеты по его истекшим и не полученным кодам и возвращает эти переводы
+	RefundExpired(fromChatID uint, now time.Time) ([]models.CoinTransfer, error)
+}
+
+// PurchaseStore хранилище покупок монет
+type PurchaseStore interface {
+	Credit(purchase *models.Purchase) (bool, error)
+}
+
+// UpdateClaim результат попытки занять обновление Telegram для обработки
+type UpdateClaim int
+
+const (
+	// UpdateClaimed обновление занято этим вызовом и должно быть обработано
+	UpdateClaimed UpdateClaim = iota
+	// UpdateBusy обновление обрабатывает другой вызов, и срок его занятости еще не истек
+	UpdateBusy
+	// UpdateProcessed обновление уже обработано
+	UpdateProcessed
+)
+
+// UpdateStore состояние обработки обновлений Telegram для защиты от повторной доставки
+type UpdateStore interface {
+	// Claim занимает обновление на время lease; занятое, но не обработанное к этому сроку обновление можно занять снова
+	Claim(updateID int, lease time.Duration) (UpdateClaim, error)
+	// MarkProcessed отмечает занятое обновление обработанным
+	MarkProcessed(updateID int) error
+	// DeleteOlderThan удаляет отметки о полученных раньше указанного возраста обновлениях
+	DeleteOlderThan(age time.Duration) (int64, error)
+}
+
+// UnitOfWork выполняет несколько изменений атомарно: либо все, либо ни одного
+type UnitOfWork interface {
+	Do(fn func(tx Tx) error) error
+}
+
+// Tx операции, доступные внутри UnitOfWork
+type Tx interface {
+	// LockChat читает чат и блокирует его от параллельных изменений до конца транзакции
+	LockChat(chatID uint) (*models.Chat, error)
+	SaveReaction(chatID, questionID uint, reactionType string) error
+	ApplyTransaction(entry *models.CoinTransaction) error
+	// ClearWaitingAnswer возвращает ErrQuestionChanged, если чат уже не ждет ответа на этот вопрос
+	ClearWaitingAnswer(chatID, questionID uint) error
+	UpdateQuestionRating(questionID uint) error
+	// AddHint отмечает в реакции еще одну взятую подсказку стоимостью cost и возвращает обновленную реакцию
+	AddHint(chatID, questionID uint, cost float64) (*models.Reaction, error)
+	// AddAttempt отмечает в реакции еще один неверный ответ и возвращает обновленную реакцию
+	AddAttempt(chatID, questionID uint) (*models.Reaction, error)
+	// AddScore добавляет закрытый вопрос в итоги чата за все периоды таблицы лидеров;
+	// вес вопроса берется по его рейтингу до пересчета
+	AddScore(chatID, questionID uint, correct bool) error
+	// SetResponder запоминает в реакции пользователя, приславшего правильный ответ
+	SetResponder(chatID, questionID uint, responderID int64, name string) error
+	// UpdateElo пересчитывает уровень чата и сложность вопроса после ответа или показа ответа
+	UpdateElo(chatID, questionID uint, correct bool) error
+	// AddReviewCard ставит вопрос на повторение к dueAt; существующая карточка не меняется
+	AddReviewCard(chatID, questionID uint, dueAt time.Time) error
+	// ClearReview возвращает ErrQuestionChanged, если чат уже не ждет ответа на этот вопрос повторения
+	ClearReview(chatID, questionID uint) error
+	// GradeReview пересчитывает карточку по оценке quality и возвращает ее с датой следующего повторения
+	GradeReview(chatID, questionID uint, quality int, now time.Time) (*models.ReviewCard, error)
+}
+
+// Stores набор хранилищ, с которыми работают обработчики
+type Stores struct {
+	Chats     ChatStore
+	Questions QuestionStore
+	Reactions ReactionStore
+	Feedbacks FeedbackStore
+	Ledger    LedgerStore
+	Transfers TransferStore
+	Purchases PurchaseStore
+	Updates   UpdateStore
+	Tags      TagStore
+	Reviews   ReviewStore
+	Daily     DailyStore
+
+	Leaderboard LeaderboardStore
+	Jobs        JobStore
+	UnitOfWork  UnitOfWork
+}
+
+// NewPostgresStores создает хранилища поверх подключения к PostgreSQL
+func NewPostgresStores() *Stores {
+	return &Stores{
+		Chats:     NewChatRepository(),
+		Questions: NewQuestionRepository(),
+		Reactions: NewReactionRepository(),
+		Feedbacks: NewFeedbackRepository(),
+		Ledger:    NewLedgerRepository(),
+		Transfers: NewTransferRepository(),
+		Purchases: NewPurchaseRepository(),
+		Updates:   NewUpdateRepository(),
+		Tags:      NewTagRepository(),
+		Reviews:   NewReviewRepository(),
+		Daily:     NewDailyRepository(),
+
+		Leaderboard: NewLeaderboardRepository(),
+		Jobs:        NewJobRepository(),
+		UnitOfWork:  NewUnitOfWorkRepository(),
+	}
+}
@@ -0,0 +1,108 @@
+package repository
+
+import (
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+	"qweasley/internal/database"
+	"qweasley/internal/models"
+)
+
+// TagRepository репозиторий для работы с темами вопросов
+type TagRepository struct {
+	db *gorm.DB
+}
+
+// NewTagRepository создает новый репозиторий тем
+func NewTagRepository() *TagRepository {
+	return &TagRepository{
+		db: database.GetDB(),
+	}
+}
+
+// GetAll возвращает все темы по алфавиту вместе с числом опубликованных вопросов
+func (r *TagRepository) GetAll() ([]models.Tag, error) {
+	var tags []models.Tag
+	err := r.db.Raw(`
+		SELECT tags.*, COUNT(questions.id) AS questions
+		FROM tags
+		LEFT JOIN question_tags ON question_tags.tag_id = tags.id
+		LEFT JOIN questions ON questions.id = question_tags.question_id AND questions.is_published
+		GROUP BY tags.id
+		ORDER BY LOWER(tags.name)`).
+		Scan(&tags).Error
+	return tags, err
+}
+
+// GetByName ищет тему по имени без учета регистра
+func (r *TagRepository) GetByName(name string) (*models.Tag, error) {
+	var tag models.Tag
+	err := r.db.Where("LOWER(name) = LOWER(?)", name).First(&tag).Error
+	if err != nil {
+		return nil, err
+	}
+	return &tag, nil
+}
+
+// GetOrCreate находит тему по имени без учета регистра или создает новую
+func (r *TagRepository) GetOrCreate(name string) (*models.Tag, error) {
+	err := r.db.Exec("INSERT INTO tags (name) VALUES (?) ON CONFLICT ((LOWER(name))) DO NOTHING", name).Error
+	if err != nil {
+		return nil, err
+	}
+	return r.GetByName(name)
+}
+
+// TagQuestions относит к теме существующие вопросы; уже отнесенные пропускаются
+func (r *TagRepository) TagQuestions(tagID uint, questionIDs []uint) (int, error) {
+	if len(questionIDs) == 0 {
+		return 0, nil
+	}
+
+	result := r.db.Exec(`
+		INSERT INTO question_tags (question_id, tag_id)
+		SELECT id, ? FROM questions WHERE id IN ?
+		ON CONFLICT DO NOTHING`, tagID, questionIDs)
+	return int(result.RowsAffected), result.Error
+}
+
+// UntagQuestions убирает вопросы из темы
+func (r *TagRepository) UntagQuestions(tagID uint, questionIDs []uint) (int, error) {
+	if len(questionIDs) == 0 {
+		return 0, nil
+	}
+
+	result := r.db.Where("tag_id = ? AND question_id IN ?", tagID, questionIDs).
+		Delete(&models.QuestionTag{})
+	return int(result.RowsAffected), result.Error
+}
+
+// GetChatTopics возвращает темы, выбранные чатом
+func (r *TagRepository) GetChatTopics(chatID uint) ([]uint, error) {
+	var tagIDs []uint
+	err := r.db.Model(&models.ChatTopic{}).
+		Where("chat_id = ?", chatID).
+		Order("tag_id").
+		Pluck("tag_id", &tagIDs).Error
+	return tagIDs, err
+}
+
+// ToggleChatTopic выбирает тему для чата или снимает выбор
+func (r *TagRepository) ToggleChatTopic(chatID, tagID uint) (bool, error) {
+	selected := false
+	err := r.db.Transaction(func(tx *gorm.DB) error {
+		result := tx.Where("chat_id = ? AND tag_id = ?", chatID, tagID).Delete(&models.ChatTopic{})
+		if result.Error != nil || result.RowsAffected > 0 {
+			return result.Error
+		}
+
+		selected = true
+		return tx.Clauses(clause.OnConflict{DoNothing: true}).
+			Create(&models.ChatTopic{ChatID: chatID, TagID: tagID}).Error
+	})
+	return selected, err
+}
+
+// ClearChatTopics снимает выбор всех тем: чату снова задаются вопросы из всех тем
+func (r *TagRepository) ClearChatTopics(chatID uint) error {
+	return r.db.Where("chat_id = ?", chatID).Delete(&models.ChatTopic{}).Error
+}
@@ -0,0 +1,160 @@
+package repository
+
+import (
+	"errors"
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+	"qweasley/internal/database"
+	"qweasley/internal/models"
+	"time"
+)
+
+// Ошибки погашения кода перевода
+var (
+	ErrTransferNotFound    = errors.New("transfer not found")
+	ErrTransferRedeemed    = errors.New("transfer already redeemed")
+	ErrTransferExpired     = errors.New("transfer expired")
+	ErrSelfTransfer        = errors.New("self transfer")
+	ErrInsufficientBalance = errors.New("insufficient balance")
+)
+
+// TransferRepository репозиторий для работы с переводами монет
+type TransferRepository struct {
+	db *gorm.DB
+}
+
+// NewTransferRepository создает новый репозиторий переводов
+func NewTransferRepository() *TransferRepository {
+	return &TransferRepository{
+		db: database.GetDB(),
+	}
+}
+
+// Create резервирует сумму перевода: списывает монеты у отправителя, создает код и ставит задачу возврата
+// на момент истечения кода в одной транзакции
+func (r *TransferRepository) Create(transfer *models.CoinTransfer) error {
+	return r.db.Transaction(func(tx *gorm.DB) error {
+		var chat models.Chat
+		err := tx.Clauses(clause.Locking{Strength: "UPDATE"}).First(&chat, transfer.FromChatID).Error
+		if err != nil {
+			return err
+		}
+		if chat.Balance < transfer.Amount {
+			return ErrInsufficientBalance
+		}
+
+		if err := tx.Create(transfer).Error; err != nil {
+			return err
+		}
+
+		err = applyTransaction(tx, &models.CoinTransaction{
+			ChatID:     transfer.FromChatID,
+			Delta:      -transfer.Amount,
+			Reason:     models.ReasonTransferOut,
+			ExternalID: &transfer.Code,
+		})
+		if err != nil {
+			return err
+		}
+
+		return tx.Create(&models.ScheduledJob{
+			RunAt:  transfer.ExpiresAt,
+			Kind:   models.JobTransferExpire,
+			ChatID: &transfer.FromChatID,
+		}).Error
+	})
+}
+
+// Redeem погашает код перевода: начисляет получателю монеты, зарезервированные при создании кода
+func (r *TransferRepository) Redeem(code string, toChatID uint) (*models.CoinTransfer, error) {
+	var transfer models.CoinTransfer
+
+	err := r.db.Transaction(func(tx *gorm.DB) error {
+		err := tx.Clauses(clause.Locking{Strength: "UPDATE"}).Where("code = ?", code).First(&transfer).Error
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return ErrTransferNotFound
+		}
+		if err != nil {
+			return err
+		}
+
+		if transfer.RedeemedAt != nil {
+			return ErrTransferRedeemed
+		}
+		if transfer.RefundedAt != nil || time.Now().UTC().After(transfer.ExpiresAt) {
+			return ErrTransferExpired
+		}
+		if transfer.FromChatID == toChatID {
+			return ErrSelfTransfer
+		}
+
+		err = applyTransaction(tx, &models.CoinTransaction{
+			ChatID:        toChatID,
+			Delta:         transfer.Amount,
+			Reason:        models.ReasonTransferIn,
+			RelatedChatID: &transfer.FromChatID,
+			ExternalID:    &transfer.Code,
+		})
+		if err != nil {
+			return err
+		}
+
+		now := time.Now().UTC()
+		transfer.ToChatID = &toChatID
+		transfer.RedeemedAt = &now
+
+		return tx.Model(&models.CoinTransfer{}).Where("id = ?", transfer.ID).Updates(map[string]interface{}{
+			"to_chat_id":  toChatID,
+			"redeemed_at": now,
+		}).Error
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	if err := r.db.Preload("FromChat").First(&transfer, transfer.ID).Error; err != nil {
+		return nil, err
+	}
+
+	return &transfer, nil
+}
+
+// RefundExpired возвращает отправителю монеты по истекшим и не полученным кодам в одной транзакции;
+// коды блокируются, поэтому одновременное погашение или повторный возврат их не затронут
+func (r *TransferRepository) RefundExpired(fromChatID uint, now time.Time) ([]models.CoinTransfer, error) {
+	var transfers []models.CoinTransfer
+
+	err := r.db.Transaction(func(tx *gorm.DB) error {
+		err := tx.Clauses(clause.Locking{Strength: "UPDATE"}).
+			Where("from_chat_id = ? AND expires_at <= ? AND redeemed_at IS NULL AND refunded_at IS NULL", fromChatID, now).
+			Order("id").
+			Find(&transfers).Error
+		if err != nil {
+			return err
+		}
+
+		for i := range transfers {
+			transfer := &transfers[i]
+			err := applyTransaction(tx, &models.CoinTransaction{
+				ChatID:     transfer.FromChatID,
+				Delta:      transfer.Amount,
+				Reason:     models.ReasonRefund,
+				ExternalID: &transfer.Code,
+			})
+			if err != nil {
+				return err
+			}
+
+			transfer.RefundedAt = &now
+			if err := tx.Model(&models.CoinTransfer{}).Where("id = ?", transfer.ID).Update("refunded_at", now).Error; err != nil {
+				return err
+			}
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	return transfers, nil
+}
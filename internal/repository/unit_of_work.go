@@ -0,0 +1,227 @@
+package repository
+
+import (
+	"errors"
+	"fmt"
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+	"qweasley/internal/database"
+	"qweasley/internal/models"
+	"time"
+)
+
+// ErrQuestionChanged активный вопрос чата сменился или уже обработан параллельным запросом
+var ErrQuestionChanged = errors.New("question is no longer active")
+
+// reactionColumns колонка реакции для каждого типа
+var reactionColumns = map[string]string{
+	"skip":     "skipped_at",
+	"response": "responsed_at",
+	"fail":     "failed_at",
+}
+
+// UnitOfWorkRepository выполняет несколько изменений в одной транзакции PostgreSQL
+type UnitOfWorkRepository struct {
+	db *gorm.DB
+}
+
+// NewUnitOfWorkRepository создает новый репозиторий транзакций
+func NewUnitOfWorkRepository() *UnitOfWorkRepository {
+	return &UnitOfWorkRepository{
+		db: database.GetDB(),
+	}
+}
+
+// Do выполняет fn в одной транзакции; ошибка из fn откатывает все изменения
+func (r *UnitOfWorkRepository) Do(fn func(tx Tx) error) error {
+	return r.db.Transaction(func(tx *gorm.DB) error {
+		return fn(&postgresTx{db: tx})
+	})
+}
+
+// postgresTx операции внутри открытой транзакции
+type postgresTx struct {
+	db *gorm.DB
+}
+
+// LockChat читает чат с блокировкой строки до конца транзакции
+func (t *postgresTx) LockChat(chatID uint) (*models.Chat, error) {
+	var chat models.Chat
+	err := t.db.Clauses(clause.Locking{Strength: "UPDATE"}).First(&chat, chatID).Error
+	if err != nil {
+		return nil, err
+	}
+	return &chat, nil
+}
+
+// SaveReaction создает или обновляет реакцию чата на вопрос
+func (t *postgresTx) SaveReaction(chatID, questionID uint, reactionType string) error {
+	return saveReaction(t.db, chatID, questionID, reactionType)
+}
+
+// ApplyTransaction изменяет баланс и пишет журнал
+func (t *postgresTx) ApplyTransaction(entry *models.CoinTransaction) error {
+	return applyTransaction(t.db, entry)
+}
+
+// ClearWaitingAnswer снимает ожидание ответа, только если чат все еще ждет ответа на этот вопрос
+func (t *postgresTx) ClearWaitingAnswer(chatID, questionID uint) error {
+	result := t.db.Model(&models.Chat{}).
+		Where("id = ? AND last_question_id = ?", chatID, questionID).
+		Updates(map[string]interface{}{
+			"last_question_id": nil,
+			"expires_at":       nil,
+		})
+	if result.Error != nil {
+		return result.Error
+	}
+	if result.RowsAffected == 0 {
+		return ErrQuestionChanged
+	}
+	return nil
+}
+
+// UpdateQuestionRating пересчитывает рейтинг вопроса
+func (t *postgresTx) UpdateQuestionRating(questionID uint) error {
+	return updateQuestionRating(t.db, questionID)
+}
+
+// AddHint одним запросом создает реакцию или увеличивает в ней счетчик и стоимость подсказок
+func (t *postgresTx) AddHint(chatID, questionID uint, cost float64) (*models.Reaction, error) {
+	var reaction models.Reaction
+	err := t.db.Raw(`
+		INSERT INTO reactions (chat_id, question_id, created_at, hints_used, hint_cost)
+		VALUES (?, ?, NOW(), 1, ?)
+		ON CONFLICT (chat_id, question_id) DO UPDATE
+		SET hints_used = reactions.hints_used + 1,
+		    hint_cost = reactions.hint_cost + EXCLUDED.hint_cost
+		RETURNING *
+	`, chatID, questionID, cost).Scan(&reaction).Error
+	if err != nil {
+		return nil, err
+	}
+	return &reaction, nil
+}
+
+// AddAttempt одним запросом создает реакцию или увеличивает в ней счетчик неверных ответов
+func (t *postgresTx) AddAttempt(chatID, questionID uint) (*models.Reaction, error) {
+	var reaction models.Reaction
+	err := t.db.Raw(`
+		INSERT INTO reactions (chat_id, question_id, created_at, attempts)
+		VALUES (?, ?, NOW(), 1)
+		ON CONFLICT (chat_id, question_id) DO UPDATE
+		SET attempts = reactions.attempts + 1
+		RETURNING *
+	`, chatID, questionID).Scan(&reaction).Error
+	if err != nil {
+		return nil, err
+	}
+	return &reaction, nil
+}
+
+// AddScore добавляет закрытый вопрос в итоги таблиц лидеров
+func (t *postgresTx) AddScore(chatID, questionID uint, correct bool) error {
+	return addScore(t.db, chatID, questionID, correct, time.Now())
+}
+
+// SetResponder запоминает в реакции пользователя, приславшего правильный ответ
+func (t *postgresTx) SetResponder(chatID, questionID uint, responderID int64, name string) error {
+	return t.db.Model(&models.Reaction{}).
+		Where("chat_id = ? AND question_id = ?", chatID, questionID).
+		Updates(map[string]interface{}{
+			"responder_id":   responderID,
+			"responder_name": name,
+		}).Error
+}
+
+// UpdateElo пересчитывает уровень чата и сложность вопроса; строка чата уже заблокирована в LockChat,
+// строку вопроса блокируем здесь, чтобы параллельные ответы других чатов не потеряли обновление
+func (t *postgresTx) UpdateElo(chatID, questionID uint, correct bool) error {
+	var chat models.Chat
+	if err := t.db.Select("id", "skill").First(&chat, chatID).Error; err != nil {
+		return err
+	}
+
+	var question models.Question
+	err := t.db.Clauses(clause.Locking{Strength: "UPDATE"}).
+		Select("id", "difficulty").
+		First(&question, questionID).Error
+	if err != nil {
+		return err
+	}
+
+	skill, difficulty := models.EloUpdate(chat.Skill, question.Difficulty, correct)
+	if err := t.db.Model(&models.Chat{}).Where("id = ?", chatID).Update("skill", skill).Error; err != nil {
+		return err
+	}
+	return t.db.Model(&models.Question{}).Where("id = ?", questionID).Update("difficulty", difficulty).Error
+}
+
+// AddReviewCard ставит вопрос на повторение, если карточки еще нет
+func (t *postgresTx) AddReviewCard(chatID, questionID uint, dueAt time.Time) error {
+	return t.db.Clauses(clause.OnConflict{DoNothing: true}).Create(&models.ReviewCard{
+		ChatID:     chatID,
+		QuestionID: questionID,
+		DueAt:      dueAt,
+		Ease:       models.ReviewInitialEase,
+	}).Error
+}
+
+// ClearReview снимает ожидание ответа, только если чат все еще ждет ответа на этот вопрос повторения
+func (t *postgresTx) ClearReview(chatID, questionID uint) error {
+	result := t.db.Model(&models.Chat{}).
+		Where("id = ? AND review_question_id = ?", chatID, questionID).
+		Updates(map[string]interface{}{
+			"review_question_id": nil,
+			"review_expires_at":  nil,
+		})
+	if result.Error != nil {
+		return result.Error
+	}
+	if result.RowsAffected == 0 {
+		return ErrQuestionChanged
+	}
+	return nil
+}
+
+// GradeReview читает карточку с блокировкой, пересчитывает ее и сохраняет
+func (t *postgresTx) GradeReview(chatID, questionID uint, quality int, now time.Time) (*models.ReviewCard, error) {
+	var card models.ReviewCard
+	err := t.db.Clauses(clause.Locking{Strength: "UPDATE"}).
+		Where("chat_id = ? AND question_id = ?", chatID, questionID).
+		First(&card).Error
+	if err != nil {
+		return nil, err
+	}
+
+	card.Grade(quality, now)
+	err = t.db.Model(&models.ReviewCard{}).
+		Where("chat_id = ? AND question_id = ?", chatID, questionID).
+		Updates(map[string]interface{}{
+			"due_at":        card.DueAt,
+			"repetitions":   card.Repetitions,
+			"interval_days": card.IntervalDays,
+			"ease":          card.Ease,
+			"reviewed_at":   card.ReviewedAt,
+		}).Error
+	if err != nil {
+		return nil, err
+	}
+	return &card, nil
+}
+
+// saveReaction одним запросом создает реакцию или отмечает новый тип реакции в существующей
+func saveReaction(db *gorm.DB, chatID, questionID uint, reactionType string) error {
+	column, ok := reactionColumns[reactionType]
+	if !ok {
+		return fmt.Errorf("unknown reaction type: %s", reactionType)
+	}
+
+	query := fmt.Sprintf(`
+		INSERT INTO reactions (chat_id, question_id, created_at, %[1]s)
+		VALUES (?, ?, NOW(), NOW())
+		ON CONFLICT (chat_id, question_id) DO UPDATE SET %[1]s = EXCLUDED.%[1]s
+	`, column)
+
+	return db.Exec(query, chatID, questionID).Error
+}
@@ -0,0 +1,66 @@
+package repository
+
+import (
+	"gorm.io/gorm"
+	"qweasley/internal/database"
+	"qweasley/internal/models"
+	"time"
+)
+
+// UpdateRepository репозиторий обработанных обновлений Telegram
+type UpdateRepository struct {
+	db *gorm.DB
+}
+
+// NewUpdateRepository создает новый репозиторий обработанных обновлений
+func NewUpdateRepository() *UpdateRepository {
+	return &UpdateRepository{
+		db: database.GetDB(),
+	}
+}
+
+// Claim занимает обновление на время lease одним запросом: новое обновление добавляется,
+// а занятое раньше перезанимается, только если оно не обработано и срок занятости истек
+func (r *UpdateRepository) Claim(updateID int, lease time.Duration) (UpdateClaim, error) {
+	now := time.Now().UTC()
+
+	var claimed []int
+	err := r.db.Raw(`
+		INSERT INTO processed_updates (update_id, received_at, locked_until)
+		VALUES (?, ?, ?)
+		ON CONFLICT (update_id) DO UPDATE SET locked_until = EXCLUDED.locked_until
+		WHERE processed_updates.processed_at IS NULL AND processed_updates.locked_until <= ?
+		RETURNING update_id
+	`, updateID, now, now.Add(lease), now).Scan(&claimed).Error
+	if err != nil {
+		return UpdateClaimed, err
+	}
+	if len(claimed) > 0 {
+		return UpdateClaimed, nil
+	}
+
+	var update models.ProcessedUpdate
+	if err := r.db.First(&update, "update_id = ?", updateID).Error; err != nil {
+		return UpdateClaimed, err
+	}
+	if update.ProcessedAt != nil {
+		return UpdateProcessed, nil
+	}
+	return UpdateBusy, nil
+}
+
+// MarkProcessed отмечает обновление обработанным
+func (r *UpdateRepository) MarkProcessed(updateID int) error {
+	return r.db.Model(&models.ProcessedUpdate{}).
+		Where("update_id = ?", updateID).
+		Updates(map[string]interface{}{
+			"processed_at": time.Now().UTC(),
+			"locked_until": nil,
+		}).Error
+}
+
+// DeleteOlderThan удаляет отметки старше указанного возраста
+func (r *UpdateRepository) DeleteOlderThan(age time.Duration) (int64, error) {
+	result := r.db.Where("received_at < ?", time.Now().UTC().Add(-age)).Delete(&models.ProcessedUpdate{})
+	return result.RowsAffected, result.Error
+}
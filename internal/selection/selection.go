@@ -0,0 +1,74 @@
+// Package selection содержит стратегии выбора следующего вопроса для чата
+package selection
+
+import (
+	"math"
+	"math/rand"
+	"qweasley/internal/models"
+	"qweasley/internal/repository"
+	"sort"
+)
+
+// nearestPool из скольких ближайших по сложности вопросов выбирается случайный,
+// чтобы чат с одним и тем же уровнем не получал вопросы в одном и том же порядке
+const nearestPool = 5
+
+// adaptiveSuccess вероятность правильного ответа, на которую нацелен адаптивный режим
+const adaptiveSuccess = 0.6
+
+// Random выбирает случайный вопрос без учета сложности
+type Random struct{}
+
+// Select выбирает случайный вопрос
+func (Random) Select(chat *models.Chat, candidates []repository.QuestionCandidate) uint {
+	return candidates[rand.Intn(len(candidates))].ID
+}
+
+// Fixed выбирает вопросы около заданной сложности, независимо от уровня чата
+type Fixed struct {
+	Difficulty float64
+}
+
+// Select выбирает вопрос, близкий к заданной сложности
+func (s Fixed) Select(chat *models.Chat, candidates []repository.QuestionCandidate) uint {
+	return nearest(candidates, s.Difficulty)
+}
+
+// Adaptive выбирает вопросы около уровня чата: такие, на которые он отвечает правильно
+// примерно с вероятностью adaptiveSuccess
+type Adaptive struct{}
+
+// Select выбирает вопрос, близкий к уровню чата
+func (Adaptive) Select(chat *models.Chat, candidates []repository.QuestionCandidate) uint {
+	target := chat.Skill - 400*math.Log10(adaptiveSuccess/(1-adaptiveSuccess))
+	return nearest(candidates, target)
+}
+
+// strategies стратегии выбора для режимов сложности
+var strategies = map[models.DifficultyMode]repository.QuestionSelector{
+	models.DifficultyRandom:   Random{},
+	models.DifficultyEasy:     Fixed{Difficulty: models.InitialElo - 200},
+	models.DifficultyNormal:   Fixed{Difficulty: models.InitialElo},
+	models.DifficultyHard:     Fixed{Difficulty: models.InitialElo + 200},
+	models.DifficultyAdaptive: Adaptive{},
+}
+
+// ForMode возвращает стратегию для режима сложности; для неизвестного режима — случайный выбор и false
+func ForMode(mode models.DifficultyMode) (repository.QuestionSelector, bool) {
+	selector, ok := strategies[mode]
+	if !ok {
+		return Random{}, false
+	}
+	return selector, true
+}
+
+// nearest выбирает случайный вопрос среди nearestPool ближайших по сложности к target
+func nearest(candidates []repository.QuestionCandidate, target float64) uint {
+	sorted := append([]repository.QuestionCandidate(nil), candidates...)
+	sort.SliceStable(sorted, func(i, j int) bool {
+		return math.Abs(sorted[i].Difficulty-target) < math.Abs(sorted[j].Difficulty-target)
+	})
+
+	pool := min(nearestPool, len(sorted))
+	return sorted[rand.Intn(pool)].ID
+}
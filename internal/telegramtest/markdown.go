@@ -0,0 +1,126 @@
+package telegramtest
+
+import (
+	"fmt"
+	"strings"
+)
+
+// ValidateMarkdownV2 проверяет текст по правилам разметки MarkdownV2 и возвращает ошибку
+// с тем же описанием, что и Telegram: неэкранированный служебный символ или незакрытая сущность
+func ValidateMarkdownV2(text string) error {
+	type entity struct {
+		marker string
+		offset int
+	}
+	var open []entity
+
+	// toggle открывает сущность или закрывает последнюю открытую с тем же маркером
+	toggle := func(marker string, offset int) error {
+		if len(open) > 0 && open[len(open)-1].marker == marker {
+			open = open[:len(open)-1]
+			return nil
+		}
+		for _, e := range open {
+			if e.marker == marker {
+				return fmt.Errorf("can't find end of the entity starting at byte offset %d", e.offset)
+			}
+		}
+		open = append(open, entity{marker: marker, offset: offset})
+		return nil
+	}
+
+	lineStart := true
+	for i := 0; i < len(text); i++ {
+		c := text[i]
+		atLineStart := lineStart
+		lineStart = c == '\n'
+
+		switch c {
+		case '\\':
+			if i+1 >= len(text) {
+				return fmt.Errorf("character '\\' is reserved and must be escaped with the preceding '\\'")
+			}
+			i++
+		case '`':
+			marker := "`"
+			if strings.HasPrefix(text[i:], "```") {
+				marker = "```"
+			}
+			end := findClosing(text, i+len(marker), marker)
+			if end < 0 {
+				if marker == "```" {
+					return fmt.Errorf("can't find end of Pre entity at byte offset %d", i)
+				}
+				return fmt.Errorf("can't find end of Code entity at byte offset %d", i)
+			}
+			i = end + len(marker) - 1
+		case '*', '~':
+			if err := toggle(string(c), i); err != nil {
+				return err
+			}
+		case '_':
+			marker := "_"
+			if strings.HasPrefix(text[i:], "__") {
+				marker = "__"
+			}
+			if err := toggle(marker, i); err != nil {
+				return err
+			}
+			i += len(marker) - 1
+		case '|':
+			if !strings.HasPrefix(text[i:], "||") {
+				return reservedError(c)
+			}
+			if err := toggle("||", i); err != nil {
+				return err
+			}
+			i++
+		case '[':
+			open = append(open, entity{marker: "[", offset: i})
+		case ']':
+			if len(open) == 0 || open[len(open)-1].marker != "[" {
+				return reservedError(c)
+			}
+			open = open[:len(open)-1]
+			if i+1 >= len(text) || text[i+1] != '(' {
+				// Без адреса квадратные скобки остаются обычным текстом, но Telegram требует их экранировать
+				return reservedError('[')
+			}
+			end := findClosing(text, i+2, ")")
+			if end < 0 {
+				return fmt.Errorf("can't find end of a URL at byte offset %d", i+1)
+			}
+			i = end
+		case '>':
+			if !atLineStart {
+				return reservedError(c)
+			}
+		case '(', ')', '#', '+', '-', '=', '{', '}', '.', '!':
+			return reservedError(c)
+		}
+	}
+
+	if len(open) > 0 {
+		return fmt.Errorf("can't find end of the entity starting at byte offset %d", open[0].offset)
+	}
+	return nil
+}
+
+// findClosing ищет неэкранированный маркер, начиная с позиции from, и возвращает его позицию или -1
+func findClosing(text string, from int, marker string) int {
+	for i := from; i < len(text); i++ {
+		if text[i] == '\\' {
+			i++
+			continue
+		}
+		if strings.HasPrefix(text[i:], marker) {
+			return i
+		}
+	}
+	return -1
+}
+
+// reservedError ошибка о неэкранированном служебном символе
+func reservedError(c byte) error {
+	return fmt.Errorf("character '%c' is reserved and must be escaped with the preceding '\\'", c)
+}
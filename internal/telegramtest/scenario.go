@@ -0,0 +1,93 @@
+package telegramtest
+
+import (
+	"encoding/json"
+	"fmt"
+	tgbotapi "github.com/go-telegram-bot-api/telegram-bot-api/v5"
+	"strings"
+)
+
+// Step шаг сценария: входящее обновление и проверка вызовов Bot API, сделанных в ответ на него
+type Step struct {
+	Name   string
+	Update tgbotapi.Update
+	// Expect получает только вызовы, сделанные при обработке этого шага
+	Expect func(calls []Call) error
+}
+
+// Scenario сквозной сценарий, например "start → неверный ответ → верный ответ → continue"
+type Scenario struct {
+	Server *Server
+	// Dispatch передает JSON обновления в бота, например в main.Handler
+	Dispatch func(update json.RawMessage) error
+}
+
+// Run выполняет шаги по порядку и останавливается на первой ошибке.
+// Любой вызов, отклоненный сервером (в том числе из-за разметки), считается ошибкой шага
+func (s *Scenario) Run(steps ...Step) error {
+	for i, step := range steps {
+		name := step.Name
+		if name == "" {
+			name = fmt.Sprintf("step %d", i+1)
+		}
+
+		before := len(s.Server.Calls())
+		if err := s.Dispatch(Marshal(step.Update)); err != nil {
+			return fmt.Errorf("%s: dispatch failed: %w", name, err)
+		}
+		calls := s.Server.Calls()[before:]
+
+		for _, call := range calls {
+			if call.Err != "" {
+				return fmt.Errorf("%s: %s rejected: %s", name, call.Method, call.Err)
+			}
+		}
+
+		if step.Expect != nil {
+			if err := step.Expect(calls); err != nil {
+				return fmt.Errorf("%s: %w", name, err)
+			}
+		}
+	}
+	return nil
+}
+
+// ExpectText проверяет, что в чат отправлено сообщение, содержащее подстроку
+func ExpectText(chatID int64, substring string) func(calls []Call) error {
+	return func(calls []Call) error {
+		for _, call := range calls {
+			if isSendMethod(call.Method) && call.ChatID() == chatID && strings.Contains(call.Text(), substring) {
+				return nil
+			}
+		}
+		return fmt.Errorf("no message to chat %d containing %q among %d calls", chatID, substring, len(calls))
+	}
+}
+
+// ExpectButton проверяет, что в чат отправлена кнопка с указанными данными callback'а
+// или с указанным действием (данные кнопок, привязанных к вопросу, содержат подпись)
+func ExpectButton(chatID int64, callbackData string) func(calls []Call) error {
+	return func(calls []Call) error {
+		for _, call := range calls {
+			if call.ChatID() != chatID {
+				continue
+			}
+			if _, ok := call.Button(callbackData); ok {
+				return nil
+			}
+		}
+		return fmt.Errorf("no button %q sent to chat %d", callbackData, chatID)
+	}
+}
+
+// ExpectAll объединяет несколько проверок шага
+func ExpectAll(checks ...func(calls []Call) error) func(calls []Call) error {
+	return func(calls []Call) error {
+		for _, check := range checks {
+			if err := check(calls); err != nil {
+				return err
+			}
+		}
+		return nil
+	}
+}
@@ -0,0 +1,406 @@
+// Package telegramtest содержит поддельный Bot API для сквозной проверки бота без api.telegram.org.
+// Сервер записывает исходящие вызовы, проверяет экранирование MarkdownV2 и отвечает так же, как Telegram
+package telegramtest
+
+import (
+	"encoding/json"
+	"fmt"
+	tgbotapi "github.com/go-telegram-bot-api/telegram-bot-api/v5"
+	"net/http"
+	"net/http/httptest"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+// Token токен, с которым бот обращается к поддельному серверу
+const Token = "123456:TEST"
+
+// BotUser пользователь, которого возвращает getMe
+var BotUser = tgbotapi.User{
+	ID:        123456,
+	IsBot:     true,
+	FirstName: "Qweasley",
+	UserName:  "qweasley_test_bot",
+}
+
+// Call запись одного вызова Bot API
+type Call struct {
+	Method string
+	Params map[string]string
+	// Err описание ошибки, которую сервер вернул на вызов (например, неверная разметка)
+	Err string
+}
+
+// ChatID возвращает чат, которому адресован вызов
+func (c Call) ChatID() int64 {
+	chatID, _ := strconv.ParseInt(c.Params["chat_id"], 10, 64)
+	return chatID
+}
+
+// Text возвращает текст сообщения или подпись к фото
+func (c Call) Text() string {
+	if text, ok := c.Params["text"]; ok {
+		return text
+	}
+	return c.Params["caption"]
+}
+
+// Keyboard возвращает inline-клавиатуру вызова или nil
+func (c Call) Keyboard() *tgbotapi.InlineKeyboardMarkup {
+	markup, ok := c.Params["reply_markup"]
+	if !ok {
+		return nil
+	}
+
+	var keyboard tgbotapi.InlineKeyboardMarkup
+	if err := json.Unmarshal([]byte(markup), &keyboard); err != nil || keyboard.InlineKeyboard == nil {
+		return nil
+	}
+	return &keyboard
+}
+
+// CallbackData возвращает данные всех кнопок inline-клавиатуры по порядку
+func (c Call) CallbackData() []string {
+	keyboard := c.Keyboard()
+	if keyboard == nil {
+		return nil
+	}
+
+	var data []string
+	for _, row := range keyboard.InlineKeyboard {
+		for _, button := range row {
+			if button.CallbackData != nil {
+				data = append(data, *button.CallbackData)
+			}
+		}
+	}
+	return data
+}
+
+// Button возвращает данные первой кнопки с указанным действием, например "skip" для "skip:1:42:..."
+func (c Call) Button(action string) (string, bool) {
+	for _, data := range c.CallbackData() {
+		if data == action || strings.HasPrefix(data, action+":") {
+			return data, true
+		}
+	}
+	return "", false
+}
+
+// Server поддельный Bot API поверх httptest
+type Server struct {
+	*httptest.Server
+
+	mu            sync.Mutex
+	calls         []Call
+	lastMessageID int
+
+	// Чаты, заблокировавшие бота: отправка в них отклоняется с кодом 403
+	blocked map[int64]bool
+
+	// Текущее содержимое отправленных сообщений: правка без изменений отклоняется, как в Telegram
+	contents map[messageKey]messageContent
+
+	// Очередь обновлений для getUpdates и сигнал о появлении новых
+	pending []tgbotapi.Update
+	arrived chan struct{}
+}
+
+// messageKey сообщение в чате
+type messageKey struct {
+	chatID    int64
+	messageID int
+}
+
+// messageContent текст (или подпись) и клавиатура сообщения
+type messageContent struct {
+	text     string
+	keyboard string
+}
+
+// NewServer запускает поддельный Bot API; сервер нужно остановить через Close
+func NewServer() *Server {
+	s := &Server{arrived: make(chan struct{}), blocked: make(map[int64]bool), contents: make(map[messageKey]messageContent)}
+	s.Server = httptest.NewServer(http.HandlerFunc(s.serveHTTP))
+	return s
+}
+
+// Endpoint возвращает шаблон адреса для tgbotapi.NewBotAPIWithAPIEndpoint
+func (s *Server) Endpoint() string {
+	return s.URL + "/bot%s/%s"
+}
+
+// NewBot создает клиента Bot API, подключенного к поддельному серверу
+func (s *Server) NewBot() (*tgbotapi.BotAPI, error) {
+	return tgbotapi.NewBotAPIWithAPIEndpoint(Token, s.Endpoint())
+}
+
+// Calls возвращает все записанные вызовы, кроме getMe
+func (s *Server) Calls() []Call {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	return append([]Call(nil), s.calls...)
+}
+
+// Sent возвращает сообщения и фото, отправленные в чат
+func (s *Server) Sent(chatID int64) []Call {
+	var result []Call
+	for _, call := range s.Calls() {
+		if isSendMethod(call.Method) && call.ChatID() == chatID {
+			result = append(result, call)
+		}
+	}
+	return result
+}
+
+// LastSent возвращает последнее сообщение, отправленное в чат
+func (s *Server) LastSent(chatID int64) (Call, bool) {
+	sent := s.Sent(chatID)
+	if len(sent) == 0 {
+		return Call{}, false
+	}
+	return sent[len(sent)-1], true
+}
+
+// Errors возвращает вызовы, на которые сервер ответил ошибкой
+func (s *Server) Errors() []Call {
+	var result []Call
+	for _, call := range s.Calls() {
+		if call.Err != "" {
+			result = append(result, call)
+		}
+	}
+	return result
+}
+
+// Push ставит обновление в очередь, которую бот получит через getUpdates
+func (s *Server) Push(update tgbotapi.Update) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	s.pending = append(s.pending, update)
+	close(s.arrived)
+	s.arrived = make(chan struct{})
+}
+
+// Pending возвращает обновления, которые бот еще не подтвердил смещением
+func (s *Server) Pending() []tgbotapi.Update {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	return append([]tgbotapi.Update(nil), s.pending...)
+}
+
+// getUpdates отдает обновления начиная со смещения, подтверждая все более ранние,
+// и при пустой очереди ждет новые не дольше timeout секунд
+func (s *Server) getUpdates(params map[string]string) []tgbotapi.Update {
+	offset, _ := strconv.Atoi(params["offset"])
+	limit, _ := strconv.Atoi(params["limit"])
+	timeout, _ := strconv.Atoi(params["timeout"])
+	deadline := time.After(time.Duration(timeout) * time.Second)
+
+	for {
+		s.mu.Lock()
+		var rest []tgbotapi.Update
+		for _, update := range s.pending {
+			if update.UpdateID >= offset {
+				rest = append(rest, update)
+			}
+		}
+		s.pending = rest
+		arrived := s.arrived
+		s.mu.Unlock()
+
+		if len(rest) > 0 || timeout == 0 {
+			if limit > 0 && len(rest) > limit {
+				rest = rest[:limit]
+			}
+			return append([]tgbotapi.Update{}, rest...)
+		}
+
+		select {
+		case <-arrived:
+		case <-deadline:
+			timeout = 0
+		}
+	}
+}
+
+// Block имитирует пользователя, заблокировавшего бота: сообщения в чат больше не доставляются
+func (s *Server) Block(chatID int64) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	s.blocked[chatID] = true
+}
+
+// isSendMethod проверяет, отправляет ли метод новое сообщение
+func isSendMethod(method string) bool {
+	switch method {
+	case "sendMessage", "sendPhoto", "sendInvoice":
+		return true
+	}
+	return false
+}
+
+// apiResponse ответ Bot API
+type apiResponse struct {
+	Ok          bool        `json:"ok"`
+	Result      interface{} `json:"result,omitempty"`
+	ErrorCode   int         `json:"error_code,omitempty"`
+	Description string      `json:"description,omitempty"`
+}
+
+// serveHTTP разбирает вызов /bot<token>/<method>, записывает его и отвечает
+func (s *Server) serveHTTP(w http.ResponseWriter, r *http.Request) {
+	path := strings.TrimPrefix(r.URL.Path, "/bot")
+	token, method, found := strings.Cut(path, "/")
+	if !found || token != Token {
+		writeResponse(w, http.StatusUnauthorized, apiResponse{ErrorCode: http.StatusUnauthorized, Description: "Unauthorized"})
+		return
+	}
+
+	if err := r.ParseMultipartForm(32 << 20); err != nil && err != http.ErrNotMultipart {
+		writeResponse(w, http.StatusBadRequest, apiResponse{ErrorCode: http.StatusBadRequest, Description: "Bad Request: " + err.Error()})
+		return
+	}
+
+	params := make(map[string]string, len(r.Form))
+	for key, values := range r.Form {
+		params[key] = values[0]
+	}
+	if r.MultipartForm != nil {
+		for key := range r.MultipartForm.File {
+			params[key] = "<upload>"
+		}
+	}
+
+	if method == "getMe" {
+		writeResponse(w, http.StatusOK, apiResponse{Ok: true, Result: BotUser})
+		return
+	}
+
+	call := Call{Method: method, Params: params}
+	status, response := s.respond(call)
+	if !response.Ok {
+		call.Err = response.Description
+	}
+
+	s.mu.Lock()
+	s.calls = append(s.calls, call)
+	s.mu.Unlock()
+
+	writeResponse(w, status, response)
+}
+
+// respond формирует ответ на вызов так, как это делает Telegram
+func (s *Server) respond(call Call) (int, apiResponse) {
+	if call.Params["parse_mode"] == tgbotapi.ModeMarkdownV2 {
+		if err := ValidateMarkdownV2(call.Text()); err != nil {
+			return http.StatusBadRequest, apiResponse{ErrorCode: http.StatusBadRequest, Description: "Bad Request: can't parse entities: " + err.Error()}
+		}
+	}
+
+	switch call.Method {
+	case "sendMessage", "sendPhoto", "sendInvoice":
+		if call.ChatID() == 0 {
+			return http.StatusBadRequest, apiResponse{ErrorCode: http.StatusBadRequest, Description: "Bad Request: chat not found"}
+		}
+		if s.isBlocked(call.ChatID()) {
+			return http.StatusForbidden, apiResponse{ErrorCode: http.StatusForbidden, Description: "Forbidden: bot was blocked by the user"}
+		}
+		message := s.message(call)
+		s.setContent(messageKey{call.ChatID(), message.MessageID}, messageContent{call.Text(), call.Params["reply_markup"]})
+		return http.StatusOK, apiResponse{Ok: true, Result: message}
+	case "editMessageText", "editMessageCaption", "editMessageReplyMarkup":
+		if call.Params["inline_message_id"] != "" {
+			return http.StatusOK, apiResponse{Ok: true, Result: true}
+		}
+		messageID, _ := strconv.Atoi(call.Params["message_id"])
+		if !s.edit(messageKey{call.ChatID(), messageID}, call) {
+			return http.StatusBadRequest, apiResponse{ErrorCode: http.StatusBadRequest, Description: "Bad Request: message is not modified: specified new message content and reply markup are exactly the same as a current content and reply markup of the message"}
+		}
+		message := s.message(call)
+		message.MessageID = messageID
+		return http.StatusOK, apiResponse{Ok: true, Result: message}
+	case "getUpdates":
+		return http.StatusOK, apiResponse{Ok: true, Result: s.getUpdates(call.Params)}
+	case "answerCallbackQuery", "answerPreCheckoutQuery", "deleteMessage", "setWebhook", "deleteWebhook":
+		return http.StatusOK, apiResponse{Ok: true, Result: true}
+	}
+
+	return http.StatusNotFound, apiResponse{ErrorCode: http.StatusNotFound, Description: "Not Found: method not found"}
+}
+
+// setContent запоминает содержимое сообщения
+func (s *Server) setContent(key messageKey, content messageContent) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	s.contents[key] = content
+}
+
+// edit применяет правку к сообщению и возвращает false, если она ничего не меняет
+func (s *Server) edit(key messageKey, call Call) bool {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	// Сообщения, отправленные не через сервер (например, message_id 0 в тестовых callback'ах), не отслеживаются
+	current, known := s.contents[key]
+	if !known {
+		return true
+	}
+
+	edited := current
+	if call.Method != "editMessageReplyMarkup" {
+		edited.text = call.Text()
+	}
+	edited.keyboard = call.Params["reply_markup"]
+	if edited == current {
+		return false
+	}
+
+	s.contents[key] = edited
+	return true
+}
+
+// isBlocked проверяет, заблокировал ли чат бота
+func (s *Server) isBlocked(chatID int64) bool {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	return s.blocked[chatID]
+}
+
+// message собирает отправленное сообщение с новым ID
+func (s *Server) message(call Call) tgbotapi.Message {
+	s.mu.Lock()
+	s.lastMessageID++
+	messageID := s.lastMessageID
+	s.mu.Unlock()
+
+	message := tgbotapi.Message{
+		MessageID: messageID,
+		From:      &BotUser,
+		Date:      int(time.Now().Unix()),
+		Chat:      &tgbotapi.Chat{ID: call.ChatID(), Type: "private"},
+		Text:      call.Params["text"],
+		Caption:   call.Params["caption"],
+	}
+	if keyboard := call.Keyboard(); keyboard != nil {
+		message.ReplyMarkup = keyboard
+	}
+	return message
+}
+
+// writeResponse пишет ответ в формате Bot API
+func writeResponse(w http.ResponseWriter, status int, response apiResponse) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(status)
+	if err := json.NewEncoder(w).Encode(response); err != nil {
+		fmt.Printf("Failed to write fake Bot API response: %v\n", err)
+	}
+}
@@ -0,0 +1,103 @@
+package telegramtest
+
+import (
+	"encoding/json"
+	tgbotapi "github.com/go-telegram-bot-api/telegram-bot-api/v5"
+	"strconv"
+	"strings"
+	"sync/atomic"
+	"time"
+)
+
+// lastUpdateID последний выданный update_id
+var lastUpdateID int64
+
+// nextUpdateID выдает следующий update_id
+func nextUpdateID() int {
+	return int(atomic.AddInt64(&lastUpdateID, 1))
+}
+
+// newMessage собирает входящее сообщение от пользователя в личном чате
+func newMessage(chatID int64, text string) *tgbotapi.Message {
+	return &tgbotapi.Message{
+		MessageID: nextUpdateID(),
+		From:      &tgbotapi.User{ID: chatID, FirstName: "Test"},
+		Date:      int(time.Now().Unix()),
+		Chat:      &tgbotapi.Chat{ID: chatID, Type: "private"},
+		Text:      text,
+	}
+}
+
+// TextUpdate обновление с обычным текстовым сообщением
+func TextUpdate(chatID int64, text string) tgbotapi.Update {
+	return tgbotapi.Update{UpdateID: nextUpdateID(), Message: newMessage(chatID, text)}
+}
+
+// CommandUpdate обновление с командой, например "/start" или "/transfer 5"
+func CommandUpdate(chatID int64, command string) tgbotapi.Update {
+	message := newMessage(chatID, command)
+	length := len(command)
+	if i := strings.IndexByte(command, ' '); i >= 0 {
+		length = i
+	}
+	message.Entities = []tgbotapi.MessageEntity{{Type: "bot_command", Offset: 0, Length: length}}
+	return tgbotapi.Update{UpdateID: nextUpdateID(), Message: message}
+}
+
+// PhotoUpdate обновление с фотографией, уже загруженной в Telegram
+func PhotoUpdate(chatID int64, fileID string) tgbotapi.Update {
+	message := newMessage(chatID, "")
+	message.Photo = []tgbotapi.PhotoSize{{FileID: fileID, FileUniqueID: fileID, Width: 800, Height: 600}}
+	return tgbotapi.Update{UpdateID: nextUpdateID(), Message: message}
+}
+
+// CallbackUpdate обновление с нажатием inline-кнопки под сообщением бота
+func CallbackUpdate(chatID int64, messageID int, data string) tgbotapi.Update {
+	message := newMessage(chatID, "")
+	message.MessageID = messageID
+	message.From = &BotUser
+	return tgbotapi.Update{
+		UpdateID: nextUpdateID(),
+		CallbackQuery: &tgbotapi.CallbackQuery{
+			ID:      strconv.Itoa(nextUpdateID()),
+			From:    &tgbotapi.User{ID: chatID, FirstName: "Test"},
+			Message: message,
+			Data:    data,
+		},
+	}
+}
+
+// PreCheckoutUpdate обновление с запросом подтверждения оплаты
+func PreCheckoutUpdate(chatID int64, currency string, totalAmount int, payload string) tgbotapi.Update {
+	return tgbotapi.Update{
+		UpdateID: nextUpdateID(),
+		PreCheckoutQuery: &tgbotapi.PreCheckoutQuery{
+			ID:             strconv.Itoa(nextUpdateID()),
+			From:           &tgbotapi.User{ID: chatID, FirstName: "Test"},
+			Currency:       currency,
+			TotalAmount:    totalAmount,
+			InvoicePayload: payload,
+		},
+	}
+}
+
+// SuccessfulPaymentUpdate обновление с сообщением об успешной оплате
+func SuccessfulPaymentUpdate(chatID int64, currency string, totalAmount int, payload string, chargeID string) tgbotapi.Update {
+	message := newMessage(chatID, "")
+	message.SuccessfulPayment = &tgbotapi.SuccessfulPayment{
+		Currency:                currency,
+		TotalAmount:             totalAmount,
+		InvoicePayload:          payload,
+		TelegramPaymentChargeID: chargeID,
+	}
+	return tgbotapi.Update{UpdateID: nextUpdateID(), Message: message}
+}
+
+// Marshal сериализует обновление в JSON, как его присылает Telegram в вебхук
+func Marshal(update tgbotapi.Update) json.RawMessage {
+	data, err := json.Marshal(update)
+	if err != nil {
+		panic(err)
+	}
+	return data
+}